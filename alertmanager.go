@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type alertmanagerPayload struct {
+	Status      string              `json:"status"`
+	Alerts      []alertmanagerAlert `json:"alerts"`
+	ExternalURL string              `json:"externalURL"`
+}
+
+type alertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+var severityEmoji = map[string]string{
+	"critical": "🚨",
+	"warning":  "⚠️",
+	"info":     "ℹ️",
+}
+
+func serveAlertmanager(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r, config.AlertmanagerToken) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var payload alertmanagerPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(payload.Alerts) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := notify(r.Context(), formatAlertmanagerMessage(payload)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func formatAlertmanagerMessage(payload alertmanagerPayload) string {
+	var b strings.Builder
+	for i, alert := range payload.Alerts {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		icon := "✅"
+		if alert.Status == "firing" {
+			icon = severityEmoji[alert.Labels["severity"]]
+			if icon == "" {
+				icon = "🔔"
+			}
+		}
+		fmt.Fprintf(&b, "%s [%s] %s", icon, strings.ToUpper(alert.Status), alert.Labels["alertname"])
+		if instance := alert.Labels["instance"]; instance != "" {
+			fmt.Fprintf(&b, " (%s)", instance)
+		}
+		b.WriteByte('\n')
+		if summary := alert.Annotations["summary"]; summary != "" {
+			b.WriteString(summary + "\n")
+		} else if description := alert.Annotations["description"]; description != "" {
+			b.WriteString(description + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+type AlertRule struct {
+	Name      string
+	Condition string
+	Message   string
+	Devices   []string
+	Severity  string
+	Urgent    bool
+}
+
+type triggeredAlert struct {
+	Rule   AlertRule
+	Device SwitchBotDevice
+}
+
+func validateAlertRules(rules []AlertRule) error {
+	for _, rule := range rules {
+		if _, ok := conditions[rule.Condition]; !ok {
+			return fmt.Errorf("alert %q references unknown condition %q", rule.Name, rule.Condition)
+		}
+		if rule.Severity != "" && !slices.Contains([]string{"critical", "error", "warning", "info"}, rule.Severity) {
+			return fmt.Errorf("alert %q has unknown severity %q", rule.Name, rule.Severity)
+		}
+	}
+	return nil
+}
+
+func evaluateDeviceAlerts(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus, history []SwitchBotDeviceStatus, latest map[string]SwitchBotDeviceStatus) []triggeredAlert {
+	key := "condition_timers:" + device.DeviceID
+	timers := conditionTimers{}
+	if _, err := stateStore.Get(ctx, key, &timers); err != nil {
+		log.Printf("Failed to load condition timers for %s: %v", device.DeviceName, err)
+	}
+	alerts := evaluateAlerts(conditionInput{
+		Device:  device,
+		Status:  status,
+		History: history,
+		Latest:  latest,
+		Timers:  timers,
+		Now:     status.ReadAt,
+	})
+	if err := stateStore.Put(ctx, key, timers); err != nil {
+		log.Printf("Failed to save condition timers for %s: %v", device.DeviceName, err)
+	}
+	previous, err := loadActiveAlerts(ctx, device.DeviceID)
+	if err != nil {
+		log.Printf("Failed to load active alerts for %s: %v", device.DeviceName, err)
+	}
+	for _, alert := range alerts {
+		if !slices.Contains(previous, alert.Rule.Name) {
+			recordOps(func(s *opsStats) { s.Alerts++ })
+		}
+	}
+	notifyPagerDuty(ctx, device, status, alerts)
+	notifyPush(ctx, device, previous, alerts)
+	publishAlertEvents(ctx, device, status, previous, alerts)
+	postUrgentAlerts(ctx, device, previous, alerts)
+	names := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		names = append(names, alert.Rule.Name)
+	}
+	if err := stateStore.Put(ctx, activeAlertsKey(device.DeviceID), names); err != nil {
+		log.Printf("Failed to save active alerts for %s: %v", device.DeviceName, err)
+	}
+	return alerts
+}
+
+func activeAlertsKey(deviceID string) string {
+	return "active_alerts:" + deviceID
+}
+
+func loadActiveAlerts(ctx context.Context, deviceID string) ([]string, error) {
+	var names []string
+	if _, err := stateStore.Get(ctx, activeAlertsKey(deviceID), &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func evaluateAlerts(in conditionInput) []triggeredAlert {
+	return evaluateRules(in, activeAlertRules(), conditions)
+}
+
+func evaluateRules(in conditionInput, rules []AlertRule, conds map[string]Condition) []triggeredAlert {
+	var alerts []triggeredAlert
+	for _, rule := range rules {
+		if !rule.appliesTo(in.Device) {
+			continue
+		}
+		if conds[rule.Condition].Evaluate(in) {
+			alerts = append(alerts, triggeredAlert{Rule: rule, Device: in.Device})
+		}
+	}
+	return alerts
+}
+
+func (r AlertRule) appliesTo(device SwitchBotDevice) bool {
+	return len(r.Devices) == 0 ||
+		slices.Contains(r.Devices, device.DeviceID) ||
+		slices.Contains(r.Devices, device.DeviceName)
+}
+
+func postUrgentAlerts(ctx context.Context, device SwitchBotDevice, previous []string, alerts []triggeredAlert) {
+	var lines, names []string
+	for _, alert := range alerts {
+		if alert.Rule.Urgent && !slices.Contains(previous, alert.Rule.Name) {
+			lines = append(lines, "🚨 "+alert.text())
+			names = append(names, alert.Rule.Name)
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	message := makeDeviceHeader(device.DeviceName) + "\n" + strings.Join(lines, "\n") + "\n"
+	if config.UrgentMention != "" {
+		message = config.UrgentMention + "\n" + message
+	}
+	if err := notifyAlert(ctx, device, names, message); err != nil {
+		log.Printf("Failed to post urgent alert for %s: %v", device.DeviceName, err)
+	}
+}
+
+func (a triggeredAlert) text() string {
+	if a.Rule.Message != "" {
+		return a.Rule.Message
+	}
+	return a.Rule.Name
+}
@@ -0,0 +1,211 @@
+// Code generated by "switchbot_bot openapi -client". DO NOT EDIT.
+
+// Package client is a Go client for the switchbot_bot daemon API described
+// in api/openapi.json.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls the daemon at BaseURL, sending Token as a bearer token when set.
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// APIError is returned for responses with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("switchbot_bot API: %d %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+func (c *Client) do(req *http.Request, out any) error {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return &APIError{StatusCode: res.StatusCode, Body: string(body)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// GetStatus calls GET /api/status: Latest reading of every device.
+func (c *Client) GetStatus(ctx context.Context) ([]LiveReading, error) {
+	var out []LiveReading
+	req, err := c.newRequest(ctx, "GET", "/api/status", nil)
+	if err != nil {
+		return out, err
+	}
+	return out, c.do(req, &out)
+}
+
+// PostNow calls POST /post-now: Collect readings and post them immediately.
+func (c *Client) PostNow(ctx context.Context) error {
+	req, err := c.newRequest(ctx, "POST", "/post-now", nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
+
+// GetKioskParams are the query parameters of GetKiosk.
+type GetKioskParams struct {
+	Expires string
+	Sig     string
+}
+
+// GetKiosk calls GET /kiosk.json: Readings for kiosk displays, signed with KioskSecret when set.
+func (c *Client) GetKiosk(ctx context.Context, params GetKioskParams) (KioskResponse, error) {
+	var out KioskResponse
+	q := url.Values{}
+	if params.Expires != "" {
+		q.Set("expires", params.Expires)
+	}
+	if params.Sig != "" {
+		q.Set("sig", params.Sig)
+	}
+	req, err := c.newRequest(ctx, "GET", "/kiosk.json"+"?"+q.Encode(), nil)
+	if err != nil {
+		return out, err
+	}
+	return out, c.do(req, &out)
+}
+
+// QueryGraphQL calls POST /graphql: Run a read-only GraphQL query.
+func (c *Client) QueryGraphQL(ctx context.Context, body GraphQLRequest) (GraphQLResponse, error) {
+	var out GraphQLResponse
+	req, err := c.newRequest(ctx, "POST", "/graphql", body)
+	if err != nil {
+		return out, err
+	}
+	return out, c.do(req, &out)
+}
+
+// PostAlertmanager calls POST /alertmanager: Receive a Prometheus Alertmanager webhook.
+func (c *Client) PostAlertmanager(ctx context.Context, body AlertmanagerPayload) error {
+	req, err := c.newRequest(ctx, "POST", "/alertmanager", body)
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
+
+type AlertmanagerAlert struct {
+	Annotations  map[string]string `json:"annotations"`
+	GeneratorURL string            `json:"generatorURL"`
+	Labels       map[string]string `json:"labels"`
+	Status       string            `json:"status"`
+}
+
+type AlertmanagerPayload struct {
+	Alerts      []AlertmanagerAlert `json:"alerts"`
+	ExternalURL string              `json:"externalURL"`
+	Status      string              `json:"status"`
+}
+
+type GraphQLError struct {
+	Message string `json:"message"`
+}
+
+type GraphQLRequest struct {
+	OperationName *string        `json:"operationName,omitempty"`
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type GraphQLResponse struct {
+	Data   any            `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+type KioskResponse struct {
+	Rooms []map[string]any `json:"rooms"`
+}
+
+type LiveReading struct {
+	CO2             *int                   `json:"CO2,omitempty"`
+	Battery         *int                   `json:"battery,omitempty"`
+	DeviceID        string                 `json:"deviceId"`
+	DeviceName      string                 `json:"deviceName"`
+	DoorState       *string                `json:"doorState,omitempty"`
+	ElectricCurrent *float64               `json:"electricCurrent,omitempty"`
+	Humidity        *float64               `json:"humidity,omitempty"`
+	LightLevel      *int                   `json:"lightLevel,omitempty"`
+	LockState       *string                `json:"lockState,omitempty"`
+	MoveDetected    *bool                  `json:"moveDetected,omitempty"`
+	OpenState       *string                `json:"openState,omitempty"`
+	Power           *string                `json:"power,omitempty"`
+	Quality         []string               `json:"quality,omitempty"`
+	Raw             *SwitchBotDeviceStatus `json:"raw,omitempty"`
+	ReadAt          *time.Time             `json:"readAt,omitempty"`
+	Source          *string                `json:"source,omitempty"`
+	Temperature     *float64               `json:"temperature,omitempty"`
+	Voltage         *float64               `json:"voltage,omitempty"`
+	Weight          *float64               `json:"weight,omitempty"`
+}
+
+type SwitchBotDeviceStatus struct {
+	CO2             *int                   `json:"CO2,omitempty"`
+	Battery         *int                   `json:"battery,omitempty"`
+	DoorState       *string                `json:"doorState,omitempty"`
+	ElectricCurrent *float64               `json:"electricCurrent,omitempty"`
+	Humidity        *float64               `json:"humidity,omitempty"`
+	LightLevel      *int                   `json:"lightLevel,omitempty"`
+	LockState       *string                `json:"lockState,omitempty"`
+	MoveDetected    *bool                  `json:"moveDetected,omitempty"`
+	OpenState       *string                `json:"openState,omitempty"`
+	Power           *string                `json:"power,omitempty"`
+	Quality         []string               `json:"quality,omitempty"`
+	Raw             *SwitchBotDeviceStatus `json:"raw,omitempty"`
+	ReadAt          *time.Time             `json:"readAt,omitempty"`
+	Source          *string                `json:"source,omitempty"`
+	Temperature     *float64               `json:"temperature,omitempty"`
+	Voltage         *float64               `json:"voltage,omitempty"`
+	Weight          *float64               `json:"weight,omitempty"`
+}
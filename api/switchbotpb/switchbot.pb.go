@@ -0,0 +1,509 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: api/switchbotpb/switchbot.proto
+
+package switchbotpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Device struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Device) Reset() {
+	*x = Device{}
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Device) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Device) ProtoMessage() {}
+
+func (x *Device) ProtoReflect() protoreflect.Message {
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Device.ProtoReflect.Descriptor instead.
+func (*Device) Descriptor() ([]byte, []int) {
+	return file_api_switchbotpb_switchbot_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Device) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Device) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Device) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+type Reading struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	DeviceName    string                 `protobuf:"bytes,2,opt,name=device_name,json=deviceName,proto3" json:"device_name,omitempty"`
+	ReadAtUnixMs  int64                  `protobuf:"varint,3,opt,name=read_at_unix_ms,json=readAtUnixMs,proto3" json:"read_at_unix_ms,omitempty"`
+	Temperature   *float64               `protobuf:"fixed64,4,opt,name=temperature,proto3,oneof" json:"temperature,omitempty"`
+	Humidity      *float64               `protobuf:"fixed64,5,opt,name=humidity,proto3,oneof" json:"humidity,omitempty"`
+	Co2           *int32                 `protobuf:"varint,6,opt,name=co2,proto3,oneof" json:"co2,omitempty"`
+	Battery       *int32                 `protobuf:"varint,7,opt,name=battery,proto3,oneof" json:"battery,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Reading) Reset() {
+	*x = Reading{}
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Reading) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reading) ProtoMessage() {}
+
+func (x *Reading) ProtoReflect() protoreflect.Message {
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reading.ProtoReflect.Descriptor instead.
+func (*Reading) Descriptor() ([]byte, []int) {
+	return file_api_switchbotpb_switchbot_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Reading) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *Reading) GetDeviceName() string {
+	if x != nil {
+		return x.DeviceName
+	}
+	return ""
+}
+
+func (x *Reading) GetReadAtUnixMs() int64 {
+	if x != nil {
+		return x.ReadAtUnixMs
+	}
+	return 0
+}
+
+func (x *Reading) GetTemperature() float64 {
+	if x != nil && x.Temperature != nil {
+		return *x.Temperature
+	}
+	return 0
+}
+
+func (x *Reading) GetHumidity() float64 {
+	if x != nil && x.Humidity != nil {
+		return *x.Humidity
+	}
+	return 0
+}
+
+func (x *Reading) GetCo2() int32 {
+	if x != nil && x.Co2 != nil {
+		return *x.Co2
+	}
+	return 0
+}
+
+func (x *Reading) GetBattery() int32 {
+	if x != nil && x.Battery != nil {
+		return *x.Battery
+	}
+	return 0
+}
+
+type ListDevicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesRequest) Reset() {
+	*x = ListDevicesRequest{}
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesRequest) ProtoMessage() {}
+
+func (x *ListDevicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesRequest.ProtoReflect.Descriptor instead.
+func (*ListDevicesRequest) Descriptor() ([]byte, []int) {
+	return file_api_switchbotpb_switchbot_proto_rawDescGZIP(), []int{2}
+}
+
+type ListDevicesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Devices       []*Device              `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesResponse) Reset() {
+	*x = ListDevicesResponse{}
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesResponse) ProtoMessage() {}
+
+func (x *ListDevicesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesResponse.ProtoReflect.Descriptor instead.
+func (*ListDevicesResponse) Descriptor() ([]byte, []int) {
+	return file_api_switchbotpb_switchbot_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListDevicesResponse) GetDevices() []*Device {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+type GetLatestReadingRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Device name or ID.
+	Device        string `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLatestReadingRequest) Reset() {
+	*x = GetLatestReadingRequest{}
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLatestReadingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLatestReadingRequest) ProtoMessage() {}
+
+func (x *GetLatestReadingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLatestReadingRequest.ProtoReflect.Descriptor instead.
+func (*GetLatestReadingRequest) Descriptor() ([]byte, []int) {
+	return file_api_switchbotpb_switchbot_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetLatestReadingRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+type StreamReadingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamReadingsRequest) Reset() {
+	*x = StreamReadingsRequest{}
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamReadingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamReadingsRequest) ProtoMessage() {}
+
+func (x *StreamReadingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamReadingsRequest.ProtoReflect.Descriptor instead.
+func (*StreamReadingsRequest) Descriptor() ([]byte, []int) {
+	return file_api_switchbotpb_switchbot_proto_rawDescGZIP(), []int{5}
+}
+
+type TriggerPostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TriggerPostRequest) Reset() {
+	*x = TriggerPostRequest{}
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TriggerPostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriggerPostRequest) ProtoMessage() {}
+
+func (x *TriggerPostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriggerPostRequest.ProtoReflect.Descriptor instead.
+func (*TriggerPostRequest) Descriptor() ([]byte, []int) {
+	return file_api_switchbotpb_switchbot_proto_rawDescGZIP(), []int{6}
+}
+
+type TriggerPostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TriggerPostResponse) Reset() {
+	*x = TriggerPostResponse{}
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TriggerPostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TriggerPostResponse) ProtoMessage() {}
+
+func (x *TriggerPostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_switchbotpb_switchbot_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TriggerPostResponse.ProtoReflect.Descriptor instead.
+func (*TriggerPostResponse) Descriptor() ([]byte, []int) {
+	return file_api_switchbotpb_switchbot_proto_rawDescGZIP(), []int{7}
+}
+
+var File_api_switchbotpb_switchbot_proto protoreflect.FileDescriptor
+
+const file_api_switchbotpb_switchbot_proto_rawDesc = "" +
+	"\n" +
+	"\x1fapi/switchbotpb/switchbot.proto\x12\fswitchbot.v1\"@\n" +
+	"\x06Device\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\"\x9d\x02\n" +
+	"\aReading\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x1f\n" +
+	"\vdevice_name\x18\x02 \x01(\tR\n" +
+	"deviceName\x12%\n" +
+	"\x0fread_at_unix_ms\x18\x03 \x01(\x03R\freadAtUnixMs\x12%\n" +
+	"\vtemperature\x18\x04 \x01(\x01H\x00R\vtemperature\x88\x01\x01\x12\x1f\n" +
+	"\bhumidity\x18\x05 \x01(\x01H\x01R\bhumidity\x88\x01\x01\x12\x15\n" +
+	"\x03co2\x18\x06 \x01(\x05H\x02R\x03co2\x88\x01\x01\x12\x1d\n" +
+	"\abattery\x18\a \x01(\x05H\x03R\abattery\x88\x01\x01B\x0e\n" +
+	"\f_temperatureB\v\n" +
+	"\t_humidityB\x06\n" +
+	"\x04_co2B\n" +
+	"\n" +
+	"\b_battery\"\x14\n" +
+	"\x12ListDevicesRequest\"E\n" +
+	"\x13ListDevicesResponse\x12.\n" +
+	"\adevices\x18\x01 \x03(\v2\x14.switchbot.v1.DeviceR\adevices\"1\n" +
+	"\x17GetLatestReadingRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\"\x17\n" +
+	"\x15StreamReadingsRequest\"\x14\n" +
+	"\x12TriggerPostRequest\"\x15\n" +
+	"\x13TriggerPostResponse2\xd5\x02\n" +
+	"\tSwitchBot\x12R\n" +
+	"\vListDevices\x12 .switchbot.v1.ListDevicesRequest\x1a!.switchbot.v1.ListDevicesResponse\x12P\n" +
+	"\x10GetLatestReading\x12%.switchbot.v1.GetLatestReadingRequest\x1a\x15.switchbot.v1.Reading\x12N\n" +
+	"\x0eStreamReadings\x12#.switchbot.v1.StreamReadingsRequest\x1a\x15.switchbot.v1.Reading0\x01\x12R\n" +
+	"\vTriggerPost\x12 .switchbot.v1.TriggerPostRequest\x1a!.switchbot.v1.TriggerPostResponseB6Z4github.com/shinderuman/switchbot_bot/api/switchbotpbb\x06proto3"
+
+var (
+	file_api_switchbotpb_switchbot_proto_rawDescOnce sync.Once
+	file_api_switchbotpb_switchbot_proto_rawDescData []byte
+)
+
+func file_api_switchbotpb_switchbot_proto_rawDescGZIP() []byte {
+	file_api_switchbotpb_switchbot_proto_rawDescOnce.Do(func() {
+		file_api_switchbotpb_switchbot_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_switchbotpb_switchbot_proto_rawDesc), len(file_api_switchbotpb_switchbot_proto_rawDesc)))
+	})
+	return file_api_switchbotpb_switchbot_proto_rawDescData
+}
+
+var file_api_switchbotpb_switchbot_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_api_switchbotpb_switchbot_proto_goTypes = []any{
+	(*Device)(nil),                  // 0: switchbot.v1.Device
+	(*Reading)(nil),                 // 1: switchbot.v1.Reading
+	(*ListDevicesRequest)(nil),      // 2: switchbot.v1.ListDevicesRequest
+	(*ListDevicesResponse)(nil),     // 3: switchbot.v1.ListDevicesResponse
+	(*GetLatestReadingRequest)(nil), // 4: switchbot.v1.GetLatestReadingRequest
+	(*StreamReadingsRequest)(nil),   // 5: switchbot.v1.StreamReadingsRequest
+	(*TriggerPostRequest)(nil),      // 6: switchbot.v1.TriggerPostRequest
+	(*TriggerPostResponse)(nil),     // 7: switchbot.v1.TriggerPostResponse
+}
+var file_api_switchbotpb_switchbot_proto_depIdxs = []int32{
+	0, // 0: switchbot.v1.ListDevicesResponse.devices:type_name -> switchbot.v1.Device
+	2, // 1: switchbot.v1.SwitchBot.ListDevices:input_type -> switchbot.v1.ListDevicesRequest
+	4, // 2: switchbot.v1.SwitchBot.GetLatestReading:input_type -> switchbot.v1.GetLatestReadingRequest
+	5, // 3: switchbot.v1.SwitchBot.StreamReadings:input_type -> switchbot.v1.StreamReadingsRequest
+	6, // 4: switchbot.v1.SwitchBot.TriggerPost:input_type -> switchbot.v1.TriggerPostRequest
+	3, // 5: switchbot.v1.SwitchBot.ListDevices:output_type -> switchbot.v1.ListDevicesResponse
+	1, // 6: switchbot.v1.SwitchBot.GetLatestReading:output_type -> switchbot.v1.Reading
+	1, // 7: switchbot.v1.SwitchBot.StreamReadings:output_type -> switchbot.v1.Reading
+	7, // 8: switchbot.v1.SwitchBot.TriggerPost:output_type -> switchbot.v1.TriggerPostResponse
+	5, // [5:9] is the sub-list for method output_type
+	1, // [1:5] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_api_switchbotpb_switchbot_proto_init() }
+func file_api_switchbotpb_switchbot_proto_init() {
+	if File_api_switchbotpb_switchbot_proto != nil {
+		return
+	}
+	file_api_switchbotpb_switchbot_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_switchbotpb_switchbot_proto_rawDesc), len(file_api_switchbotpb_switchbot_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_switchbotpb_switchbot_proto_goTypes,
+		DependencyIndexes: file_api_switchbotpb_switchbot_proto_depIdxs,
+		MessageInfos:      file_api_switchbotpb_switchbot_proto_msgTypes,
+	}.Build()
+	File_api_switchbotpb_switchbot_proto = out.File
+	file_api_switchbotpb_switchbot_proto_goTypes = nil
+	file_api_switchbotpb_switchbot_proto_depIdxs = nil
+}
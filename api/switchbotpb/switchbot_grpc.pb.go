@@ -0,0 +1,239 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: api/switchbotpb/switchbot.proto
+
+package switchbotpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SwitchBot_ListDevices_FullMethodName      = "/switchbot.v1.SwitchBot/ListDevices"
+	SwitchBot_GetLatestReading_FullMethodName = "/switchbot.v1.SwitchBot/GetLatestReading"
+	SwitchBot_StreamReadings_FullMethodName   = "/switchbot.v1.SwitchBot/StreamReadings"
+	SwitchBot_TriggerPost_FullMethodName      = "/switchbot.v1.SwitchBot/TriggerPost"
+)
+
+// SwitchBotClient is the client API for SwitchBot service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SwitchBotClient interface {
+	ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error)
+	GetLatestReading(ctx context.Context, in *GetLatestReadingRequest, opts ...grpc.CallOption) (*Reading, error)
+	StreamReadings(ctx context.Context, in *StreamReadingsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Reading], error)
+	TriggerPost(ctx context.Context, in *TriggerPostRequest, opts ...grpc.CallOption) (*TriggerPostResponse, error)
+}
+
+type switchBotClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSwitchBotClient(cc grpc.ClientConnInterface) SwitchBotClient {
+	return &switchBotClient{cc}
+}
+
+func (c *switchBotClient) ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDevicesResponse)
+	err := c.cc.Invoke(ctx, SwitchBot_ListDevices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchBotClient) GetLatestReading(ctx context.Context, in *GetLatestReadingRequest, opts ...grpc.CallOption) (*Reading, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Reading)
+	err := c.cc.Invoke(ctx, SwitchBot_GetLatestReading_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchBotClient) StreamReadings(ctx context.Context, in *StreamReadingsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Reading], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SwitchBot_ServiceDesc.Streams[0], SwitchBot_StreamReadings_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamReadingsRequest, Reading]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SwitchBot_StreamReadingsClient = grpc.ServerStreamingClient[Reading]
+
+func (c *switchBotClient) TriggerPost(ctx context.Context, in *TriggerPostRequest, opts ...grpc.CallOption) (*TriggerPostResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TriggerPostResponse)
+	err := c.cc.Invoke(ctx, SwitchBot_TriggerPost_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SwitchBotServer is the server API for SwitchBot service.
+// All implementations must embed UnimplementedSwitchBotServer
+// for forward compatibility.
+type SwitchBotServer interface {
+	ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error)
+	GetLatestReading(context.Context, *GetLatestReadingRequest) (*Reading, error)
+	StreamReadings(*StreamReadingsRequest, grpc.ServerStreamingServer[Reading]) error
+	TriggerPost(context.Context, *TriggerPostRequest) (*TriggerPostResponse, error)
+	mustEmbedUnimplementedSwitchBotServer()
+}
+
+// UnimplementedSwitchBotServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSwitchBotServer struct{}
+
+func (UnimplementedSwitchBotServer) ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListDevices not implemented")
+}
+func (UnimplementedSwitchBotServer) GetLatestReading(context.Context, *GetLatestReadingRequest) (*Reading, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetLatestReading not implemented")
+}
+func (UnimplementedSwitchBotServer) StreamReadings(*StreamReadingsRequest, grpc.ServerStreamingServer[Reading]) error {
+	return status.Error(codes.Unimplemented, "method StreamReadings not implemented")
+}
+func (UnimplementedSwitchBotServer) TriggerPost(context.Context, *TriggerPostRequest) (*TriggerPostResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method TriggerPost not implemented")
+}
+func (UnimplementedSwitchBotServer) mustEmbedUnimplementedSwitchBotServer() {}
+func (UnimplementedSwitchBotServer) testEmbeddedByValue()                   {}
+
+// UnsafeSwitchBotServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SwitchBotServer will
+// result in compilation errors.
+type UnsafeSwitchBotServer interface {
+	mustEmbedUnimplementedSwitchBotServer()
+}
+
+func RegisterSwitchBotServer(s grpc.ServiceRegistrar, srv SwitchBotServer) {
+	// If the following call panics, it indicates UnimplementedSwitchBotServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SwitchBot_ServiceDesc, srv)
+}
+
+func _SwitchBot_ListDevices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDevicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchBotServer).ListDevices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SwitchBot_ListDevices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchBotServer).ListDevices(ctx, req.(*ListDevicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchBot_GetLatestReading_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLatestReadingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchBotServer).GetLatestReading(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SwitchBot_GetLatestReading_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchBotServer).GetLatestReading(ctx, req.(*GetLatestReadingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SwitchBot_StreamReadings_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamReadingsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SwitchBotServer).StreamReadings(m, &grpc.GenericServerStream[StreamReadingsRequest, Reading]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SwitchBot_StreamReadingsServer = grpc.ServerStreamingServer[Reading]
+
+func _SwitchBot_TriggerPost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriggerPostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchBotServer).TriggerPost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SwitchBot_TriggerPost_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchBotServer).TriggerPost(ctx, req.(*TriggerPostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SwitchBot_ServiceDesc is the grpc.ServiceDesc for SwitchBot service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SwitchBot_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "switchbot.v1.SwitchBot",
+	HandlerType: (*SwitchBotServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListDevices",
+			Handler:    _SwitchBot_ListDevices_Handler,
+		},
+		{
+			MethodName: "GetLatestReading",
+			Handler:    _SwitchBot_GetLatestReading_Handler,
+		},
+		{
+			MethodName: "TriggerPost",
+			Handler:    _SwitchBot_TriggerPost_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamReadings",
+			Handler:       _SwitchBot_StreamReadings_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/switchbotpb/switchbot.proto",
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	awayModeKey     = "away_mode"
+	awayLastPostKey = "away_last_post"
+)
+
+type AwayProfile struct {
+	Alerts              []AlertRule
+	PostIntervalMinutes int
+}
+
+type awayState struct {
+	Enabled bool      `json:"enabled"`
+	Since   time.Time `json:"since"`
+	Until   time.Time `json:"until,omitzero"`
+}
+
+var awayActive atomic.Bool
+
+func loadAwayMode(ctx context.Context, now time.Time) (awayState, error) {
+	var s awayState
+	if _, err := stateStore.Get(ctx, awayModeKey, &s); err != nil {
+		return awayState{}, err
+	}
+	if s.Enabled && !s.Until.IsZero() && now.After(s.Until) {
+		s.Enabled = false
+	}
+	return s, nil
+}
+
+func refreshAwayMode(ctx context.Context, now time.Time) {
+	s, err := loadAwayMode(ctx, now)
+	if err != nil {
+		log.Printf("Failed to load away mode: %v", err)
+		return
+	}
+	awayActive.Store(s.Enabled)
+}
+
+func setAwayMode(ctx context.Context, enabled bool, until time.Time) error {
+	s := awayState{Enabled: enabled, Since: time.Now(), Until: until}
+	if err := stateStore.Put(ctx, awayModeKey, s); err != nil {
+		return err
+	}
+	awayActive.Store(enabled)
+	return nil
+}
+
+func activeAlertRules() []AlertRule {
+	if awayActive.Load() && config.Away != nil && len(config.Away.Alerts) > 0 {
+		return config.Away.Alerts
+	}
+	return config.Alerts
+}
+
+func awayPostDue(ctx context.Context, now time.Time) bool {
+	if !awayActive.Load() || config.Away == nil || config.Away.PostIntervalMinutes <= 0 {
+		return true
+	}
+	var last time.Time
+	if _, err := stateStore.Get(ctx, awayLastPostKey, &last); err != nil {
+		log.Printf("Failed to load last away post time: %v", err)
+		return true
+	}
+	if now.Sub(last) < time.Duration(config.Away.PostIntervalMinutes)*time.Minute {
+		return false
+	}
+	if err := stateStore.Put(ctx, awayLastPostKey, now); err != nil {
+		log.Printf("Failed to save last away post time: %v", err)
+	}
+	return true
+}
+
+func describeAwayMode(s awayState) string {
+	switch {
+	case !s.Enabled:
+		return "🏠 在宅モード"
+	case s.Until.IsZero():
+		return "🧳 留守モード"
+	}
+	return fmt.Sprintf("🧳 留守モード（%sまで）", s.Until.In(timeLocation()).Format("2006-01-02 15:04"))
+}
+
+func runAwayCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: away on|off|status [flags]")
+	}
+	switch args[0] {
+	case "on":
+		fs := flag.NewFlagSet("away on", flag.ContinueOnError)
+		until := fs.String("until", "", "return automatically to home mode at this time (YYYY-MM-DD or YYYY-MM-DD HH:MM)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		var end time.Time
+		if *until != "" {
+			var err error
+			if end, err = parseAwayUntil(*until); err != nil {
+				return err
+			}
+		}
+		if err := setAwayMode(ctx, true, end); err != nil {
+			return err
+		}
+	case "off":
+		if err := setAwayMode(ctx, false, time.Time{}); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return fmt.Errorf("unknown away command %q", args[0])
+	}
+	s, err := loadAwayMode(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Println(describeAwayMode(s))
+	return nil
+}
+
+func parseAwayUntil(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, timeLocation()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
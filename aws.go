@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+var (
+	awsCfg      aws.Config
+	awsCfgErr   error
+	awsCfgOnce  sync.Once
+	roleCfg     aws.Config
+	roleCfgOnce sync.Once
+)
+
+func loadAWSConfig(ctx context.Context) (aws.Config, error) {
+	awsCfgOnce.Do(func() {
+		awsCfg, awsCfgErr = awsconfig.LoadDefaultConfig(ctx, awsconfig.WithHTTPClient(sharedHTTPClient()))
+	})
+	return awsCfg, awsCfgErr
+}
+
+// loadRoleAWSConfig is for metrics and state only; SES and SNS stay in the function's account.
+func loadRoleAWSConfig(ctx context.Context) (aws.Config, error) {
+	cfg, err := loadAWSConfig(ctx)
+	if err != nil || config.RoleARN == "" {
+		return cfg, err
+	}
+	roleCfgOnce.Do(func() {
+		roleCfg = cfg.Copy()
+		roleCfg.Credentials = assumeRole(cfg, config.RoleARN)
+	})
+	return roleCfg, nil
+}
+
+func assumeRole(cfg aws.Config, roleARN string) aws.CredentialsProvider {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = "switchbot_bot"
+	})
+	return aws.NewCredentialsCache(provider)
+}
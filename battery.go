@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const batteryHistoryDays = 180
+
+type batterySample struct {
+	At      time.Time `json:"at"`
+	Battery int       `json:"battery"`
+}
+
+func batteryHistoryKey(deviceID string) string {
+	return "battery_history:" + deviceID
+}
+
+func recordBatterySample(ctx context.Context, deviceID string, status SwitchBotDeviceStatus) ([]batterySample, error) {
+	var samples []batterySample
+	if _, err := stateStore.Get(ctx, batteryHistoryKey(deviceID), &samples); err != nil {
+		return nil, err
+	}
+	day := status.ReadAt.In(timeLocation()).Format(time.DateOnly)
+	if n := len(samples); n > 0 && samples[n-1].At.In(timeLocation()).Format(time.DateOnly) == day {
+		return samples, nil
+	}
+	cutoff := status.ReadAt.AddDate(0, 0, -batteryHistoryDays)
+	kept := make([]batterySample, 0, len(samples)+1)
+	for _, s := range samples {
+		if s.At.After(cutoff) {
+			kept = append(kept, s)
+		}
+	}
+	kept = append(kept, batterySample{At: status.ReadAt, Battery: *status.Battery})
+	return kept, stateStore.Put(ctx, batteryHistoryKey(deviceID), kept)
+}
+
+func forecastDepletion(samples []batterySample) (time.Time, bool) {
+	// A jump up means the batteries were replaced.
+	start := 0
+	for i := 1; i < len(samples); i++ {
+		if samples[i].Battery > samples[i-1].Battery+5 {
+			start = i
+		}
+	}
+	samples = samples[start:]
+	if len(samples) < 3 || samples[len(samples)-1].At.Sub(samples[0].At) < 3*24*time.Hour {
+		return time.Time{}, false
+	}
+
+	origin := samples[0].At
+	var n, sx, sy, sxx, sxy float64
+	for _, s := range samples {
+		x := s.At.Sub(origin).Hours() / 24
+		y := float64(s.Battery)
+		n++
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	denom := n*sxx - sx*sx
+	if denom == 0 {
+		return time.Time{}, false
+	}
+	slope := (n*sxy - sx*sy) / denom
+	if slope >= 0 {
+		return time.Time{}, false
+	}
+	intercept := (sy - slope*sx) / n
+	days := -intercept / slope
+	return origin.Add(time.Duration(days * 24 * float64(time.Hour))), true
+}
+
+func batteryForecastLine(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus) string {
+	if status.Battery == nil {
+		return ""
+	}
+	samples, err := recordBatterySample(ctx, device.DeviceID, status)
+	if err != nil {
+		log.Printf("Failed to record battery sample for %s: %v", device.DeviceName, err)
+		return ""
+	}
+	if config.BatteryForecastDays <= 0 {
+		return ""
+	}
+	empty, ok := forecastDepletion(samples)
+	if !ok || empty.Sub(status.ReadAt) > time.Duration(config.BatteryForecastDays)*24*time.Hour {
+		return ""
+	}
+	return fmt.Sprintf(tr("🪫 そろそろ電池交換（%s頃）"), formatDate(empty))
+}
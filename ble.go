@@ -0,0 +1,140 @@
+package main
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// switchBotCompanyID is Woan Technology's Bluetooth SIG company ID.
+const switchBotCompanyID = 0x0969
+
+var bleServiceUUIDs = []uint16{0xfd3d, 0x0d00}
+
+// cloudReportLatency allows for meters uploading through the hub every few minutes.
+const cloudReportLatency = 5 * time.Minute
+
+// bleReadings is keyed by device ID, which for BLE devices is the MAC without colons.
+var bleReadings = struct {
+	sync.Mutex
+	byID    map[string]SwitchBotDeviceStatus
+	devices []SwitchBotDevice
+}{byID: map[string]SwitchBotDeviceStatus{}}
+
+func bleDeviceID(mac string) string {
+	return strings.ToUpper(strings.ReplaceAll(mac, ":", ""))
+}
+
+// bleMeterStatus reads either layout; newer firmware moves the readings to mfrData.
+func bleMeterStatus(serviceData, mfrData []byte) (SwitchBotDeviceStatus, bool) {
+	if len(serviceData) < 3 {
+		return SwitchBotDeviceStatus{}, false
+	}
+	model := serviceData[0] & 0x7f
+	switch model {
+	case 'T', 'i', 'w', '4', '5':
+	default:
+		return SwitchBotDeviceStatus{}, false
+	}
+	var th []byte
+	switch {
+	case len(mfrData) >= 11:
+		th = mfrData[8:11]
+	case len(serviceData) >= 6:
+		th = serviceData[3:6]
+	default:
+		return SwitchBotDeviceStatus{}, false
+	}
+	temperature := math.Round((float64(th[1]&0x7f)+float64(th[0]&0x0f)/10)*10) / 10
+	if th[1]&0x80 == 0 {
+		temperature = -temperature
+	}
+	humidity := float64(th[2] & 0x7f)
+	battery := int(serviceData[2] & 0x7f)
+	status := SwitchBotDeviceStatus{Battery: &battery, Temperature: &temperature, Humidity: &humidity}
+	if model == '5' && len(mfrData) >= 15 {
+		co2 := int(binary.BigEndian.Uint16(mfrData[13:15]))
+		status.CO2 = &co2
+	}
+	return status, true
+}
+
+func recordBLEReading(deviceID string, status SwitchBotDeviceStatus) {
+	bleReadings.Lock()
+	defer bleReadings.Unlock()
+	bleReadings.byID[deviceID] = status
+}
+
+func bleReading(deviceID string, now time.Time) (SwitchBotDeviceStatus, bool) {
+	bleReadings.Lock()
+	defer bleReadings.Unlock()
+	status, ok := bleReadings.byID[deviceID]
+	if !ok || now.Sub(status.ReadAt) > time.Duration(config.BLEMaxAgeSeconds)*time.Second {
+		return SwitchBotDeviceStatus{}, false
+	}
+	return status, true
+}
+
+func rememberDevices(devices []SwitchBotDevice) {
+	if !config.BLEEnabled {
+		return
+	}
+	bleReadings.Lock()
+	defer bleReadings.Unlock()
+	bleReadings.devices = devices
+}
+
+func bleFallbackDevices(now time.Time) []SwitchBotDevice {
+	bleReadings.Lock()
+	devices := bleReadings.devices
+	bleReadings.Unlock()
+	var fresh []SwitchBotDevice
+	for _, d := range devices {
+		if _, ok := bleReading(d.DeviceID, now); ok {
+			fresh = append(fresh, d)
+		}
+	}
+	return fresh
+}
+
+func reconcileBLE(device SwitchBotDevice, cloud SwitchBotDeviceStatus, now time.Time) SwitchBotDeviceStatus {
+	if !config.BLEEnabled {
+		return cloud
+	}
+	cloud.Source = "cloud"
+	ble, ok := bleReading(device.DeviceID, now)
+	if !ok {
+		return cloud
+	}
+	chosen, other := cloud, ble
+	if ble.ReadAt.After(cloud.ReadAt.Add(-cloudReportLatency)) {
+		chosen, other = ble, cloud
+	}
+	chosen.Temperature = cmp.Or(chosen.Temperature, other.Temperature)
+	chosen.Humidity = cmp.Or(chosen.Humidity, other.Humidity)
+	chosen.CO2 = cmp.Or(chosen.CO2, other.CO2)
+	chosen.Battery = cmp.Or(chosen.Battery, other.Battery)
+	chosen.SourceConflict = bleDisagreement(cloud, ble)
+	if chosen.SourceConflict != "" {
+		log.Printf("BLE and cloud readings of %s disagree: %s", device.DeviceName, chosen.SourceConflict)
+	}
+	return chosen
+}
+
+func bleDisagreement(cloud, ble SwitchBotDeviceStatus) string {
+	var diffs []string
+	if cloud.Temperature != nil && ble.Temperature != nil && math.Abs(*cloud.Temperature-*ble.Temperature) > config.BLEMaxTemperatureDelta {
+		c, unit := displayTemperature(*cloud.Temperature)
+		b, _ := displayTemperature(*ble.Temperature)
+		diffs = append(diffs, fmt.Sprintf("%s: %s %s%s / BLE %s%s", tr("温度"), tr("クラウド"), formatNumber(c, 1), unit, formatNumber(b, 1), unit))
+	}
+	if cloud.Humidity != nil && ble.Humidity != nil && math.Abs(*cloud.Humidity-*ble.Humidity) > config.BLEMaxHumidityDelta {
+		diffs = append(diffs, fmt.Sprintf("%s: %s %s%% / BLE %s%%", tr("湿度"), tr("クラウド"), formatNumber(*cloud.Humidity, 1), formatNumber(*ble.Humidity, 1)))
+	}
+	return strings.Join(diffs, ", ")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"tinygo.org/x/bluetooth"
+)
+
+func startBLEScanner(ctx context.Context) error {
+	adapter := bluetooth.DefaultAdapter
+	if err := adapter.Enable(); err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		adapter.StopScan()
+	}()
+	go func() {
+		err := adapter.Scan(func(_ *bluetooth.Adapter, result bluetooth.ScanResult) {
+			handleBLEAdvertisement(result)
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("BLE scan stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+func handleBLEAdvertisement(result bluetooth.ScanResult) {
+	var serviceData, mfrData []byte
+	for _, sd := range result.ServiceData() {
+		if sd.UUID.Is16Bit() && slices.Contains(bleServiceUUIDs, sd.UUID.Get16Bit()) {
+			serviceData = sd.Data
+		}
+	}
+	if serviceData == nil {
+		return
+	}
+	for _, md := range result.ManufacturerData() {
+		if md.CompanyID == switchBotCompanyID {
+			mfrData = md.Data
+		}
+	}
+	status, ok := bleMeterStatus(serviceData, mfrData)
+	if !ok {
+		return
+	}
+	status.ReadAt = time.Now()
+	status.Source = "ble"
+	recordBLEReading(bleDeviceID(result.Address.String()), status)
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+func startBLEScanner(context.Context) error {
+	return errors.New("BLE scanning is only supported on Linux")
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultBlueskyPDS   = "https://bsky.social"
+	blueskySessionKey   = "bluesky_session"
+	blueskyGraphemes    = 300
+	blueskyAccessMaxAge = 90 * time.Minute
+)
+
+type blueskyNotifier struct{}
+
+func (blueskyNotifier) Name() string { return "bluesky" }
+
+// blueskySession is cached because createSession is rate limited far below the run frequency.
+type blueskySession struct {
+	Fingerprint string    `json:"fingerprint"`
+	AccessJwt   string    `json:"accessJwt"`
+	RefreshJwt  string    `json:"refreshJwt"`
+	DID         string    `json:"did"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type blueskyRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+func (blueskyNotifier) Notify(ctx context.Context, message string) error {
+	session, err := loadBlueskySession(ctx)
+	if err != nil {
+		return err
+	}
+	parts := []string{message}
+	if graphemeCount(message) > blueskyGraphemes {
+		parts = splitStatus(message, "", blueskyGraphemes)
+	}
+	var root, parent *blueskyRef
+	for i, part := range parts {
+		record := map[string]any{
+			"$type":     "app.bsky.feed.post",
+			"text":      part,
+			"createdAt": time.Now().UTC().Format(time.RFC3339),
+		}
+		if root != nil {
+			record["reply"] = map[string]any{"root": root, "parent": parent}
+		}
+		var ref blueskyRef
+		err := blueskyRequest(session.AccessJwt, "com.atproto.repo.createRecord", map[string]any{
+			"repo":       session.DID,
+			"collection": "app.bsky.feed.post",
+			"record":     record,
+		}, &ref)
+		if err != nil {
+			if i > 0 {
+				return fmt.Errorf("part %d of %d: %w", i+1, len(parts), err)
+			}
+			return err
+		}
+		if root == nil {
+			root = &ref
+		}
+		parent = &ref
+	}
+	log.Println("Bluesky post successful:", message)
+	return nil
+}
+
+func loadBlueskySession(ctx context.Context) (blueskySession, error) {
+	fingerprint := credentialFingerprint(blueskyPDS()+config.BlueskyHandle, config.BlueskyAppPassword)
+	var cached blueskySession
+	if ok, err := stateStore.Get(ctx, blueskySessionKey, &cached); err != nil {
+		log.Printf("Failed to read cached Bluesky session: %v", err)
+	} else if ok && cached.Fingerprint == fingerprint {
+		if time.Since(cached.CreatedAt) < blueskyAccessMaxAge {
+			return cached, nil
+		}
+		session, err := blueskyAuth(ctx, cached.RefreshJwt, "com.atproto.server.refreshSession", nil, fingerprint)
+		if err == nil {
+			return session, nil
+		}
+		log.Printf("Failed to refresh Bluesky session: %v", err)
+	}
+	return blueskyAuth(ctx, "", "com.atproto.server.createSession", map[string]any{
+		"identifier": config.BlueskyHandle,
+		"password":   config.BlueskyAppPassword,
+	}, fingerprint)
+}
+
+func blueskyAuth(ctx context.Context, token, method string, payload map[string]any, fingerprint string) (blueskySession, error) {
+	var session blueskySession
+	if err := blueskyRequest(token, method, payload, &session); err != nil {
+		return blueskySession{}, err
+	}
+	session.Fingerprint = fingerprint
+	session.CreatedAt = time.Now()
+	if err := stateStore.Put(ctx, blueskySessionKey, session); err != nil {
+		log.Printf("Failed to cache Bluesky session: %v", err)
+	}
+	return session, nil
+}
+
+func blueskyPDS() string {
+	if config.BlueskyPDS != "" {
+		return strings.TrimSuffix(config.BlueskyPDS, "/")
+	}
+	return defaultBlueskyPDS
+}
+
+func blueskyRequest(token, method string, payload map[string]any, result any) error {
+	var body io.Reader = http.NoBody
+	if payload != nil {
+		buf, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequest("POST", blueskyPDS()+"/xrpc/"+method, body)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("bluesky API error (%s): %s", method, b)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+func graphemeCount(s string) int {
+	count := 0
+	joined := false
+	for _, r := range s {
+		switch {
+		case r == '\u200d':
+			joined = true
+			continue
+		case unicode.In(r, unicode.Mn, unicode.Me), unicode.Is(unicode.Variation_Selector, r),
+			r >= 0x1F3FB && r <= 0x1F3FF:
+			continue
+		case joined:
+			joined = false
+			continue
+		}
+		count++
+	}
+	return count
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const historyBootstrappedKey = "history_bootstrapped"
+
+// bootstrapHistoryFromPosts only serves installs that predate stored history.
+func bootstrapHistoryFromPosts(ctx context.Context, readings []deviceReading) {
+	var done bool
+	if _, err := stateStore.Get(ctx, historyBootstrappedKey, &done); err != nil {
+		log.Printf("Failed to load history bootstrap state: %v", err)
+		return
+	}
+	if done {
+		return
+	}
+	if usesMastodon() || usesMisskey() {
+		devices := make([]SwitchBotDevice, len(readings))
+		for i, r := range readings {
+			devices[i] = r.Device
+		}
+		fetch := fetchRecentMastodonPosts
+		if !usesMastodon() {
+			fetch = fetchRecentMisskeyNotes
+		}
+		posts, err := fetch(ctx, devices)
+		if err != nil {
+			log.Printf("Failed to fetch posts to bootstrap history: %v", err)
+			return
+		}
+		for _, r := range readings {
+			if err := bootstrapDeviceHistory(ctx, r.Device, posts); err != nil {
+				log.Printf("Failed to bootstrap history for %s: %v", r.Device.DeviceName, err)
+				return
+			}
+		}
+	}
+	if err := stateStore.Put(ctx, historyBootstrappedKey, true); err != nil {
+		log.Printf("Failed to save history bootstrap state: %v", err)
+	}
+}
+
+func bootstrapDeviceHistory(ctx context.Context, device SwitchBotDevice, posts []MastodonPost) error {
+	history, err := loadHistory(ctx, device.DeviceID)
+	if err != nil {
+		return err
+	}
+	if len(history) >= batteryCheckPostCount {
+		return nil
+	}
+	var seeded []SwitchBotDeviceStatus
+	for _, post := range posts {
+		status, ok := parsePostedStatus(stripHTMLTags(post.Content), device.DeviceName)
+		if !ok || (len(history) > 0 && !post.CreatedAt.Before(history[0].ReadAt)) {
+			continue
+		}
+		status.ReadAt = post.CreatedAt
+		seeded = append(seeded, status)
+	}
+	if len(seeded) == 0 {
+		return nil
+	}
+	slices.SortFunc(seeded, func(a, b SwitchBotDeviceStatus) int { return a.ReadAt.Compare(b.ReadAt) })
+	log.Printf("Bootstrapped %d readings for %s from recent posts", len(seeded), device.DeviceName)
+	return stateStore.Put(ctx, historyKey(device.DeviceID), append(seeded, history...))
+}
+
+func parsePostedStatus(text, deviceName string) (SwitchBotDeviceStatus, bool) {
+	header := makeDeviceHeader(deviceName)
+	idx := strings.Index(text, header)
+	if idx == -1 {
+		return SwitchBotDeviceStatus{}, false
+	}
+	section := text[idx+len(header):]
+	if end := strings.Index(section, "# "); end != -1 {
+		section = section[:end]
+	}
+	_, unit := displayTemperature(0)
+	status := SwitchBotDeviceStatus{
+		Temperature: extractFloatValue(section, postedLinePattern("温度", unit)),
+		Humidity:    extractFloatValue(section, postedLinePattern("湿度", "%")),
+		CO2:         extractIntValue(section, `CO2: `+localizedNumberPattern+`ppm`),
+		LightLevel:  extractIntValue(section, postedLinePattern("照度", "")),
+	}
+	if status.Temperature != nil {
+		*status.Temperature = celsiusFromDisplay(*status.Temperature)
+	}
+	return status, true
+}
+
+func postedLinePattern(label, unit string) string {
+	return regexp.QuoteMeta(tr(label)+": ") + localizedNumberPattern + regexp.QuoteMeta(unit)
+}
+
+func extractFloatValue(text, pattern string) *float64 {
+	matches := regexp.MustCompile(pattern).FindStringSubmatch(text)
+	if len(matches) < 2 {
+		return nil
+	}
+	v, err := parseLocalizedNumber(matches[1])
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func extractIntValue(text, pattern string) *int {
+	matches := regexp.MustCompile(pattern).FindStringSubmatch(text)
+	if len(matches) < 2 {
+		return nil
+	}
+	f, err := parseLocalizedNumber(matches[1])
+	if err != nil {
+		return nil
+	}
+	v := int(f)
+	return &v
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+)
+
+func switchBotBudget(ctx context.Context, now time.Time) (int, error) {
+	var day opsStats
+	if _, err := stateStore.Get(ctx, opsStatsKey(opsDate(now)), &day); err != nil {
+		return 0, err
+	}
+	opsMu.Lock()
+	used := day.SwitchBotCalls + opsPending.SwitchBotCalls
+	opsMu.Unlock()
+	return switchBotDailyQuota - used, nil
+}
+
+func budgetDevices(devices []SwitchBotDevice, remaining int) []SwitchBotDevice {
+	if remaining >= config.SwitchBotBudgetReserve || len(config.LowPriorityDevices) == 0 {
+		return devices
+	}
+	var kept []SwitchBotDevice
+	var skipped int
+	for _, d := range devices {
+		if slices.Contains(config.LowPriorityDevices, d.DeviceName) {
+			skipped++
+			continue
+		}
+		kept = append(kept, d)
+	}
+	if skipped > 0 {
+		log.Printf("Skipping %d low-priority devices: only %d SwitchBot API calls left today", skipped, remaining)
+	}
+	return kept
+}
+
+func checkSwitchBotBudget(ctx context.Context, now time.Time) int {
+	remaining, err := switchBotBudget(ctx, now)
+	if err != nil {
+		log.Printf("Failed to load the SwitchBot API budget: %v", err)
+		return switchBotDailyQuota
+	}
+	log.Printf("SwitchBot API budget: %d of %d calls left today", remaining, switchBotDailyQuota)
+	entry := map[string]any{"type": "Budget", "remaining": remaining, "quota": switchBotDailyQuota, "timestamp": now}
+	if err := putRunMetric(ctx, "SwitchBotAPIRemaining", remaining, entry, now); err != nil {
+		log.Printf("Failed to send the SwitchBot API budget metric: %v", err)
+	}
+	return remaining
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const calibrationMaxSkew = 2 * time.Minute
+
+type CalibrationOffset struct {
+	Temperature float64 `json:",omitempty"`
+	Humidity    float64 `json:",omitempty"`
+	CO2         int     `json:",omitempty"`
+}
+
+// calibrateStatus must be applied on every path, so raw and calibrated values never mix.
+func calibrateStatus(device SwitchBotDevice, status SwitchBotDeviceStatus) SwitchBotDeviceStatus {
+	offset, ok := config.Calibration[device.DeviceName]
+	if !ok {
+		return status
+	}
+	s := &status
+	calibrated := false
+	if s.Temperature != nil && offset.Temperature != 0 {
+		setReadingValue(s, "temperature", *s.Temperature+offset.Temperature)
+		calibrated = true
+	}
+	if s.Humidity != nil && offset.Humidity != 0 {
+		setReadingValue(s, "humidity", min(max(*s.Humidity+offset.Humidity, 0), 100))
+		calibrated = true
+	}
+	if s.CO2 != nil && offset.CO2 != 0 {
+		setReadingValue(s, "co2", float64(*s.CO2+offset.CO2))
+		calibrated = true
+	}
+	if calibrated {
+		addQuality(s, qualityCalibrated)
+	}
+	return status
+}
+
+type calibrationStat struct {
+	Metric  string
+	Samples int
+	Mean    float64
+	StdDev  float64
+	Current float64
+}
+
+func (s calibrationStat) suggested() float64 {
+	return s.Current + s.Mean
+}
+
+func compareWithReference(device, reference []SwitchBotDeviceStatus, current CalibrationOffset, since time.Time) []calibrationStat {
+	metrics := []struct {
+		name    string
+		current float64
+	}{
+		{"temperature", current.Temperature},
+		{"humidity", current.Humidity},
+		{"co2", float64(current.CO2)},
+	}
+	var stats []calibrationStat
+	for _, m := range metrics {
+		var diffs []float64
+		for _, d := range device {
+			if d.ReadAt.Before(since) {
+				continue
+			}
+			v, ok := readingValue(d, m.name)
+			if !ok {
+				continue
+			}
+			ref, ok := nearestReading(reference, d.ReadAt)
+			if !ok {
+				continue
+			}
+			if r, ok := readingValue(ref, m.name); ok {
+				diffs = append(diffs, r-v)
+			}
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+		var sum, sq float64
+		for _, d := range diffs {
+			sum += d
+		}
+		mean := sum / float64(len(diffs))
+		for _, d := range diffs {
+			sq += (d - mean) * (d - mean)
+		}
+		stats = append(stats, calibrationStat{
+			Metric:  m.name,
+			Samples: len(diffs),
+			Mean:    mean,
+			StdDev:  math.Sqrt(sq / float64(len(diffs))),
+			Current: m.current,
+		})
+	}
+	return stats
+}
+
+func nearestReading(history []SwitchBotDeviceStatus, at time.Time) (SwitchBotDeviceStatus, bool) {
+	var best SwitchBotDeviceStatus
+	bestSkew := calibrationMaxSkew + 1
+	for _, h := range history {
+		skew := h.ReadAt.Sub(at).Abs()
+		if skew < bestSkew {
+			best, bestSkew = h, skew
+		}
+	}
+	return best, bestSkew <= calibrationMaxSkew
+}
+
+func runCalibrateCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("calibrate", flag.ContinueOnError)
+	deviceName := fs.String("device", "", "device to calibrate")
+	referenceName := fs.String("reference", "", "reference device")
+	period := fs.String("period", "24h", "period of history to compare, e.g. 12h or 7d (limited by HistoryHours)")
+	write := fs.Bool("write", false, "write the suggested offsets to Calibration in config.json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *deviceName == "" || *referenceName == "" {
+		return fmt.Errorf("usage: calibrate --device NAME --reference NAME [--period 24h] [--write]")
+	}
+	lookback, err := parseLookback(*period)
+	if err != nil {
+		return err
+	}
+	devices, err := fetchDevices(ctx)
+	if err != nil {
+		return fmt.Errorf("fetchDevices error: %w", err)
+	}
+	device, ok := findDevice(devices, *deviceName)
+	if !ok {
+		return fmt.Errorf("unknown device %q", *deviceName)
+	}
+	reference, ok := findDevice(devices, *referenceName)
+	if !ok {
+		return fmt.Errorf("unknown device %q", *referenceName)
+	}
+	deviceHistory, err := loadHistory(ctx, device.DeviceID)
+	if err != nil {
+		return err
+	}
+	referenceHistory, err := loadHistory(ctx, reference.DeviceID)
+	if err != nil {
+		return err
+	}
+
+	current := config.Calibration[device.DeviceName]
+	stats := compareWithReference(deviceHistory, referenceHistory, current, time.Now().Add(-lookback))
+	if len(stats) == 0 {
+		return fmt.Errorf("no readings of %s and %s taken at the same time in the last %s", device.DeviceName, reference.DeviceName, *period)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METRIC\tSAMPLES\tMEAN DIFF\tSTDDEV\tCURRENT\tSUGGESTED")
+	suggested := current
+	for _, s := range stats {
+		fmt.Fprintf(w, "%s\t%d\t%+.2f\t%.2f\t%+.2f\t%+.2f\n", s.Metric, s.Samples, s.Mean, s.StdDev, s.Current, s.suggested())
+		switch s.Metric {
+		case "temperature":
+			suggested.Temperature = math.Round(s.suggested()*10) / 10
+		case "humidity":
+			suggested.Humidity = math.Round(s.suggested()*10) / 10
+		case "co2":
+			suggested.CO2 = int(math.Round(s.suggested()))
+		}
+	}
+	w.Flush()
+	if !*write {
+		fmt.Println("\nRun again with --write to save the suggested offsets")
+		return nil
+	}
+	calibration := map[string]CalibrationOffset{}
+	for name, offset := range config.Calibration {
+		calibration[name] = offset
+	}
+	calibration[device.DeviceName] = suggested
+	if err := writeConfigKey(configPath(), "Calibration", calibration); err != nil {
+		return fmt.Errorf("writing %s failed: %w", configPath(), err)
+	}
+	fmt.Printf("\nSaved the offsets of %s to %s\n", device.DeviceName, configPath())
+	return nil
+}
+
+// writeConfigKey leaves the other keys as written, rather than re-encoding
+// Config with its defaults and secrets.
+func writeConfigKey(path, key string, value any) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("%s is not a JSON object", path)
+	}
+	var keys []string
+	values := map[string]json.RawMessage{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		k := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if _, ok := values[k]; !ok {
+			keys = append(keys, k)
+		}
+		values[k] = raw
+	}
+	encoded, err := json.MarshalIndent(value, "    ", "    ")
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		keys = append(keys, key)
+	}
+	values[key] = encoded
+
+	var out bytes.Buffer
+	out.WriteString("{\n")
+	for i, k := range keys {
+		name, _ := json.Marshal(k)
+		fmt.Fprintf(&out, "    %s: %s", name, values[k])
+		if i < len(keys)-1 {
+			out.WriteByte(',')
+		}
+		out.WriteByte('\n')
+	}
+	out.WriteString("}\n")
+	return os.WriteFile(path, out.Bytes(), info.Mode().Perm())
+}
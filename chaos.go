@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ChaosConfig is deliberately left out of the documented settings.
+type ChaosConfig struct {
+	SwitchBot190Rate  float64
+	MastodonErrorRate float64
+	DelayMillis       int
+}
+
+type chaosTransport struct {
+	next         http.RoundTripper
+	cfg          ChaosConfig
+	mastodonHost string
+}
+
+func newChaosTransport(next http.RoundTripper, cfg ChaosConfig) *chaosTransport {
+	t := &chaosTransport{next: next, cfg: cfg}
+	if u, err := url.Parse(config.MastodonURL); err == nil {
+		t.mastodonHost = u.Host
+	}
+	return t
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.cfg.DelayMillis > 0 {
+		select {
+		case <-time.After(time.Duration(t.cfg.DelayMillis) * time.Millisecond):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	switch {
+	case req.URL.Host == "api.switch-bot.com" && rand.Float64() < t.cfg.SwitchBot190Rate:
+		log.Printf("Chaos: injecting statusCode 190 for %s", req.URL.Path)
+		return chaosResponse(req, http.StatusOK, `{"statusCode":190,"message":"chaos: injected device internal error","body":{}}`), nil
+	case t.mastodonHost != "" && req.URL.Host == t.mastodonHost && rand.Float64() < t.cfg.MastodonErrorRate:
+		log.Printf("Chaos: injecting HTTP 500 for %s", req.URL.Path)
+		return chaosResponse(req, http.StatusInternalServerError, `{"error":"chaos: injected server error"}`), nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+func chaosResponse(req *http.Request, code int, body string) *http.Response {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		Status:        http.StatusText(code),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}, "Date": {time.Now().UTC().Format(http.TimeFormat)}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+)
+
+const (
+	chartPostedKey      = "chart_posted"
+	maxMastodonMediaIDs = 4
+)
+
+type chartImage struct {
+	Title string
+	PNG   []byte
+}
+
+type chartNotifier interface {
+	NotifyWithCharts(ctx context.Context, message string, charts []chartImage) error
+}
+
+// dailyCharts returns the day to pass to markChartsPosted, or "" when none are due.
+func dailyCharts(ctx context.Context, readings []deviceReading) ([]chartImage, string) {
+	if !config.ChartEnabled || !usesMastodon() {
+		return nil, ""
+	}
+	now := time.Now().In(timeLocation())
+	if now.Hour() < config.ChartHour {
+		return nil, ""
+	}
+	today := now.Format("2006-01-02")
+	var posted string
+	if _, err := stateStore.Get(ctx, chartPostedKey, &posted); err != nil {
+		log.Printf("Failed to load chart state: %v", err)
+		return nil, ""
+	}
+	if posted == today {
+		return nil, ""
+	}
+
+	var charts []chartImage
+	for _, r := range readings {
+		if len(charts) == maxMastodonMediaIDs {
+			break
+		}
+		png, err := renderMetricChart(ctx, r.Device)
+		if err != nil {
+			log.Printf("Failed to render chart for %s: %v", r.Device.DeviceName, err)
+			continue
+		}
+		if png != nil {
+			charts = append(charts, chartImage{Title: r.Device.DeviceName, PNG: png})
+		}
+	}
+	return charts, today
+}
+
+func markChartsPosted(ctx context.Context, day string) {
+	if day == "" {
+		return
+	}
+	if err := stateStore.Put(ctx, chartPostedKey, day); err != nil {
+		log.Printf("Failed to save chart state: %v", err)
+	}
+}
+
+func renderMetricChart(ctx context.Context, device SwitchBotDevice) ([]byte, error) {
+	var metrics [][]any
+	for _, name := range []string{"Temperature", "Humidity", "CO2"} {
+		metric := []any{config.MetricsNamespace, name}
+		for _, d := range metricDimensions(device.DeviceID, device.DeviceName) {
+			metric = append(metric, *d.Name, *d.Value)
+		}
+		if name == "CO2" {
+			metric = append(metric, map[string]string{"yAxis": "right"})
+		}
+		metrics = append(metrics, metric)
+	}
+	widget, err := json.Marshal(map[string]any{
+		"title":    device.DeviceName,
+		"metrics":  metrics,
+		"start":    "-PT24H",
+		"period":   300,
+		"stat":     "Average",
+		"view":     "timeSeries",
+		"width":    800,
+		"height":   400,
+		"timezone": time.Now().In(timeLocation()).Format("-0700"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	client, err := cloudWatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.GetMetricWidgetImage(ctx, &cloudwatch.GetMetricWidgetImageInput{
+		MetricWidget: aws.String(string(widget)),
+		OutputFormat: aws.String("png"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.MetricWidgetImage, nil
+}
+
+func uploadMastodonMedia(ctx context.Context, chart chartImage) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", "chart.png")
+	if err != nil {
+		return "", err
+	}
+	part.Write(chart.PNG)
+	w.WriteField("description", chart.Title+"の24時間の推移")
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", config.MastodonURL+"/media", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.MastodonToken)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		b, _ := io.ReadAll(res.Body)
+		return "", fmt.Errorf("mastodon media upload error: %s", b)
+	}
+	var media struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&media); err != nil {
+		return "", err
+	}
+	return media.ID, nil
+}
+
+func (mastodonNotifier) NotifyWithCharts(ctx context.Context, message string, charts []chartImage) error {
+	return postMastodonWithCharts(ctx, message, config.SpoilerText, charts)
+}
+
+func postMastodonWithCharts(ctx context.Context, message, spoiler string, charts []chartImage) error {
+	payload := mastodonStatus(message, config.PostVisibility, spoiler)
+	if ids := uploadMastodonCharts(ctx, charts); len(ids) > 0 {
+		payload["media_ids"] = ids
+	}
+	_, err := postMastodonStatus(ctx, payload)
+	return err
+}
+
+func uploadMastodonCharts(ctx context.Context, charts []chartImage) []string {
+	var ids []string
+	for _, chart := range charts {
+		id, err := uploadMastodonMedia(ctx, chart)
+		if err != nil {
+			log.Printf("Failed to upload chart for %s: %v", chart.Title, err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
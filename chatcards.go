@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type messageSection struct {
+	Title string
+	Lines []string
+}
+
+func splitMessageSections(message string) []messageSection {
+	var sections []messageSection
+	for line := range strings.SplitSeq(strings.TrimSpace(message), "\n") {
+		if title, ok := strings.CutPrefix(line, "# "); ok {
+			sections = append(sections, messageSection{Title: title})
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if len(sections) == 0 {
+			sections = append(sections, messageSection{})
+		}
+		last := &sections[len(sections)-1]
+		last.Lines = append(last.Lines, line)
+	}
+	return sections
+}
+
+func postWebhookJSON(ctx context.Context, name, url string, payload any) error {
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s webhook error: %s", name, body)
+	}
+	log.Printf("%s post successful", name)
+	return nil
+}
+
+type googleChatNotifier struct {
+	webhookURL string
+}
+
+func (googleChatNotifier) Name() string { return "googlechat" }
+
+func (n googleChatNotifier) Notify(ctx context.Context, message string) error {
+	var sections []map[string]any
+	for _, s := range splitMessageSections(message) {
+		escaped := make([]string, len(s.Lines))
+		for i, line := range s.Lines {
+			escaped[i] = html.EscapeString(line)
+		}
+		section := map[string]any{
+			"widgets": []map[string]any{
+				{"textParagraph": map[string]string{"text": strings.Join(escaped, "<br>")}},
+			},
+		}
+		if s.Title != "" {
+			section["header"] = s.Title
+		}
+		sections = append(sections, section)
+	}
+	payload := map[string]any{
+		"cardsV2": []map[string]any{{
+			"cardId": "switchbot",
+			"card": map[string]any{
+				"header":   map[string]string{"title": "SwitchBot"},
+				"sections": sections,
+			},
+		}},
+	}
+	return postWebhookJSON(ctx, "Google Chat", n.webhookURL, payload)
+}
+
+type teamsNotifier struct {
+	webhookURL string
+}
+
+func (teamsNotifier) Name() string { return "teams" }
+
+func (n teamsNotifier) Notify(ctx context.Context, message string) error {
+	var body []map[string]any
+	for _, s := range splitMessageSections(message) {
+		if s.Title != "" {
+			body = append(body, map[string]any{
+				"type":      "TextBlock",
+				"text":      s.Title,
+				"weight":    "Bolder",
+				"size":      "Medium",
+				"wrap":      true,
+				"separator": len(body) > 0,
+			})
+		}
+		for _, line := range s.Lines {
+			body = append(body, map[string]any{
+				"type":    "TextBlock",
+				"text":    line,
+				"wrap":    true,
+				"spacing": "None",
+			})
+		}
+	}
+	payload := map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+	return postWebhookJSON(ctx, "Teams", n.webhookURL, payload)
+}
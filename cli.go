@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func runCommand(ctx context.Context, args []string) error {
+	switch args[0] {
+	case "status":
+		return runStatusCommand(ctx, args[1:])
+	case "openapi":
+		return runOpenAPICommand(ctx, args[1:])
+	case "service":
+		return runServiceCommand(ctx, args[1:])
+	}
+	if err := setup(); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "rules":
+		return runRulesCommand(ctx, args[1:])
+	case "daemon":
+		return runDaemon(ctx, args[1:])
+	case "kiosk-url":
+		return runKioskCommand(ctx, args[1:])
+	case "state":
+		return runStateCommand(ctx, args[1:])
+	case "health":
+		return runHealthCommand(ctx, args[1:])
+	case "prune":
+		return runPruneCommand(ctx, args[1:])
+	case "calibrate":
+		return runCalibrateCommand(ctx, args[1:])
+	case "away":
+		return runAwayCommand(ctx, args[1:])
+	case "share":
+		return runShareCommand(ctx, args[1:])
+	case "guest-token":
+		return runGuestTokenCommand(ctx, args[1:])
+	case "daily-summary":
+		return runDailySummary(ctx)
+	case "email-digest":
+		return runEmailDigest(ctx)
+	case "rollup":
+		return runRollup(ctx, time.Now())
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func runRulesCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: rules simulate|whatif [flags]")
+	}
+	switch args[0] {
+	case "simulate":
+		return runRulesSimulate(ctx, args[1:])
+	case "whatif":
+		return runRulesWhatIf(ctx, args[1:])
+	}
+	return fmt.Errorf("unknown rules command %q", args[0])
+}
+
+func parseLookback(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,45 @@
+package main
+
+import "math"
+
+// dewPoint uses the Magnus formula.
+func dewPoint(t, rh float64) float64 {
+	const a, b = 17.62, 243.12
+	g := math.Log(rh/100) + a*t/(b+t)
+	return b * g / (a - g)
+}
+
+// absoluteHumidity is in g/m³.
+func absoluteHumidity(t, rh float64) float64 {
+	return 6.112 * math.Exp(17.67*t/(t+243.5)) * rh * 2.1674 / (273.15 + t)
+}
+
+// wbgt follows the indoor estimate of the Japanese Society of Biometeorology.
+func wbgt(t, rh float64) float64 {
+	return 0.725*t + 0.0368*rh + 0.00364*t*rh - 3.246
+}
+
+func comfortValues(status SwitchBotDeviceStatus) (dew, abs, heat float64, ok bool) {
+	if status.Temperature == nil || status.Humidity == nil || *status.Humidity <= 0 {
+		return 0, 0, 0, false
+	}
+	t, rh := *status.Temperature, *status.Humidity
+	return dewPoint(t, rh), absoluteHumidity(t, rh), wbgt(t, rh), true
+}
+
+// discomfortIndex is the Japanese 不快指数.
+func discomfortIndex(t, rh float64) float64 {
+	return 0.81*t + 0.01*rh*(0.99*t-14.3) + 46.3
+}
+
+func discomfortEmoji(di float64) string {
+	switch {
+	case di >= 80:
+		return "🥵"
+	case di >= 75:
+		return "😓"
+	case di < 55:
+		return "🥶"
+	}
+	return "😀"
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	commandQueueKey    = "command_queue"
+	commandOutcomesKey = "command_outcomes"
+	maxCommandAttempts = 3
+	maxCommandOutcomes = 100
+)
+
+var commandVerifyDelay = 5 * time.Second
+
+var commandQueueMu sync.Mutex
+
+type queuedCommand struct {
+	Device      SwitchBotDevice `json:"device"`
+	Command     string          `json:"command"`
+	Source      string          `json:"source"`
+	Attempts    int             `json:"attempts"`
+	Sent        bool            `json:"sent,omitempty"`
+	EnqueuedAt  time.Time       `json:"enqueuedAt"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+}
+
+type commandOutcome struct {
+	Device     string    `json:"device"`
+	Command    string    `json:"command"`
+	Source     string    `json:"source"`
+	Attempts   int       `json:"attempts"`
+	Result     string    `json:"result"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+const (
+	commandVerified   = "verified"
+	commandUnverified = "sent"
+	commandRetrying   = "retrying"
+	commandFailed     = "failed"
+)
+
+func expectedState(device SwitchBotDevice, command string, status SwitchBotDeviceStatus) (done, ok bool) {
+	if device.RemoteType != "" {
+		return false, false
+	}
+	switch command {
+	case "turnOn", "turnOff":
+		if status.PowerState == nil {
+			return false, false
+		}
+		return *status.PowerState == map[string]string{"turnOn": "on", "turnOff": "off"}[command], true
+	case "lock", "unlock":
+		if status.LockState == nil {
+			return false, false
+		}
+		return *status.LockState == command+"ed", true
+	}
+	return false, false
+}
+
+func enqueueCommand(ctx context.Context, device SwitchBotDevice, command, source string) string {
+	commandQueueMu.Lock()
+	defer commandQueueMu.Unlock()
+	now := time.Now()
+	c := queuedCommand{Device: device, Command: command, Source: source, EnqueuedAt: now, NextAttempt: now}
+	result := attemptCommand(ctx, &c)
+	if result == commandRetrying {
+		var queue []queuedCommand
+		if _, err := stateStore.Get(ctx, commandQueueKey, &queue); err != nil {
+			log.Printf("Failed to load command queue: %v", err)
+		}
+		if err := stateStore.Put(ctx, commandQueueKey, append(queue, c)); err != nil {
+			log.Printf("Failed to save command queue: %v", err)
+		}
+	}
+	return result
+}
+
+func processCommandQueue(ctx context.Context) {
+	commandQueueMu.Lock()
+	defer commandQueueMu.Unlock()
+	var queue []queuedCommand
+	if _, err := stateStore.Get(ctx, commandQueueKey, &queue); err != nil {
+		log.Printf("Failed to load command queue: %v", err)
+		return
+	}
+	if len(queue) == 0 {
+		return
+	}
+	now := time.Now()
+	var pending []queuedCommand
+	for _, c := range queue {
+		if now.Before(c.NextAttempt) || attemptCommand(ctx, &c) == commandRetrying {
+			pending = append(pending, c)
+		}
+	}
+	if err := stateStore.Put(ctx, commandQueueKey, pending); err != nil {
+		log.Printf("Failed to save command queue: %v", err)
+	}
+}
+
+// verifiable is false for commands that sending again would repeat, such as a toggle.
+func verifiable(device SwitchBotDevice, command string) bool {
+	switch command {
+	case "turnOn", "turnOff", "lock", "unlock":
+		return device.RemoteType == ""
+	}
+	return false
+}
+
+// attemptCommand only polls the status once an earlier attempt has sent c.
+func attemptCommand(ctx context.Context, c *queuedCommand) string {
+	c.Attempts++
+	result := commandUnverified
+	var err error
+	if !c.Sent {
+		err = sendDeviceCommand(ctx, c.Device, c.Command)
+		c.Sent = err == nil
+	}
+	if err == nil && verifiable(c.Device, c.Command) {
+		var verified bool
+		if verified, err = verifyCommand(ctx, c); verified {
+			result = commandVerified
+		}
+	}
+	if err != nil {
+		log.Printf("Command %s for %s failed (attempt %d/%d): %v", c.Command, c.Device.DeviceName, c.Attempts, maxCommandAttempts, err)
+		if c.Attempts < maxCommandAttempts {
+			c.NextAttempt = time.Now().Add(time.Duration(c.Attempts) * time.Minute)
+			return commandRetrying
+		}
+		result = commandFailed
+		message := fmt.Sprintf("❌ %s: %s を%d回試しましたが確認できませんでした", c.Device.DeviceName, c.Command, c.Attempts)
+		if err := notifyUrgent(ctx, message); err != nil {
+			log.Printf("Failed to send command failure alert: %v", err)
+		}
+	}
+	recordCommandOutcome(ctx, commandOutcome{
+		Device:     c.Device.DeviceName,
+		Command:    c.Command,
+		Source:     c.Source,
+		Attempts:   c.Attempts,
+		Result:     result,
+		FinishedAt: time.Now(),
+	})
+	return result
+}
+
+func verifyCommand(ctx context.Context, c *queuedCommand) (verified bool, err error) {
+	if err := sleepContext(ctx, commandVerifyDelay); err != nil {
+		return false, err
+	}
+	status, err := fetchDeviceStatus(ctx, c.Device)
+	if err != nil {
+		return false, err
+	}
+	done, ok := expectedState(c.Device, c.Command, status)
+	if ok && !done {
+		return false, fmt.Errorf("device did not reach the expected state")
+	}
+	return ok, nil
+}
+
+func recordCommandOutcome(ctx context.Context, o commandOutcome) {
+	var outcomes []commandOutcome
+	if _, err := stateStore.Get(ctx, commandOutcomesKey, &outcomes); err != nil {
+		log.Printf("Failed to load command outcomes: %v", err)
+	}
+	outcomes = append(outcomes, o)
+	if len(outcomes) > maxCommandOutcomes {
+		outcomes = outcomes[len(outcomes)-maxCommandOutcomes:]
+	}
+	if err := stateStore.Put(ctx, commandOutcomesKey, outcomes); err != nil {
+		log.Printf("Failed to save command outcomes: %v", err)
+	}
+}
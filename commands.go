@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	mentionSinceKey = "mention_since_id"
+	mentionPageSize = 30
+)
+
+var mentionsMu sync.Mutex
+
+const (
+	roleViewer   = "viewer"
+	roleOperator = "operator"
+	roleAdmin    = "admin"
+)
+
+var roleRanks = map[string]int{roleViewer: 1, roleOperator: 2, roleAdmin: 3}
+
+var commandActions = map[string]string{
+	"status": "status",
+	"on":     "turnOn",
+	"off":    "turnOff",
+	"press":  "press",
+	"lock":   "lock",
+	"unlock": "unlock",
+	"状態":     "status",
+	"オン":     "turnOn",
+	"オフ":     "turnOff",
+	"施錠":     "lock",
+	"解錠":     "unlock",
+}
+
+var actionRoles = map[string]string{
+	"status":  roleViewer,
+	"turnOn":  roleOperator,
+	"turnOff": roleOperator,
+	"press":   roleOperator,
+	"lock":    roleOperator,
+	"unlock":  roleAdmin,
+}
+
+type mastodonNotification struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Account struct {
+		Acct string `json:"acct"`
+	} `json:"account"`
+	Status *struct {
+		ID         string `json:"id"`
+		Content    string `json:"content"`
+		Visibility string `json:"visibility"`
+	} `json:"status"`
+}
+
+type switchBotCommand struct {
+	Command     string `json:"command"`
+	Parameter   string `json:"parameter"`
+	CommandType string `json:"commandType"`
+}
+
+// processMentions only records the newest mention on the first run, so old
+// ones are never replayed against real devices.
+func processMentions(ctx context.Context) {
+	if !config.CommandsEnabled || config.MastodonURL == "" {
+		return
+	}
+	mentionsMu.Lock()
+	defer mentionsMu.Unlock()
+
+	var sinceID string
+	if _, err := stateStore.Get(ctx, mentionSinceKey, &sinceID); err != nil {
+		log.Printf("Failed to load mention cursor: %v", err)
+		return
+	}
+	// since_id would return the newest page and skip mentions before it.
+	var devices []SwitchBotDevice
+	for {
+		query := url.Values{"types[]": {"mention"}, "limit": {strconv.Itoa(mentionPageSize)}}
+		if sinceID != "" {
+			query.Set("min_id", sinceID)
+		}
+		var notifications []mastodonNotification
+		if err := httpGet(ctx, "/notifications?"+query.Encode(), &notifications); err != nil {
+			log.Printf("Failed to fetch mentions: %v", err)
+			return
+		}
+		if len(notifications) == 0 {
+			return
+		}
+		if sinceID != "" && !runMentionCommands(ctx, notifications, &devices) {
+			return
+		}
+		if err := stateStore.Put(ctx, mentionSinceKey, notifications[0].ID); err != nil {
+			log.Printf("Failed to save mention cursor: %v", err)
+			return
+		}
+		if sinceID == "" || len(notifications) < mentionPageSize {
+			return
+		}
+		sinceID = notifications[0].ID
+	}
+}
+
+func runMentionCommands(ctx context.Context, notifications []mastodonNotification, devices *[]SwitchBotDevice) bool {
+	for _, n := range slices.Backward(notifications) {
+		if n.Type != "mention" || n.Status == nil {
+			continue
+		}
+		if *devices == nil {
+			list, err := fetchDeviceList(ctx)
+			if err != nil {
+				log.Printf("Failed to fetch devices for commands: %v", err)
+				return false
+			}
+			*devices = append(list.DeviceList, list.InfraredRemoteList...)
+		}
+		reply := handleMentionCommand(ctx, n, *devices)
+		payload := map[string]any{
+			"status":         "@" + n.Account.Acct + " " + reply,
+			"in_reply_to_id": n.Status.ID,
+			"visibility":     n.Status.Visibility,
+		}
+		if _, err := postMastodonStatus(ctx, payload); err != nil {
+			log.Printf("Failed to reply to command from %s: %v", n.Account.Acct, err)
+		}
+	}
+	return true
+}
+
+func handleMentionCommand(ctx context.Context, n mastodonNotification, devices []SwitchBotDevice) string {
+	role := commandRole(n.Account.Acct)
+	if role == "" {
+		auditCommand(n.Account.Acct, role, "", "", "denied")
+		return "🚫 このアカウントからの操作は許可されていません"
+	}
+	name, action, ok := parseMentionCommand(n.Status.Content)
+	if !ok {
+		return "使い方: <デバイス名> status|on|off|press|lock|unlock"
+	}
+	if strings.EqualFold(name, "away") || name == "留守" {
+		return handleAwayCommand(ctx, n.Account.Acct, role, action)
+	}
+	device, ok := findDevice(devices, name)
+	if !ok {
+		return fmt.Sprintf("❓ デバイス「%s」が見つかりません", name)
+	}
+	if roleRanks[role] < roleRanks[actionRoles[action]] {
+		auditCommand(n.Account.Acct, role, device.DeviceName, action, "denied")
+		return fmt.Sprintf("🚫 %s の権限では %s は実行できません", role, action)
+	}
+	if action == "status" {
+		status, err := fetchDeviceStatus(ctx, device)
+		if err != nil {
+			auditCommand(n.Account.Acct, role, device.DeviceName, action, "failed")
+			return fmt.Sprintf("❌ %s: 状態の取得に失敗しました", device.DeviceName)
+		}
+		auditCommand(n.Account.Acct, role, device.DeviceName, action, "ok")
+		return device.DeviceName + "\n" + describeStatus(calibrateStatus(device, status))
+	}
+	result := enqueueCommand(ctx, device, action, "mention:"+n.Account.Acct)
+	auditCommand(n.Account.Acct, role, device.DeviceName, action, result)
+	switch result {
+	case commandVerified:
+		return fmt.Sprintf("✅ %s: %s（状態を確認しました）", device.DeviceName, action)
+	case commandUnverified:
+		return fmt.Sprintf("✅ %s: %s", device.DeviceName, action)
+	case commandRetrying:
+		return fmt.Sprintf("⏳ %s: %s を確認できなかったため再試行します", device.DeviceName, action)
+	}
+	return fmt.Sprintf("❌ %s: %s に失敗しました", device.DeviceName, action)
+}
+
+func handleAwayCommand(ctx context.Context, acct, role, action string) string {
+	if action != "status" && roleRanks[role] < roleRanks[roleOperator] {
+		auditCommand(acct, role, "away", action, "denied")
+		return fmt.Sprintf("🚫 %s の権限では留守モードを切り替えられません", role)
+	}
+	var err error
+	switch action {
+	case "turnOn":
+		err = setAwayMode(ctx, true, time.Time{})
+	case "turnOff":
+		err = setAwayMode(ctx, false, time.Time{})
+	case "status":
+	default:
+		return "使い方: away on|off|status"
+	}
+	if err != nil {
+		auditCommand(acct, role, "away", action, "failed")
+		return "❌ 留守モードの切り替えに失敗しました"
+	}
+	s, err := loadAwayMode(ctx, time.Now())
+	if err != nil {
+		return "❌ 留守モードの状態を取得できませんでした"
+	}
+	auditCommand(acct, role, "away", action, "ok")
+	return describeAwayMode(s)
+}
+
+func commandRole(acct string) string {
+	if role, ok := config.CommandRoles[acct]; ok {
+		return role
+	}
+	if slices.Contains(config.CommandAccounts, acct) {
+		return roleOperator
+	}
+	return ""
+}
+
+func validateCommandRoles(roles map[string]string) error {
+	for acct, role := range roles {
+		if _, ok := roleRanks[role]; !ok {
+			return fmt.Errorf("unknown role %q for %s", role, acct)
+		}
+	}
+	return nil
+}
+
+func auditCommand(acct, role, device, action, result string) {
+	b, err := json.Marshal(struct {
+		Type      string    `json:"type"`
+		Account   string    `json:"account"`
+		Role      string    `json:"role,omitempty"`
+		Device    string    `json:"device,omitempty"`
+		Action    string    `json:"action,omitempty"`
+		Result    string    `json:"result"`
+		Timestamp time.Time `json:"timestamp"`
+	}{"Audit", acct, role, device, action, result, time.Now()})
+	if err != nil {
+		log.Printf("Failed to marshal audit log: %v", err)
+		return
+	}
+	fmt.Println(string(b))
+}
+
+func describeStatus(status SwitchBotDeviceStatus) string {
+	var lines []string
+	if status.Temperature != nil {
+		t, unit := displayTemperature(*status.Temperature)
+		lines = append(lines, fmt.Sprintf("%s: %s%s", tr("温度"), formatNumber(t, 1), unit))
+	}
+	if status.Humidity != nil {
+		lines = append(lines, fmt.Sprintf("%s: %s%%", tr("湿度"), formatNumber(*status.Humidity, 1)))
+	}
+	if status.CO2 != nil {
+		lines = append(lines, fmt.Sprintf("CO2: %sppm", formatInt(*status.CO2)))
+	}
+	if status.Power != nil {
+		lines = append(lines, fmt.Sprintf("%s: %sW", tr("電力"), formatNumber(*status.Power, 1)))
+	}
+	lines = append(lines, stateLines(status)...)
+	if len(lines) == 0 {
+		return "取得できる測定値はありません"
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parseMentionCommand(content string) (string, string, bool) {
+	text := html.UnescapeString(stripHTMLTags(strings.NewReplacer("<br>", " ", "<br />", " ", "</p>", " ").Replace(content)))
+	var words []string
+	for _, w := range strings.Fields(text) {
+		if !strings.HasPrefix(w, "@") {
+			words = append(words, w)
+		}
+	}
+	if len(words) < 2 {
+		return "", "", false
+	}
+	action, ok := commandActions[strings.ToLower(words[len(words)-1])]
+	if !ok {
+		return "", "", false
+	}
+	return strings.Join(words[:len(words)-1], " "), action, true
+}
+
+func findDevice(devices []SwitchBotDevice, name string) (SwitchBotDevice, bool) {
+	for _, d := range devices {
+		if strings.EqualFold(d.DeviceName, name) || d.DeviceID == name {
+			return d, true
+		}
+	}
+	return SwitchBotDevice{}, false
+}
+
+func sendDeviceCommand(ctx context.Context, device SwitchBotDevice, command string) error {
+	payload, err := json.Marshal(switchBotCommand{Command: command, Parameter: "default", CommandType: "command"})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("https://api.switch-bot.com/v1.1/devices/%s/commands", device.DeviceID)
+	var resp SwitchBotResponse[json.RawMessage]
+	return switchBotRequest(ctx, "POST", url, payload, &resp)
+}
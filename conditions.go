@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type ConditionSpec struct {
+	Type        string
+	Device      string
+	Metric      string
+	Operator    string
+	Value       float64
+	OtherDevice string
+	OtherMetric string
+	Minutes     int
+	Start       string
+	End         string
+	Weekdays    []string
+	Conditions  []string
+
+	name string
+}
+
+type Condition interface {
+	Evaluate(in conditionInput) bool
+}
+
+type conditionInput struct {
+	Device  SwitchBotDevice
+	Status  SwitchBotDeviceStatus
+	History []SwitchBotDeviceStatus
+	Latest  map[string]SwitchBotDeviceStatus
+	Timers  conditionTimers
+	Now     time.Time
+}
+
+func (in conditionInput) statusOf(device string) (SwitchBotDeviceStatus, bool) {
+	if device == "" || device == in.Device.DeviceID || device == in.Device.DeviceName {
+		return in.Status, true
+	}
+	status, ok := in.Latest[device]
+	return status, ok
+}
+
+type conditionTimers map[string]time.Time
+
+func (t conditionTimers) track(key string, active bool, now time.Time) time.Time {
+	if !active {
+		delete(t, key)
+		return now
+	}
+	if since, ok := t[key]; ok {
+		return since
+	}
+	t[key] = now
+	return now
+}
+
+type conditionResolver func(name string) (Condition, error)
+
+var conditionTypes = map[string]func(spec ConditionSpec, resolve conditionResolver) (Condition, error){
+	"threshold": newThresholdCondition,
+	"compare":   newCompareCondition,
+	"rate":      newRateCondition,
+	"duration":  newDurationCondition,
+	"schedule":  newScheduleCondition,
+	"all":       newCompositeCondition,
+	"any":       newCompositeCondition,
+	"not":       newNotCondition,
+}
+
+var conditions map[string]Condition
+
+func buildConditions(specs map[string]ConditionSpec) (map[string]Condition, error) {
+	built := map[string]Condition{}
+	building := map[string]bool{}
+	var resolve conditionResolver
+	resolve = func(name string) (Condition, error) {
+		if c, ok := built[name]; ok {
+			return c, nil
+		}
+		spec, ok := specs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown condition %q", name)
+		}
+		if building[name] {
+			return nil, fmt.Errorf("condition %q references itself", name)
+		}
+		factory, ok := conditionTypes[spec.Type]
+		if !ok {
+			return nil, fmt.Errorf("condition %q has unknown type %q", name, spec.Type)
+		}
+		building[name] = true
+		spec.name = name
+		c, err := factory(spec, resolve)
+		building[name] = false
+		if err != nil {
+			return nil, fmt.Errorf("condition %q: %w", name, err)
+		}
+		built[name] = c
+		return c, nil
+	}
+	for name := range specs {
+		if _, err := resolve(name); err != nil {
+			return nil, err
+		}
+	}
+	return built, nil
+}
+
+func metricValue(status SwitchBotDeviceStatus, metric string) (float64, bool) {
+	switch strings.ToLower(metric) {
+	case "temperature":
+		if status.Temperature != nil {
+			return *status.Temperature, true
+		}
+	case "humidity":
+		if status.Humidity != nil {
+			return *status.Humidity, true
+		}
+	case "co2":
+		if status.CO2 != nil {
+			return float64(*status.CO2), true
+		}
+	case "battery":
+		if status.Battery != nil {
+			return float64(*status.Battery), true
+		}
+	case "lightlevel":
+		if status.LightLevel != nil {
+			return float64(*status.LightLevel), true
+		}
+	case "power":
+		if status.Power != nil {
+			return *status.Power, true
+		}
+	case "voltage":
+		if status.Voltage != nil {
+			return *status.Voltage, true
+		}
+	case "dewpoint":
+		if dew, _, _, ok := comfortValues(status); ok {
+			return dew, true
+		}
+	case "absolutehumidity":
+		if _, abs, _, ok := comfortValues(status); ok {
+			return abs, true
+		}
+	case "discomfortindex":
+		if status.Temperature != nil && status.Humidity != nil {
+			return discomfortIndex(*status.Temperature, *status.Humidity), true
+		}
+	case "wbgt":
+		if _, _, heat, ok := comfortValues(status); ok {
+			return heat, true
+		}
+	}
+	return 0, false
+}
+
+func compare(operator string, a, b float64) (bool, error) {
+	switch operator {
+	case ">":
+		return a > b, nil
+	case ">=":
+		return a >= b, nil
+	case "<":
+		return a < b, nil
+	case "<=":
+		return a <= b, nil
+	case "==":
+		return a == b, nil
+	case "!=":
+		return a != b, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", operator)
+}
+
+type thresholdCondition struct {
+	device   string
+	metric   string
+	operator string
+	value    float64
+}
+
+func newThresholdCondition(spec ConditionSpec, _ conditionResolver) (Condition, error) {
+	if _, err := compare(spec.Operator, 0, 0); err != nil {
+		return nil, err
+	}
+	if !isKnownMetric(spec.Metric) {
+		return nil, fmt.Errorf("unknown metric %q", spec.Metric)
+	}
+	value := spec.Value
+	if isTemperature(spec.Metric) {
+		value = celsiusFromConfig(value)
+	}
+	return thresholdCondition{device: spec.Device, metric: spec.Metric, operator: spec.Operator, value: value}, nil
+}
+
+func (c thresholdCondition) Evaluate(in conditionInput) bool {
+	status, ok := in.statusOf(c.device)
+	if !ok {
+		return false
+	}
+	v, ok := metricValue(status, c.metric)
+	if !ok {
+		return false
+	}
+	matched, _ := compare(c.operator, v, c.value)
+	return matched
+}
+
+type compareCondition struct {
+	device, metric           string
+	otherDevice, otherMetric string
+	operator                 string
+	offset                   float64
+}
+
+func newCompareCondition(spec ConditionSpec, _ conditionResolver) (Condition, error) {
+	if _, err := compare(spec.Operator, 0, 0); err != nil {
+		return nil, err
+	}
+	if spec.OtherDevice == "" {
+		return nil, fmt.Errorf("compare requires OtherDevice")
+	}
+	otherMetric := spec.OtherMetric
+	if otherMetric == "" {
+		otherMetric = spec.Metric
+	}
+	for _, metric := range []string{spec.Metric, otherMetric} {
+		if !isKnownMetric(metric) {
+			return nil, fmt.Errorf("unknown metric %q", metric)
+		}
+	}
+	return compareCondition{
+		device:      spec.Device,
+		metric:      spec.Metric,
+		otherDevice: spec.OtherDevice,
+		otherMetric: otherMetric,
+		operator:    spec.Operator,
+		offset:      celsiusDelta(spec.Metric, spec.Value),
+	}, nil
+}
+
+func (c compareCondition) Evaluate(in conditionInput) bool {
+	status, ok := in.statusOf(c.device)
+	if !ok {
+		return false
+	}
+	other, ok := in.statusOf(c.otherDevice)
+	if !ok {
+		return false
+	}
+	v, ok := metricValue(status, c.metric)
+	if !ok {
+		return false
+	}
+	w, ok := metricValue(other, c.otherMetric)
+	if !ok {
+		return false
+	}
+	matched, _ := compare(c.operator, v, w+c.offset)
+	return matched
+}
+
+func isKnownMetric(metric string) bool {
+	return slices.Contains([]string{"temperature", "humidity", "co2", "battery", "lightlevel", "power", "voltage", "dewpoint", "absolutehumidity", "wbgt", "discomfortindex"}, strings.ToLower(metric))
+}
+
+func isTemperature(metric string) bool {
+	return strings.EqualFold(metric, "temperature") || strings.EqualFold(metric, "dewpoint") || strings.EqualFold(metric, "wbgt")
+}
+
+// celsiusDelta needs no offset, unlike absolute thresholds.
+func celsiusDelta(metric string, v float64) float64 {
+	if isTemperature(metric) && configuredInFahrenheit() {
+		return v * 5 / 9
+	}
+	return v
+}
+
+type rateCondition struct {
+	metric   string
+	operator string
+	value    float64
+	window   time.Duration
+}
+
+func newRateCondition(spec ConditionSpec, _ conditionResolver) (Condition, error) {
+	if _, err := compare(spec.Operator, 0, 0); err != nil {
+		return nil, err
+	}
+	if !isKnownMetric(spec.Metric) {
+		return nil, fmt.Errorf("unknown metric %q", spec.Metric)
+	}
+	if spec.Minutes <= 0 {
+		return nil, fmt.Errorf("rate requires a positive Minutes window")
+	}
+	return rateCondition{
+		metric:   spec.Metric,
+		operator: spec.Operator,
+		value:    celsiusDelta(spec.Metric, spec.Value),
+		window:   time.Duration(spec.Minutes) * time.Minute,
+	}, nil
+}
+
+func (c rateCondition) Evaluate(in conditionInput) bool {
+	current, ok := metricValue(in.Status, c.metric)
+	if !ok {
+		return false
+	}
+	since := in.Now.Add(-c.window)
+	for _, past := range in.History {
+		if past.ReadAt.Before(since) {
+			continue
+		}
+		previous, ok := metricValue(past, c.metric)
+		if !ok {
+			continue
+		}
+		matched, _ := compare(c.operator, current-previous, c.value)
+		return matched
+	}
+	return false
+}
+
+type durationCondition struct {
+	key       string
+	condition Condition
+	duration  time.Duration
+}
+
+func newDurationCondition(spec ConditionSpec, resolve conditionResolver) (Condition, error) {
+	if len(spec.Conditions) != 1 {
+		return nil, fmt.Errorf("duration requires exactly one condition")
+	}
+	if spec.Minutes <= 0 {
+		return nil, fmt.Errorf("duration requires a positive Minutes value")
+	}
+	child, err := resolve(spec.Conditions[0])
+	if err != nil {
+		return nil, err
+	}
+	return durationCondition{
+		key:       spec.name,
+		condition: child,
+		duration:  time.Duration(spec.Minutes) * time.Minute,
+	}, nil
+}
+
+func (c durationCondition) Evaluate(in conditionInput) bool {
+	active := c.condition.Evaluate(in)
+	if in.Timers == nil {
+		return false
+	}
+	since := in.Timers.track(c.key, active, in.Now)
+	return active && in.Now.Sub(since) >= c.duration
+}
+
+type scheduleCondition struct {
+	start, end int
+	weekdays   map[time.Weekday]bool
+}
+
+func newScheduleCondition(spec ConditionSpec, _ conditionResolver) (Condition, error) {
+	start, err := parseClock(spec.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(spec.End)
+	if err != nil {
+		return nil, err
+	}
+	c := scheduleCondition{start: start, end: end}
+	if len(spec.Weekdays) > 0 {
+		c.weekdays = map[time.Weekday]bool{}
+		for _, name := range spec.Weekdays {
+			day, err := parseWeekday(name)
+			if err != nil {
+				return nil, err
+			}
+			c.weekdays[day] = true
+		}
+	}
+	return c, nil
+}
+
+func (c scheduleCondition) Evaluate(in conditionInput) bool {
+	now := in.Now.In(timeLocation())
+	if c.weekdays != nil && !c.weekdays[now.Weekday()] {
+		return false
+	}
+	return inClockRange(now.Hour()*60+now.Minute(), c.start, c.end)
+}
+
+func inClockRange(minute, start, end int) bool {
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) || strings.EqualFold(s, d.String()[:3]) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", s)
+}
+
+type compositeCondition struct {
+	all        bool
+	conditions []Condition
+}
+
+func newCompositeCondition(spec ConditionSpec, resolve conditionResolver) (Condition, error) {
+	if len(spec.Conditions) == 0 {
+		return nil, fmt.Errorf("%s requires at least one condition", spec.Type)
+	}
+	c := compositeCondition{all: spec.Type == "all"}
+	for _, name := range spec.Conditions {
+		child, err := resolve(name)
+		if err != nil {
+			return nil, err
+		}
+		c.conditions = append(c.conditions, child)
+	}
+	return c, nil
+}
+
+func (c compositeCondition) Evaluate(in conditionInput) bool {
+	result := c.all
+	// Evaluate every child so that duration timers stay up to date.
+	for _, child := range c.conditions {
+		if child.Evaluate(in) != c.all {
+			result = !c.all
+		}
+	}
+	return result
+}
+
+type notCondition struct {
+	condition Condition
+}
+
+func newNotCondition(spec ConditionSpec, resolve conditionResolver) (Condition, error) {
+	if len(spec.Conditions) != 1 {
+		return nil, fmt.Errorf("not requires exactly one condition")
+	}
+	child, err := resolve(spec.Conditions[0])
+	if err != nil {
+		return nil, err
+	}
+	return notCondition{condition: child}, nil
+}
+
+func (c notCondition) Evaluate(in conditionInput) bool {
+	return !c.condition.Evaluate(in)
+}
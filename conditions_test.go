@@ -0,0 +1,163 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		operator string
+		a, b     float64
+		want     bool
+		wantErr  bool
+	}{
+		{">", 2, 1, true, false},
+		{">", 1, 1, false, false},
+		{">=", 1, 1, true, false},
+		{"<", 1, 2, true, false},
+		{"<=", 2, 1, false, false},
+		{"==", 1, 1, true, false},
+		{"!=", 1, 1, false, false},
+		{"=>", 1, 1, false, true},
+	}
+	for _, tt := range tests {
+		got, err := compare(tt.operator, tt.a, tt.b)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("compare(%q, %v, %v) = %v, %v", tt.operator, tt.a, tt.b, got, err)
+		}
+	}
+}
+
+func TestInClockRange(t *testing.T) {
+	tests := []struct {
+		minute, start, end int
+		want               bool
+	}{
+		{600, 540, 1020, true},
+		{1020, 540, 1020, false},
+		{500, 540, 1020, false},
+		{1380, 1320, 360, true},
+		{120, 1320, 360, true},
+		{720, 1320, 360, false},
+	}
+	for _, tt := range tests {
+		if got := inClockRange(tt.minute, tt.start, tt.end); got != tt.want {
+			t.Errorf("inClockRange(%d, %d, %d) = %v, want %v", tt.minute, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestConditions(t *testing.T) {
+	now := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC) // a Monday
+	reading := func(temp, humidity float64, at time.Time) SwitchBotDeviceStatus {
+		return SwitchBotDeviceStatus{Temperature: &temp, Humidity: &humidity, ReadAt: at}
+	}
+	specs := map[string]ConditionSpec{
+		"hot":       {Type: "threshold", Metric: "temperature", Operator: ">=", Value: 28},
+		"humid":     {Type: "threshold", Metric: "humidity", Operator: ">", Value: 60},
+		"warmer":    {Type: "compare", Metric: "temperature", OtherDevice: "outside", Operator: ">", Value: 2},
+		"rising":    {Type: "rate", Metric: "temperature", Operator: ">=", Value: 2, Minutes: 30},
+		"weekday":   {Type: "schedule", Start: "09:00", End: "18:00", Weekdays: []string{"Mon", "Tuesday"}},
+		"night":     {Type: "schedule", Start: "22:00", End: "06:00"},
+		"muggy":     {Type: "all", Conditions: []string{"hot", "humid"}},
+		"either":    {Type: "any", Conditions: []string{"hot", "humid"}},
+		"notHot":    {Type: "not", Conditions: []string{"hot"}},
+		"stillHot":  {Type: "duration", Conditions: []string{"hot"}, Minutes: 10},
+		"onOutside": {Type: "threshold", Device: "outside", Metric: "temperature", Operator: "<", Value: 10},
+	}
+	oldConfig := config
+	t.Cleanup(func() { config = oldConfig })
+	config = defaultConfig()
+	config.TimeZone = "UTC"
+	built, err := buildConditions(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		condition string
+		status    SwitchBotDeviceStatus
+		history   []SwitchBotDeviceStatus
+		outside   float64
+		want      bool
+	}{
+		{"threshold met", "hot", reading(28, 50, now), nil, 20, true},
+		{"threshold not met", "hot", reading(27.9, 50, now), nil, 20, false},
+		{"threshold on other device", "onOutside", reading(20, 50, now), nil, 5, true},
+		{"compare with offset", "warmer", reading(23, 50, now), nil, 20, true},
+		{"compare within offset", "warmer", reading(22, 50, now), nil, 20, false},
+		{"rate rising", "rising", reading(25, 50, now), []SwitchBotDeviceStatus{reading(22, 50, now.Add(-20*time.Minute))}, 20, true},
+		{"rate outside window", "rising", reading(25, 50, now), []SwitchBotDeviceStatus{reading(22, 50, now.Add(-time.Hour))}, 20, false},
+		{"schedule in range", "weekday", reading(20, 50, now), nil, 20, true},
+		{"schedule overnight", "night", reading(20, 50, now), nil, 20, false},
+		{"all met", "muggy", reading(29, 70, now), nil, 20, true},
+		{"all partly met", "muggy", reading(29, 50, now), nil, 20, false},
+		{"any partly met", "either", reading(20, 70, now), nil, 20, true},
+		{"not", "notHot", reading(20, 50, now), nil, 20, true},
+		{"duration without timers", "stillHot", reading(30, 50, now), nil, 20, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outside := reading(tt.outside, 50, now)
+			in := conditionInput{
+				Device:  SwitchBotDevice{DeviceID: "room", DeviceName: "Room"},
+				Status:  tt.status,
+				History: tt.history,
+				Latest:  map[string]SwitchBotDeviceStatus{"outside": outside},
+				Now:     now,
+			}
+			if got := built[tt.condition].Evaluate(in); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.condition, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationCondition(t *testing.T) {
+	c, err := buildConditions(map[string]ConditionSpec{
+		"hot":      {Type: "threshold", Metric: "temperature", Operator: ">", Value: 28},
+		"stillHot": {Type: "duration", Conditions: []string{"hot"}, Minutes: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	timers := conditionTimers{}
+	steps := []struct {
+		minutes int
+		temp    float64
+		want    bool
+	}{
+		{0, 30, false},
+		{5, 30, false},
+		{10, 30, true},
+		{11, 20, false},
+		{12, 30, false},
+		{22, 30, true},
+	}
+	for _, s := range steps {
+		temp := s.temp
+		in := conditionInput{Status: SwitchBotDeviceStatus{Temperature: &temp}, Timers: timers, Now: start.Add(time.Duration(s.minutes) * time.Minute)}
+		if got := c["stillHot"].Evaluate(in); got != s.want {
+			t.Errorf("at +%dm with %v: got %v, want %v", s.minutes, s.temp, got, s.want)
+		}
+	}
+}
+
+func TestBuildConditionsErrors(t *testing.T) {
+	tests := map[string]map[string]ConditionSpec{
+		"unknown type":     {"a": {Type: "sometimes"}},
+		"unknown metric":   {"a": {Type: "threshold", Metric: "noise", Operator: ">"}},
+		"unknown operator": {"a": {Type: "threshold", Metric: "temperature", Operator: "~"}},
+		"unknown child":    {"a": {Type: "not", Conditions: []string{"b"}}},
+		"cycle":            {"a": {Type: "not", Conditions: []string{"b"}}, "b": {Type: "not", Conditions: []string{"a"}}},
+		"bad clock":        {"a": {Type: "schedule", Start: "9am", End: "18:00"}},
+		"rate window":      {"a": {Type: "rate", Metric: "temperature", Operator: ">"}},
+	}
+	for name, specs := range tests {
+		if _, err := buildConditions(specs); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+type Config struct {
+	SwitchBotToken             string
+	SwitchBotSecret            string
+	SwitchBotClockSync         bool
+	SwitchBotMaxSkewSeconds    int
+	SwitchBotMaxAttempts       int
+	SwitchBotRetryBaseMillis   int
+	MastodonURL                string
+	MastodonToken              string
+	MastodonAccountID          string
+	MisskeyURL                 string
+	MisskeyToken               string
+	BlueskyHandle              string
+	BlueskyAppPassword         string
+	BlueskyPDS                 string
+	TargetDeviceTypes          []string
+	DeviceAllowlist            []string
+	DeviceDenylist             []string
+	BatteryCheckPostCount      int
+	BatteryForecastDays        int
+	BatteryTiers               []BatteryTier
+	BatteryStaleEmoji          string
+	TokenCheckHours            int
+	BreakGlassNtfyURL          string
+	BreakGlassNtfyToken        string
+	PushNtfyURL                string
+	PushNtfyToken              string
+	PushoverToken              string
+	PushoverUser               string
+	SNSTopicARN                string
+	EmailDigestFrom            string
+	EmailDigestTo              []string
+	MQTTBrokerURL              string
+	MQTTUsername               string
+	MQTTPassword               string
+	MQTTClientID               string
+	MQTTCAFile                 string
+	MQTTCertFile               string
+	MQTTKeyFile                string
+	MQTTTopicPrefix            string
+	MQTTDiscoveryPrefix        string
+	HTTPMaxIdleConns           int
+	HTTPIdleConnTimeoutSeconds int
+	HTTPForceHTTP2             bool
+	HTTPTimeoutSeconds         int
+	HTTPProxy                  string
+	DestinationBudgets         map[string]DestinationBudget
+	DeadlineReserveSeconds     int
+	FetchConcurrency           int
+	StateFile                  string
+	StateTable                 string
+	RoleARN                    string
+	MetricsBackend             string
+	MetricsNamespace           string
+	MetricsDestinations        []MetricsDestination
+	Rooms                      map[string]string
+	TimestreamDatabase         string
+	TimestreamTable            string
+	PrometheusURL              string
+	PrometheusUsername         string
+	PrometheusPassword         string
+	PrometheusJob              string
+	PluginDir                  string
+	FormatterWASM              string
+	TimeZone                   string
+	Locale                     string
+	TemperatureUnit            string
+	TemperatureUnitMetric      bool
+	ComfortMetrics             bool
+	DiscomfortIndex            bool
+	QualityNotes               bool
+	GapPolicy                  string
+	HistoryHours               int
+	OfficeStatsWeeks           int
+	MetricBufferDays           int
+	DaemonListen               string
+	DaemonIntervalMinutes      int
+	ReadinessCacheSeconds      int
+	ConfigReloadSeconds        int
+	BLEEnabled                 bool
+	BLEMaxAgeSeconds           int
+	BLEMaxTemperatureDelta     float64
+	BLEMaxHumidityDelta        float64
+	DashboardToken             string
+	GuestTokenSecret           string
+	GRPCListen                 string
+	GraphQLEnabled             bool
+	AlertmanagerEnabled        bool
+	AlertmanagerToken          string
+	WebhookToken               string
+	KioskEnabled               bool
+	KioskFields                []string
+	KioskDevices               []string
+	KioskSecret                string
+	PagerDutyRoutingKey        string
+	MatrixHomeserver           string
+	MatrixAccessToken          string
+	MatrixRoomID               string
+	XMPPJID                    string
+	XMPPPassword               string
+	XMPPServer                 string
+	XMPPRecipient              string
+	Notifier                   string
+	SlackWebhookURL            string
+	GoogleChatWebhookURL       string
+	TeamsWebhookURL            string
+	PostVisibility             string
+	SpoilerText                string
+	AlertSpoilerText           string
+	Hashtags                   []string
+	UrgentVisibility           string
+	UrgentMention              string
+	DeviceThreads              bool
+	PinnedStatus               string
+	ProfileFields              []string
+	ChartEnabled               bool
+	ChartHour                  int
+	OpsSummaryEnabled          bool
+	OpsSummaryMention          string
+	SwitchBotBudgetReserve     int
+	LowPriorityDevices         []string
+	ReleaseCheck               bool
+	ReleaseRepo                string
+	CommandsEnabled            bool
+	CommandAccounts            []string
+	CommandRoles               map[string]string
+	CommandPollSeconds         int
+	ScenesDryRun               bool
+	Chaos                      *ChaosConfig
+	Office                     *OfficeProfile
+	EnergyAdvisor              *EnergyAdvisor
+	TimeOfUse                  *TimeOfUse
+	Away                       *AwayProfile
+	QuietMode                  *QuietMode
+	QuietHours                 *QuietHours
+	Conditions                 map[string]ConditionSpec
+	Alerts                     []AlertRule
+	DerivedMetrics             []DerivedMetric
+	Filters                    *ReadingFilters
+	Calibration                map[string]CalibrationOffset
+	Scenes                     []SceneBinding
+}
+
+func defaultConfig() Config {
+	return Config{
+		SwitchBotMaxSkewSeconds:    30,
+		SwitchBotMaxAttempts:       5,
+		SwitchBotRetryBaseMillis:   1000,
+		TargetDeviceTypes:          slices.Clone(defaultTargetDeviceTypes),
+		TokenCheckHours:            24,
+		BatteryTiers:               slices.Clone(defaultBatteryTiers),
+		BatteryStaleEmoji:          "💤",
+		HTTPMaxIdleConns:           100,
+		HTTPIdleConnTimeoutSeconds: 90,
+		HTTPForceHTTP2:             true,
+		HTTPTimeoutSeconds:         30,
+		DeadlineReserveSeconds:     10,
+		FetchConcurrency:           4,
+		StateFile:                  "state.json",
+		MetricsBackend:             "log",
+		MetricsNamespace:           "SwitchBotMetrics",
+		TimeZone:                   "Asia/Tokyo",
+		HistoryHours:               24,
+		OfficeStatsWeeks:           12,
+		MetricBufferDays:           14,
+		DaemonListen:               ":8080",
+		DaemonIntervalMinutes:      5,
+		ReadinessCacheSeconds:      300,
+		ConfigReloadSeconds:        30,
+		BLEMaxAgeSeconds:           300,
+		BLEMaxTemperatureDelta:     1.0,
+		BLEMaxHumidityDelta:        5,
+		PostVisibility:             "unlisted",
+		UrgentVisibility:           "public",
+		KioskFields:                []string{"temperature", "co2"},
+		CommandPollSeconds:         30,
+		ChartHour:                  8,
+		GapPolicy:                  gapIgnore,
+		SwitchBotBudgetReserve:     1000,
+		ReleaseRepo:                "shinderuman/switchbot_bot",
+		MQTTClientID:               "switchbot_bot",
+		PrometheusJob:              "switchbot_bot",
+		MQTTTopicPrefix:            "switchbot",
+		MQTTDiscoveryPrefix:        "homeassistant",
+	}
+}
+
+func loadConfig() error {
+	config = defaultConfig()
+	if isLambda() {
+		if envPostCount := os.Getenv("BATTERY_CHECK_POST_COUNT"); envPostCount != "" {
+			if count, err := strconv.Atoi(envPostCount); err == nil && count > 0 {
+				batteryCheckPostCount = count
+			}
+		}
+		config.SwitchBotToken = os.Getenv("SWITCHBOT_API_TOKEN")
+		config.SwitchBotSecret = os.Getenv("SWITCHBOT_API_SECRET")
+		config.SwitchBotClockSync = envBool("SWITCHBOT_CLOCK_SYNC", config.SwitchBotClockSync)
+		config.SwitchBotMaxSkewSeconds = envInt("SWITCHBOT_MAX_SKEW_SECONDS", config.SwitchBotMaxSkewSeconds)
+		config.SwitchBotMaxAttempts = envInt("SWITCHBOT_MAX_ATTEMPTS", config.SwitchBotMaxAttempts)
+		config.SwitchBotRetryBaseMillis = envIntAllowZero("SWITCHBOT_RETRY_BASE_MILLIS", config.SwitchBotRetryBaseMillis)
+		config.MastodonURL = os.Getenv("MASTODON_API_URL")
+		config.MastodonToken = os.Getenv("MASTODON_ACCESS_TOKEN")
+		config.MastodonAccountID = os.Getenv("MASTODON_ACCOUNT_ID")
+		config.MisskeyURL = os.Getenv("MISSKEY_URL")
+		config.MisskeyToken = os.Getenv("MISSKEY_TOKEN")
+		config.BlueskyHandle = os.Getenv("BLUESKY_HANDLE")
+		config.BlueskyAppPassword = os.Getenv("BLUESKY_APP_PASSWORD")
+		config.BlueskyPDS = os.Getenv("BLUESKY_PDS")
+		config.TargetDeviceTypes = envList("TARGET_DEVICE_TYPES", config.TargetDeviceTypes)
+		config.DeviceAllowlist = envList("DEVICE_ALLOWLIST", nil)
+		config.DeviceDenylist = envList("DEVICE_DENYLIST", nil)
+		config.BatteryCheckPostCount = batteryCheckPostCount
+		config.BatteryForecastDays = envInt("BATTERY_FORECAST_DAYS", config.BatteryForecastDays)
+		config.BatteryStaleEmoji = envString("BATTERY_STALE_EMOJI", config.BatteryStaleEmoji)
+		config.TokenCheckHours = envInt("TOKEN_CHECK_HOURS", config.TokenCheckHours)
+		config.BreakGlassNtfyURL = os.Getenv("BREAK_GLASS_NTFY_URL")
+		config.BreakGlassNtfyToken = os.Getenv("BREAK_GLASS_NTFY_TOKEN")
+		config.PushNtfyURL = os.Getenv("PUSH_NTFY_URL")
+		config.PushNtfyToken = os.Getenv("PUSH_NTFY_TOKEN")
+		config.PushoverToken = os.Getenv("PUSHOVER_TOKEN")
+		config.PushoverUser = os.Getenv("PUSHOVER_USER")
+		config.SNSTopicARN = os.Getenv("SNS_TOPIC_ARN")
+		config.EmailDigestFrom = os.Getenv("EMAIL_DIGEST_FROM")
+		config.EmailDigestTo = envList("EMAIL_DIGEST_TO", nil)
+		config.MQTTBrokerURL = os.Getenv("MQTT_BROKER_URL")
+		config.MQTTUsername = os.Getenv("MQTT_USERNAME")
+		config.MQTTPassword = os.Getenv("MQTT_PASSWORD")
+		config.MQTTClientID = envString("MQTT_CLIENT_ID", config.MQTTClientID)
+		config.MQTTCAFile = os.Getenv("MQTT_CA_FILE")
+		config.MQTTCertFile = os.Getenv("MQTT_CERT_FILE")
+		config.MQTTKeyFile = os.Getenv("MQTT_KEY_FILE")
+		config.MQTTTopicPrefix = envString("MQTT_TOPIC_PREFIX", config.MQTTTopicPrefix)
+		config.MQTTDiscoveryPrefix = envString("MQTT_DISCOVERY_PREFIX", config.MQTTDiscoveryPrefix)
+		config.HTTPMaxIdleConns = envInt("HTTP_MAX_IDLE_CONNS", config.HTTPMaxIdleConns)
+		config.HTTPIdleConnTimeoutSeconds = envInt("HTTP_IDLE_CONN_TIMEOUT_SECONDS", config.HTTPIdleConnTimeoutSeconds)
+		config.HTTPForceHTTP2 = envBool("HTTP_FORCE_HTTP2", config.HTTPForceHTTP2)
+		config.HTTPTimeoutSeconds = envInt("HTTP_TIMEOUT_SECONDS", config.HTTPTimeoutSeconds)
+		config.DeadlineReserveSeconds = envIntAllowZero("DEADLINE_RESERVE_SECONDS", config.DeadlineReserveSeconds)
+		config.HTTPProxy = os.Getenv("HTTP_PROXY_URL")
+		config.FetchConcurrency = envInt("FETCH_CONCURRENCY", config.FetchConcurrency)
+		config.StateFile = envString("STATE_FILE", "/tmp/switchbot_state.json")
+		config.StateTable = os.Getenv("STATE_TABLE")
+		config.RoleARN = os.Getenv("ROLE_ARN")
+		config.MetricsBackend = envString("METRICS_BACKEND", config.MetricsBackend)
+		config.MetricsNamespace = envString("METRICS_NAMESPACE", config.MetricsNamespace)
+		config.TimestreamDatabase = os.Getenv("TIMESTREAM_DATABASE")
+		config.TimestreamTable = os.Getenv("TIMESTREAM_TABLE")
+		config.PrometheusURL = os.Getenv("PROMETHEUS_URL")
+		config.PrometheusUsername = os.Getenv("PROMETHEUS_USERNAME")
+		config.PrometheusPassword = os.Getenv("PROMETHEUS_PASSWORD")
+		config.PrometheusJob = envString("PROMETHEUS_JOB", config.PrometheusJob)
+		config.PluginDir = os.Getenv("PLUGIN_DIR")
+		config.FormatterWASM = os.Getenv("FORMATTER_WASM")
+		config.TimeZone = envString("TIME_ZONE", config.TimeZone)
+		config.Locale = os.Getenv("LOCALE")
+		config.TemperatureUnit = os.Getenv("TEMPERATURE_UNIT")
+		config.TemperatureUnitMetric = envBool("TEMPERATURE_UNIT_METRIC", config.TemperatureUnitMetric)
+		config.ComfortMetrics = envBool("COMFORT_METRICS", config.ComfortMetrics)
+		config.DiscomfortIndex = envBool("DISCOMFORT_INDEX", config.DiscomfortIndex)
+		config.QualityNotes = envBool("QUALITY_NOTES", config.QualityNotes)
+		config.GapPolicy = envString("GAP_POLICY", config.GapPolicy)
+		config.WebhookToken = os.Getenv("WEBHOOK_TOKEN")
+		config.PagerDutyRoutingKey = os.Getenv("PAGERDUTY_ROUTING_KEY")
+		config.MatrixHomeserver = os.Getenv("MATRIX_HOMESERVER")
+		config.MatrixAccessToken = os.Getenv("MATRIX_ACCESS_TOKEN")
+		config.MatrixRoomID = os.Getenv("MATRIX_ROOM_ID")
+		config.XMPPJID = os.Getenv("XMPP_JID")
+		config.XMPPPassword = os.Getenv("XMPP_PASSWORD")
+		config.XMPPServer = os.Getenv("XMPP_SERVER")
+		config.XMPPRecipient = os.Getenv("XMPP_RECIPIENT")
+		config.Notifier = os.Getenv("NOTIFIER")
+		config.SlackWebhookURL = os.Getenv("SLACK_WEBHOOK_URL")
+		config.GoogleChatWebhookURL = os.Getenv("GOOGLE_CHAT_WEBHOOK_URL")
+		config.TeamsWebhookURL = os.Getenv("TEAMS_WEBHOOK_URL")
+		config.HistoryHours = envInt("HISTORY_HOURS", config.HistoryHours)
+		config.OfficeStatsWeeks = envInt("OFFICE_STATS_WEEKS", config.OfficeStatsWeeks)
+		config.MetricBufferDays = envInt("METRIC_BUFFER_DAYS", config.MetricBufferDays)
+		config.PostVisibility = envString("POST_VISIBILITY", config.PostVisibility)
+		config.SpoilerText = os.Getenv("SPOILER_TEXT")
+		config.AlertSpoilerText = os.Getenv("ALERT_SPOILER_TEXT")
+		config.Hashtags = envList("HASHTAGS", nil)
+		config.UrgentVisibility = envString("URGENT_VISIBILITY", config.UrgentVisibility)
+		config.UrgentMention = os.Getenv("URGENT_MENTION")
+		config.DeviceThreads = envBool("DEVICE_THREADS", config.DeviceThreads)
+		config.PinnedStatus = os.Getenv("PINNED_STATUS")
+		config.ProfileFields = envList("PROFILE_FIELDS", nil)
+		config.ChartEnabled = envBool("CHART_ENABLED", config.ChartEnabled)
+		config.ChartHour = envIntAllowZero("CHART_HOUR", config.ChartHour)
+		config.OpsSummaryEnabled = envBool("OPS_SUMMARY_ENABLED", config.OpsSummaryEnabled)
+		config.OpsSummaryMention = os.Getenv("OPS_SUMMARY_MENTION")
+		config.SwitchBotBudgetReserve = envIntAllowZero("SWITCHBOT_BUDGET_RESERVE", config.SwitchBotBudgetReserve)
+		config.LowPriorityDevices = envList("LOW_PRIORITY_DEVICES", nil)
+		config.ReleaseCheck = envBool("RELEASE_CHECK", config.ReleaseCheck)
+		config.ReleaseRepo = envString("RELEASE_REPO", config.ReleaseRepo)
+		config.CommandsEnabled = envBool("COMMANDS_ENABLED", config.CommandsEnabled)
+		config.CommandAccounts = envList("COMMAND_ACCOUNTS", nil)
+		config.ScenesDryRun = envBool("SCENES_DRY_RUN", config.ScenesDryRun)
+		if err := envJSON("CONDITIONS", &config.Conditions); err != nil {
+			return err
+		}
+		if err := envJSON("ROOMS", &config.Rooms); err != nil {
+			return err
+		}
+		if err := envJSON("METRICS_DESTINATIONS", &config.MetricsDestinations); err != nil {
+			return err
+		}
+		if err := envJSON("DESTINATION_BUDGETS", &config.DestinationBudgets); err != nil {
+			return err
+		}
+		if err := envJSON("DERIVED_METRICS", &config.DerivedMetrics); err != nil {
+			return err
+		}
+		if err := envJSON("CALIBRATION", &config.Calibration); err != nil {
+			return err
+		}
+		if err := envJSON("FILTERS", &config.Filters); err != nil {
+			return err
+		}
+		if err := envJSON("ALERTS", &config.Alerts); err != nil {
+			return err
+		}
+		if err := envJSON("SCENES", &config.Scenes); err != nil {
+			return err
+		}
+		if err := envJSON("OFFICE", &config.Office); err != nil {
+			return err
+		}
+		if err := envJSON("ENERGY_ADVISOR", &config.EnergyAdvisor); err != nil {
+			return err
+		}
+		if err := envJSON("TIME_OF_USE", &config.TimeOfUse); err != nil {
+			return err
+		}
+		if err := envJSON("AWAY", &config.Away); err != nil {
+			return err
+		}
+		if err := envJSON("QUIET_MODE", &config.QuietMode); err != nil {
+			return err
+		}
+		if err := envJSON("QUIET_HOURS", &config.QuietHours); err != nil {
+			return err
+		}
+		if err := envJSON("BATTERY_TIERS", &config.BatteryTiers); err != nil {
+			return err
+		}
+		if err := envJSON("CHAOS", &config.Chaos); err != nil {
+			return err
+		}
+		if err := envJSON("COMMAND_ROLES", &config.CommandRoles); err != nil {
+			return err
+		}
+		return loadSecretFiles(os.Getenv("SECRETS_DIR"))
+	}
+	file, err := os.Open(configPath())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := json.NewDecoder(file).Decode(&config); err != nil {
+		return err
+	}
+	return loadSecretFiles(os.Getenv("SECRETS_DIR"))
+}
+
+func envJSON(key string, out any) error {
+	if v := os.Getenv(key); v != "" {
+		if err := json.Unmarshal([]byte(v), out); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func timeLocation() *time.Location {
+	loc, err := time.LoadLocation(config.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// envIntAllowZero is envInt for settings where 0 means something, such as
+// midnight for an hour.
+func envIntAllowZero(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return def
+}
+
+func envList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	var list []string
+	for item := range strings.SplitSeq(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func envBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
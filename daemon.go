@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var runMu sync.Mutex
+
+func runDaemon(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	listen := fs.String("listen", config.DaemonListen, "address for the dashboard HTTP server")
+	interval := fs.Duration("interval", time.Duration(config.DaemonIntervalMinutes)*time.Minute, "time between collection runs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mux := http.NewServeMux()
+	registerDashboard(mux)
+	registerHealth(mux)
+	srv := &http.Server{Addr: *listen, Handler: mux}
+	go func() {
+		log.Printf("Dashboard listening on %s", *listen)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Dashboard server error: %v", err)
+			stop()
+		}
+	}()
+
+	if config.GRPCListen != "" {
+		grpcSrv, err := startGRPCServer(config.GRPCListen)
+		if err != nil {
+			return fmt.Errorf("startGRPCServer error: %w", err)
+		}
+		defer grpcSrv.GracefulStop()
+	}
+
+	go watchConfig(ctx)
+
+	if config.BLEEnabled {
+		if err := startBLEScanner(ctx); err != nil {
+			return fmt.Errorf("startBLEScanner error: %w", err)
+		}
+	}
+
+	if config.CommandsEnabled {
+		go pollMentions(ctx, time.Duration(config.CommandPollSeconds)*time.Second)
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		configMu.RLock()
+		err := runSerialized(ctx)
+		configMu.RUnlock()
+		if err != nil {
+			log.Printf("Run failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return srv.Shutdown(shutdownCtx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollMentions checks for commands more often than the collection interval so
+// that devices respond to mentions within seconds rather than minutes.
+func pollMentions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			configMu.RLock()
+			processMentions(ctx)
+			configMu.RUnlock()
+		}
+	}
+}
+
+func runSerialized(ctx context.Context) error {
+	runMu.Lock()
+	defer runMu.Unlock()
+	return run(ctx)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+var (
+	dashboardTemplate = template.Must(template.ParseFS(dashboardFiles, "dashboard/index.html"))
+	dashboardMu       sync.RWMutex
+	dashboardReadings []deviceReading
+)
+
+type dashboardDevice struct {
+	ID         string
+	Name       string
+	Status     SwitchBotDeviceStatus
+	Alerts     []string
+	Sparklines []sparkline
+}
+
+type sparkline struct {
+	Label    string
+	Points   string
+	Min, Max float64
+}
+
+type liveReading struct {
+	DeviceID   string `json:"deviceId"`
+	DeviceName string `json:"deviceName"`
+	SwitchBotDeviceStatus
+}
+
+type readingsBroker struct {
+	mu      sync.Mutex
+	clients map[chan []deviceReading]struct{}
+}
+
+var dashboardEvents = &readingsBroker{clients: map[chan []deviceReading]struct{}{}}
+
+func (b *readingsBroker) subscribe() chan []deviceReading {
+	ch := make(chan []deviceReading, 4)
+	b.mu.Lock()
+	b.clients[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *readingsBroker) unsubscribe(ch chan []deviceReading) {
+	b.mu.Lock()
+	delete(b.clients, ch)
+	b.mu.Unlock()
+}
+
+func (b *readingsBroker) publish(readings []deviceReading) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.clients {
+		select {
+		case ch <- readings:
+		default:
+			// Slow clients miss an update rather than stalling collection.
+		}
+	}
+}
+
+func recordDashboardReadings(readings []deviceReading) {
+	dashboardMu.Lock()
+	dashboardReadings = readings
+	dashboardMu.Unlock()
+	dashboardEvents.publish(readings)
+}
+
+func latestDashboardReadings() []deviceReading {
+	dashboardMu.RLock()
+	defer dashboardMu.RUnlock()
+	return dashboardReadings
+}
+
+// serveEvents is registered without withConfig; see configMu.
+func serveEvents(w http.ResponseWriter, r *http.Request) {
+	access := func() bool { return dashboardAccess(r) }
+	if !readingConfig(access) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	ch := dashboardEvents.subscribe()
+	defer dashboardEvents.unsubscribe(ch)
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case readings := <-ch:
+			live := make([]liveReading, 0, len(readings))
+			for _, r := range readings {
+				live = append(live, liveReading{DeviceID: r.Device.DeviceID, DeviceName: r.Device.DeviceName, SwitchBotDeviceStatus: r.Status})
+			}
+			msg, err := json.Marshal(live)
+			if err != nil {
+				log.Printf("Encoding live readings failed: %v", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: readings\ndata: %s\n\n", msg)
+		case <-keepAlive.C:
+			if !readingConfig(access) {
+				return
+			}
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+func registerDashboard(mux *http.ServeMux) {
+	static, _ := fs.Sub(dashboardFiles, "dashboard")
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	mux.HandleFunc("GET /{$}", withConfig(serveDashboard))
+	mux.HandleFunc("GET "+sharePath, withConfig(serveShare))
+	registerAPI(mux)
+}
+
+const (
+	dashboardTokenCookie = "dashboard_token"
+	dashboardShareCookie = "dashboard_share"
+)
+
+// serveDashboard keeps ?token= in a cookie, which browsers also send with /events.
+func serveDashboard(w http.ResponseWriter, r *http.Request) {
+	if token := r.URL.Query().Get("token"); token != "" {
+		if !validReadToken(token, time.Now()) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     dashboardTokenCookie,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+		})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if !dashboardAccess(r) {
+		http.Error(w, "unauthorized: open /?token=<DashboardToken or guest token>", http.StatusUnauthorized)
+		return
+	}
+	renderDashboard(w, r, config.DashboardToken != "")
+}
+
+func dashboardAccess(r *http.Request) bool {
+	if authorizedRead(r) {
+		return true
+	}
+	if c, err := r.Cookie(dashboardTokenCookie); err == nil && validReadToken(c.Value, time.Now()) {
+		return true
+	}
+	if c, err := r.Cookie(dashboardShareCookie); err == nil {
+		q, err := url.ParseQuery(c.Value)
+		return err == nil && validShareLink(r.Context(), q, time.Now())
+	}
+	return false
+}
+
+func renderDashboard(w http.ResponseWriter, r *http.Request, postEnabled bool) {
+	readings := latestDashboardReadings()
+	devices := make([]dashboardDevice, 0, len(readings))
+	for _, reading := range readings {
+		devices = append(devices, buildDashboardDevice(r, reading))
+	}
+	data := struct {
+		Devices     []dashboardDevice
+		PostEnabled bool
+		Now         time.Time
+	}{devices, postEnabled, time.Now().In(timeLocation())}
+	if err := dashboardTemplate.Execute(w, data); err != nil {
+		log.Printf("Rendering dashboard failed: %v", err)
+	}
+}
+
+func buildDashboardDevice(r *http.Request, reading deviceReading) dashboardDevice {
+	device := dashboardDevice{ID: reading.Device.DeviceID, Name: reading.Device.DeviceName, Status: reading.Status}
+	history, err := loadHistory(r.Context(), reading.Device.DeviceID)
+	if err != nil {
+		log.Printf("Failed to load history for %s: %v", reading.Device.DeviceName, err)
+	}
+	for _, metric := range []string{"temperature", "humidity", "co2"} {
+		if line, ok := makeSparkline(metric, history); ok {
+			device.Sparklines = append(device.Sparklines, line)
+		}
+	}
+	if device.Alerts, err = loadActiveAlerts(r.Context(), reading.Device.DeviceID); err != nil {
+		log.Printf("Failed to load active alerts for %s: %v", reading.Device.DeviceName, err)
+	}
+	return device
+}
+
+func makeSparkline(metric string, history []SwitchBotDeviceStatus) (sparkline, bool) {
+	const width, height = 200.0, 40.0
+	var values []float64
+	for _, status := range history {
+		if v, ok := metricValue(status, metric); ok {
+			values = append(values, v)
+		}
+	}
+	if len(values) < 2 {
+		return sparkline{}, false
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	spread := max(hi-lo, 1e-9)
+	points := make([]string, len(values))
+	for i, v := range values {
+		x := float64(i) / float64(len(values)-1) * width
+		y := height - (v-lo)/spread*height
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	return sparkline{Label: metric, Points: strings.Join(points, " "), Min: lo, Max: hi}, true
+}
+
+func servePostNow(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r, config.DashboardToken) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := runSerialized(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
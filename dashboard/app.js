@@ -0,0 +1,47 @@
+const button = document.getElementById("post-now");
+if (button) {
+  const token = document.getElementById("token");
+  const result = document.getElementById("post-result");
+  token.value = sessionStorage.getItem("dashboardToken") || "";
+  button.addEventListener("click", async () => {
+    sessionStorage.setItem("dashboardToken", token.value);
+    button.disabled = true;
+    result.textContent = "…";
+    try {
+      const res = await fetch("/post-now", {
+        method: "POST",
+        headers: { Authorization: "Bearer " + token.value },
+      });
+      result.textContent = res.ok ? "投稿しました" : await res.text();
+    } catch (e) {
+      result.textContent = e.message;
+    } finally {
+      button.disabled = false;
+    }
+  });
+}
+
+const decimals = { temperature: 1, humidity: 1 };
+const events = new EventSource("/events");
+events.addEventListener("readings", (e) => {
+  const readings = JSON.parse(e.data);
+  let known = true;
+  for (const reading of readings) {
+    const section = document.querySelector(`[data-device="${CSS.escape(reading.deviceId)}"]`);
+    if (!section) {
+      known = false;
+      continue;
+    }
+    for (const el of section.querySelectorAll("[data-metric]")) {
+      const value = reading[el.dataset.metric];
+      if (value !== undefined && value !== null) {
+        el.textContent = el.dataset.metric in decimals ? value.toFixed(decimals[el.dataset.metric]) : value;
+      }
+    }
+  }
+  if (!known) {
+    location.reload();
+    return;
+  }
+  document.getElementById("updated").textContent = new Date().toLocaleString("ja-JP");
+});
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+type DestinationBudget struct {
+	TimeoutSeconds int
+	MaxAttempts    int
+	Priority       string
+}
+
+var errDestinationSkipped = errors.New("skipped to stay within the deadline")
+
+func validateDestinationBudgets(budgets map[string]DestinationBudget) error {
+	names := []string{"switchbot", "cloudwatch"}
+	for _, n := range notifiers {
+		names = append(names, n.Name())
+	}
+	for name, b := range budgets {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("DestinationBudgets: unknown destination %q", name)
+		}
+		if name == "switchbot" && b.Priority == "low" {
+			return fmt.Errorf("DestinationBudgets: switchbot cannot be low priority")
+		}
+		switch b.Priority {
+		case "", "high", "low":
+		default:
+			return fmt.Errorf("DestinationBudgets: invalid Priority %q for %s", b.Priority, name)
+		}
+		if b.TimeoutSeconds < 0 || b.MaxAttempts < 0 {
+			return fmt.Errorf("DestinationBudgets: negative budget for %s", name)
+		}
+	}
+	return nil
+}
+
+func destinationTimeout(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	if secs := config.DestinationBudgets[name].TimeoutSeconds; secs > 0 {
+		return context.WithTimeout(ctx, time.Duration(secs)*time.Second)
+	}
+	return ctx, func() {}
+}
+
+func destinationAttempts(name string, def int) int {
+	if n := config.DestinationBudgets[name].MaxAttempts; n > 0 {
+		return n
+	}
+	return max(def, 1)
+}
+
+func skipDestination(ctx context.Context, name string) bool {
+	if config.DestinationBudgets[name].Priority != "low" {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) >= time.Duration(config.DeadlineReserveSeconds)*time.Second {
+		return false
+	}
+	log.Printf("Skipping %s: %v left before the deadline", name, time.Until(deadline).Round(time.Millisecond))
+	return true
+}
+
+// callNotifier tries once by default, since a retried post may be a duplicate.
+func callNotifier(ctx context.Context, n Notifier, call func(context.Context) error) error {
+	if skipDestination(ctx, n.Name()) {
+		return errDestinationSkipped
+	}
+	attempts := destinationAttempts(n.Name(), 1)
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := destinationTimeout(ctx, n.Name())
+		err := call(attemptCtx)
+		cancel()
+		if err == nil || attempt >= attempts {
+			return err
+		}
+		log.Printf("%s failed (attempt %d/%d): %v", n.Name(), attempt, attempts, err)
+		if werr := waitForRetry(ctx, time.Second<<(attempt-1)); werr != nil {
+			return err
+		}
+		recordOps(func(s *opsStats) { s.NotifierRetries++ })
+	}
+}
@@ -32,7 +32,7 @@ build_function() {
     export CGO_ENABLED=0
     
     # Build the binary
-    go build -ldflags="-s -w" -o bootstrap main.go
+    go build -ldflags="-s -w" -o bootstrap .
     
     # Create zip file
     zip -r lambda.zip bootstrap
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/google/cel-go/cel"
+)
+
+type DerivedMetric struct {
+	Name       string
+	Expression string
+	Label      string
+	Unit       string
+	Decimals   int
+}
+
+type derivedValue struct {
+	Metric DerivedMetric
+	Value  float64
+}
+
+var derivedPrograms map[string]cel.Program
+
+func derivedEnv() (*cel.Env, error) {
+	opts := []cel.EnvOption{cel.Variable("prev", cel.MapType(cel.StringType, cel.DoubleType))}
+	for _, name := range []string{"temperature", "humidity", "co2", "lightLevel", "power", "voltage", "current", "battery"} {
+		opts = append(opts, cel.Variable(name, cel.DoubleType))
+	}
+	return cel.NewEnv(opts...)
+}
+
+func compileDerivedMetrics(metrics []DerivedMetric) (map[string]cel.Program, error) {
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+	env, err := derivedEnv()
+	if err != nil {
+		return nil, err
+	}
+	programs := make(map[string]cel.Program, len(metrics))
+	for _, m := range metrics {
+		if m.Name == "" {
+			return nil, fmt.Errorf("derived metric %q has no Name", m.Expression)
+		}
+		if _, ok := programs[m.Name]; ok {
+			return nil, fmt.Errorf("duplicate derived metric %q", m.Name)
+		}
+		ast, issues := env.Compile(m.Expression)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("derived metric %q: %w", m.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.DoubleType {
+			return nil, fmt.Errorf("derived metric %q evaluates to %s, not double", m.Name, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("derived metric %q: %w", m.Name, err)
+		}
+		programs[m.Name] = program
+	}
+	return programs, nil
+}
+
+func derivedVariables(status SwitchBotDeviceStatus) map[string]any {
+	vars := map[string]any{}
+	setFloat := func(name string, v *float64) {
+		if v != nil {
+			vars[name] = *v
+		}
+	}
+	setInt := func(name string, v *int) {
+		if v != nil {
+			vars[name] = float64(*v)
+		}
+	}
+	setFloat("temperature", status.Temperature)
+	setFloat("humidity", status.Humidity)
+	setInt("co2", status.CO2)
+	setInt("lightLevel", status.LightLevel)
+	setFloat("power", status.Power)
+	setFloat("voltage", status.Voltage)
+	setFloat("current", status.Current)
+	setInt("battery", status.Battery)
+	return vars
+}
+
+func derivedValues(status, prev SwitchBotDeviceStatus) []derivedValue {
+	if len(derivedPrograms) == 0 {
+		return nil
+	}
+	vars := derivedVariables(status)
+	previous := map[string]float64{}
+	for k, v := range derivedVariables(prev) {
+		previous[k] = v.(float64)
+	}
+	vars["prev"] = previous
+	var values []derivedValue
+	for _, m := range config.DerivedMetrics {
+		out, _, err := derivedPrograms[m.Name].Eval(vars)
+		if err != nil {
+			continue
+		}
+		v, ok := out.Value().(float64)
+		if !ok {
+			continue
+		}
+		// Neither CloudWatch nor JSON accept NaN or ±Inf.
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			log.Printf("Derived metric %s evaluated to %v; skipping it", m.Name, v)
+			continue
+		}
+		values = append(values, derivedValue{Metric: m, Value: v})
+	}
+	return values
+}
+
+func readingBefore(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus) SwitchBotDeviceStatus {
+	if len(derivedPrograms) == 0 {
+		return SwitchBotDeviceStatus{}
+	}
+	history, err := loadHistory(ctx, device.DeviceID)
+	if err != nil {
+		log.Printf("Failed to load history for %s: %v", device.DeviceName, err)
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].ReadAt.Before(status.ReadAt) {
+			return history[i]
+		}
+	}
+	return SwitchBotDeviceStatus{}
+}
+
+func derivedLine(v derivedValue) string {
+	label := v.Metric.Label
+	if label == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s%s", label, formatNumber(v.Value, v.Metric.Decimals), v.Metric.Unit)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var (
+	dynamoDBClient     *dynamodb.Client
+	dynamoDBClientOnce sync.Once
+)
+
+type dynamoStateStore struct {
+	table string
+}
+
+func newDynamoStateStore(table string) *dynamoStateStore {
+	return &dynamoStateStore{table: table}
+}
+
+func (s *dynamoStateStore) Get(ctx context.Context, key string, out any) (bool, error) {
+	client, err := dynamoDB(ctx)
+	if err != nil {
+		return false, err
+	}
+	res, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            map[string]types.AttributeValue{"Key": &types.AttributeValueMemberS{Value: key}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return false, fmt.Errorf("reading state %q from DynamoDB failed: %w", key, err)
+	}
+	attr, ok := res.Item["Value"].(*types.AttributeValueMemberS)
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal([]byte(attr.Value), out); err != nil {
+		return false, fmt.Errorf("decoding state %q failed: %w", key, err)
+	}
+	return true, nil
+}
+
+func (s *dynamoStateStore) Put(ctx context.Context, key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding state %q failed: %w", key, err)
+	}
+	client, err := dynamoDB(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]types.AttributeValue{
+			"Key":   &types.AttributeValueMemberS{Value: key},
+			"Value": &types.AttributeValueMemberS{Value: string(raw)},
+		},
+	}); err != nil {
+		return fmt.Errorf("writing state %q to DynamoDB failed: %w", key, err)
+	}
+	return nil
+}
+
+func (s *dynamoStateStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	client, err := dynamoDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(s.table),
+		ProjectionExpression:     aws.String("#k"),
+		ExpressionAttributeNames: map[string]string{"#k": "Key"},
+	}
+	if prefix != "" {
+		input.FilterExpression = aws.String("begins_with(#k, :prefix)")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{":prefix": &types.AttributeValueMemberS{Value: prefix}}
+	}
+	var keys []string
+	pages := dynamodb.NewScanPaginator(client, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("scanning state keys in DynamoDB failed: %w", err)
+		}
+		for _, item := range page.Items {
+			if attr, ok := item["Key"].(*types.AttributeValueMemberS); ok {
+				keys = append(keys, attr.Value)
+			}
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func (s *dynamoStateStore) Delete(ctx context.Context, key string) error {
+	client, err := dynamoDB(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       map[string]types.AttributeValue{"Key": &types.AttributeValueMemberS{Value: key}},
+	}); err != nil {
+		return fmt.Errorf("deleting state %q from DynamoDB failed: %w", key, err)
+	}
+	return nil
+}
+
+func dynamoDB(ctx context.Context) (*dynamodb.Client, error) {
+	cfg, err := loadRoleAWSConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config failed: %w", err)
+	}
+	dynamoDBClientOnce.Do(func() {
+		dynamoDBClient = dynamodb.NewFromConfig(cfg)
+	})
+	return dynamoDBClient, nil
+}
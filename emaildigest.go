@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	sestypes "github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+const emailDigestMode = "email_digest"
+
+var (
+	sesClient     *sesv2.Client
+	sesClientOnce sync.Once
+)
+
+type digestRow struct {
+	Label, Current, Min, Max string
+}
+
+type digestDevice struct {
+	Name     string
+	Battery  string
+	Rows     []digestRow
+	Coverage string
+}
+
+type digestPage struct {
+	Title                        string
+	NowLabel, MinLabel, MaxLabel string
+	BatteryLabel, PeriodLabel    string
+	Devices                      []digestDevice
+}
+
+var digestTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif; color: #222;">
+<h2>{{.Title}}</h2>
+{{range .Devices}}
+<h3 style="margin-bottom: 4px;">{{.Name}}{{if .Battery}} <small style="color: #666;">{{$.BatteryLabel}} {{.Battery}}</small>{{end}}</h3>
+<table style="border-collapse: collapse; margin-bottom: 16px;">
+<tr style="background: #f0f0f0;"><th style="padding: 4px 12px; text-align: left;"></th><th style="padding: 4px 12px;">{{$.NowLabel}}</th><th style="padding: 4px 12px;">{{$.MinLabel}}</th><th style="padding: 4px 12px;">{{$.MaxLabel}}</th></tr>
+{{range .Rows}}<tr><td style="padding: 4px 12px; border-top: 1px solid #ddd;">{{.Label}}</td><td style="padding: 4px 12px; border-top: 1px solid #ddd; text-align: right;">{{.Current}}</td><td style="padding: 4px 12px; border-top: 1px solid #ddd; text-align: right;">{{.Min}}</td><td style="padding: 4px 12px; border-top: 1px solid #ddd; text-align: right;">{{.Max}}</td></tr>
+{{end}}</table>
+{{if .Coverage}}<p style="color: #666; font-size: small; margin-top: -12px;">{{.Coverage}}</p>{{end}}
+{{end}}
+<p style="color: #666; font-size: small;">{{.PeriodLabel}}</p>
+</body></html>
+`))
+
+func sesSender(ctx context.Context) (*sesv2.Client, error) {
+	cfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config failed: %w", err)
+	}
+	sesClientOnce.Do(func() {
+		sesClient = sesv2.NewFromConfig(cfg)
+	})
+	return sesClient, nil
+}
+
+func digestDevices(ctx context.Context, readings []deviceReading, now time.Time) []digestDevice {
+	var devices []digestDevice
+	for _, r := range readings {
+		history, err := loadHistory(ctx, r.Device.DeviceID)
+		if err != nil {
+			log.Printf("Failed to load history for %s: %v", r.Device.DeviceName, err)
+		}
+		var recent []SwitchBotDeviceStatus
+		var times []time.Time
+		for _, h := range history {
+			if now.Sub(h.ReadAt) <= 24*time.Hour {
+				recent = append(recent, h)
+				times = append(times, h.ReadAt)
+			}
+		}
+		recent = append(recent, r.Status)
+		times = append(times, r.Status.ReadAt)
+
+		d := digestDevice{Name: r.Device.DeviceName}
+		if c := coverage(times, now.Add(-24*time.Hour), now, 0); c.Interval > 0 {
+			d.Coverage = coverageLine(c)
+		}
+		if r.Status.Battery != nil {
+			d.Battery = batteryStatusEmoji(r.Status, history) + formatInt(*r.Status.Battery) + "%"
+		}
+		_, tempUnit := displayTemperature(0)
+		for _, m := range []struct {
+			label, unit string
+			decimals    int
+			value       func(SwitchBotDeviceStatus) *float64
+		}{
+			{tr("温度"), tempUnit, 1, func(s SwitchBotDeviceStatus) *float64 {
+				if s.Temperature == nil {
+					return nil
+				}
+				t, _ := displayTemperature(*s.Temperature)
+				return &t
+			}},
+			{tr("湿度"), "%", 1, func(s SwitchBotDeviceStatus) *float64 { return s.Humidity }},
+			{"CO2", "ppm", 0, func(s SwitchBotDeviceStatus) *float64 { return intReading(s.CO2) }},
+			{tr("照度"), "", 0, func(s SwitchBotDeviceStatus) *float64 { return intReading(s.LightLevel) }},
+			{tr("電力"), "W", 1, func(s SwitchBotDeviceStatus) *float64 { return s.Power }},
+		} {
+			current := m.value(r.Status)
+			if current == nil {
+				continue
+			}
+			lo, hi := *current, *current
+			for _, h := range recent {
+				if v := m.value(h); v != nil {
+					lo, hi = min(lo, *v), max(hi, *v)
+				}
+			}
+			d.Rows = append(d.Rows, digestRow{
+				Label:   m.label,
+				Current: formatNumber(*current, m.decimals) + m.unit,
+				Min:     formatNumber(lo, m.decimals) + m.unit,
+				Max:     formatNumber(hi, m.decimals) + m.unit,
+			})
+		}
+		if len(d.Rows) > 0 || d.Battery != "" {
+			devices = append(devices, d)
+		}
+	}
+	return devices
+}
+
+func digestText(devices []digestDevice) string {
+	var b strings.Builder
+	for _, d := range devices {
+		b.WriteString(makeDeviceHeader(d.Name))
+		if d.Battery != "" {
+			fmt.Fprintf(&b, " (%s)", d.Battery)
+		}
+		b.WriteByte('\n')
+		for _, row := range d.Rows {
+			fmt.Fprintf(&b, "%s: %s (%s%s / %s%s)\n", row.Label, row.Current,
+				tr("最低"), row.Min, tr("最高"), row.Max)
+		}
+		if d.Coverage != "" {
+			b.WriteString(d.Coverage + "\n")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func runEmailDigest(ctx context.Context) error {
+	if config.EmailDigestFrom == "" || len(config.EmailDigestTo) == 0 {
+		return fmt.Errorf("EmailDigestFrom and EmailDigestTo are required")
+	}
+	devices, err := fetchDevices(ctx)
+	if err != nil {
+		recordSwitchBotAuthFailure(ctx, err)
+		return fmt.Errorf("fetchDevices error: %w", err)
+	}
+	now := time.Now()
+	fetched, _ := fetchReadings(ctx, devices)
+	readings, _ := splitOffline(fetched)
+	digest := digestDevices(ctx, readings, now)
+	if len(digest) == 0 {
+		log.Println("No readings for the email digest")
+		return nil
+	}
+
+	subject := fmt.Sprintf(tr("SwitchBot 日次レポート（%s）"), formatDate(now))
+	var html bytes.Buffer
+	if err := digestTemplate.Execute(&html, digestPage{
+		Title:        subject,
+		NowLabel:     tr("現在"),
+		MinLabel:     strings.TrimSpace(tr("最低")),
+		MaxLabel:     strings.TrimSpace(tr("最高")),
+		BatteryLabel: tr("電池"),
+		PeriodLabel:  tr("過去24時間"),
+		Devices:      digest,
+	}); err != nil {
+		return err
+	}
+
+	client, err := sesSender(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = client.SendEmail(ctx, &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(config.EmailDigestFrom),
+		Destination:      &sestypes.Destination{ToAddresses: config.EmailDigestTo},
+		Content: &sestypes.EmailContent{
+			Simple: &sestypes.Message{
+				Subject: &sestypes.Content{Data: aws.String(subject), Charset: aws.String("UTF-8")},
+				Body: &sestypes.Body{
+					Html: &sestypes.Content{Data: aws.String(html.String()), Charset: aws.String("UTF-8")},
+					Text: &sestypes.Content{Data: aws.String(digestText(digest)), Charset: aws.String("UTF-8")},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("SendEmail failed: %w", err)
+	}
+	log.Printf("Sent the email digest for %d devices to %d recipients", len(digest), len(config.EmailDigestTo))
+	return nil
+}
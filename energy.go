@@ -0,0 +1,226 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+type EnergyAdvisor struct {
+	Pairs         []EnergyPair
+	ReportWeekday string
+	ReportHour    *int
+}
+
+type EnergyPair struct {
+	Plug   string
+	Room   string
+	Mode   string
+	Target float64
+}
+
+type energyStats struct {
+	Wh         float64
+	RunHours   float64
+	TempSum    float64
+	TempCount  int
+	OverHours  float64
+	OverWh     float64
+	LastReadAt time.Time
+	LastPower  float64
+	Target     float64
+	Mode       string
+	Hours      float64
+	GapHours   float64
+	Gaps       int
+}
+
+const (
+	energyReportKey = "energy_report_posted"
+	runningWatts    = 5.0
+	// maxEnergyGap keeps outages from counting as hours of consumption.
+	maxEnergyGap = time.Hour
+)
+
+func validateEnergyAdvisor(a *EnergyAdvisor) error {
+	if a == nil {
+		return nil
+	}
+	for _, p := range a.Pairs {
+		if p.Plug == "" || p.Room == "" {
+			return fmt.Errorf("energy pair requires Plug and Room")
+		}
+		if m := p.mode(); m != "heating" && m != "cooling" {
+			return fmt.Errorf("energy pair %q has unknown Mode %q", p.Plug, p.Mode)
+		}
+	}
+	if a.ReportWeekday != "" {
+		if _, err := parseWeekday(a.ReportWeekday); err != nil {
+			return err
+		}
+	}
+	if h := a.reportHour(); h < 0 || h > 23 {
+		return fmt.Errorf("invalid ReportHour %d", h)
+	}
+	return nil
+}
+
+func (a *EnergyAdvisor) reportHour() int {
+	if a.ReportHour == nil {
+		return 9
+	}
+	return *a.ReportHour
+}
+
+func (p EnergyPair) mode() string {
+	return cmp.Or(p.Mode, "heating")
+}
+
+func (p EnergyPair) target() float64 {
+	if p.Target == 0 {
+		return map[string]float64{"heating": 20, "cooling": 28}[p.mode()]
+	}
+	return celsiusFromConfig(p.Target)
+}
+
+func (p EnergyPair) overTarget(temp float64) bool {
+	if p.mode() == "cooling" {
+		return temp <= p.target()
+	}
+	return temp >= p.target()
+}
+
+func runEnergyAdvisor(ctx context.Context, readings []deviceReading, now time.Time) {
+	a := config.EnergyAdvisor
+	latest := latestReadings(readings)
+	statsKey := "energy_stats:" + officeWeek(now)
+	stats := map[string]energyStats{}
+	if _, err := stateStore.Get(ctx, statsKey, &stats); err != nil {
+		log.Printf("Failed to load energy stats: %v", err)
+	}
+
+	for _, p := range a.Pairs {
+		plug, ok := latest[p.Plug]
+		if !ok || plug.Power == nil {
+			continue
+		}
+		room, ok := latest[p.Room]
+		if !ok || room.Temperature == nil {
+			continue
+		}
+		s := stats[p.Plug]
+		if !s.LastReadAt.IsZero() {
+			elapsed := plug.ReadAt.Sub(s.LastReadAt)
+			hours, power := min(elapsed, maxEnergyGap).Hours(), *plug.Power
+			if elapsed > maxEnergyGap {
+				s.Gaps++
+				s.GapHours += (elapsed - maxEnergyGap).Hours()
+				if config.GapPolicy == gapInterpolate {
+					// Count the whole gap at the mean of the readings on either side.
+					hours, power = elapsed.Hours(), (s.LastPower+*plug.Power)/2
+				}
+			}
+			s.Hours += max(elapsed, 0).Hours()
+			if hours > 0 && power >= runningWatts {
+				wh := power * hours
+				s.Wh += wh
+				s.RunHours += hours
+				s.TempSum += *room.Temperature
+				s.TempCount++
+				if p.overTarget(*room.Temperature) {
+					s.OverHours += hours
+					s.OverWh += wh
+				}
+			}
+		}
+		s.LastReadAt, s.LastPower = plug.ReadAt, *plug.Power
+		s.Target, s.Mode = p.target(), p.mode()
+		stats[p.Plug] = s
+	}
+	if err := stateStore.Put(ctx, statsKey, stats); err != nil {
+		log.Printf("Failed to save energy stats: %v", err)
+	}
+	postEnergyReport(ctx, now)
+}
+
+func postEnergyReport(ctx context.Context, now time.Time) {
+	a := config.EnergyAdvisor
+	local := now.In(timeLocation())
+	weekday, err := parseWeekday(cmp.Or(a.ReportWeekday, "Mon"))
+	if err != nil || local.Weekday() != weekday || local.Hour() < a.reportHour() {
+		return
+	}
+	week := officeWeek(now)
+	var posted string
+	if _, err := stateStore.Get(ctx, energyReportKey, &posted); err != nil {
+		log.Printf("Failed to load energy report state: %v", err)
+		return
+	}
+	if posted == week {
+		return
+	}
+
+	previous := officeWeek(now.AddDate(0, 0, -7))
+	stats := map[string]energyStats{}
+	if _, err := stateStore.Get(ctx, "energy_stats:"+previous, &stats); err != nil {
+		log.Printf("Failed to load energy stats: %v", err)
+		return
+	}
+	if message := formatEnergyReport(previous, stats); message != "" {
+		if err := notify(ctx, message); err != nil {
+			log.Printf("Failed to post energy report: %v", err)
+			return
+		}
+	}
+	if err := stateStore.Put(ctx, energyReportKey, week); err != nil {
+		log.Printf("Failed to save energy report state: %v", err)
+	}
+}
+
+// formatEnergyReport assumes the rule of thumb of about 10% saved per degree.
+func formatEnergyReport(week string, stats map[string]energyStats) string {
+	plugs := make([]string, 0, len(stats))
+	for plug, s := range stats {
+		if s.TempCount > 0 {
+			plugs = append(plugs, plug)
+		}
+	}
+	if len(plugs) == 0 {
+		return ""
+	}
+	slices.SortFunc(plugs, func(a, b string) int { return cmp.Compare(stats[b].Wh, stats[a].Wh) })
+
+	var b strings.Builder
+	b.WriteString(makeDeviceHeader(fmt.Sprintf("省エネアドバイス (%s)", week)) + "\n")
+	for _, plug := range plugs {
+		s := stats[plug]
+		avg, unit := displayTemperature(s.TempSum / float64(s.TempCount))
+		target, _ := displayTemperature(s.Target)
+		fmt.Fprintf(&b, "%s: %skWh / 稼働%s時間 / 稼働中の平均室温 %s%s（目標 %s%s）", plug,
+			formatNumber(s.Wh/1000, 1), formatNumber(s.RunHours, 1), formatNumber(avg, 1), unit, formatNumber(target, 1), unit)
+		if s.Hours > 0 {
+			fmt.Fprintf(&b, " / データ完全性 %s%%", formatNumber((1-s.GapHours/s.Hours)*100, 0))
+		}
+		if config.GapPolicy == gapFlag && s.Gaps > 0 {
+			fmt.Fprintf(&b, " ⚠️ 欠測 %s回（%s時間）", formatInt(s.Gaps), formatNumber(s.GapHours, 1))
+		}
+		b.WriteString("\n")
+		if s.OverHours >= 1 {
+			fmt.Fprintf(&b, "💡 目標に達した後も%s時間（%skWh）稼働していました。シーンやタイマーでの自動オフを検討してください\n",
+				formatNumber(s.OverHours, 1), formatNumber(s.OverWh/1000, 1))
+		}
+		diff := s.TempSum/float64(s.TempCount) - s.Target
+		if s.Mode == "cooling" {
+			diff = -diff
+		}
+		if diff >= 1 {
+			fmt.Fprintf(&b, "💡 設定温度を1度%sと約%skWh（10%%）の節約が見込めます\n",
+				map[string]string{"heating": "下げる", "cooling": "上げる"}[s.Mode], formatNumber(s.Wh/10000, 1))
+		}
+	}
+	return b.String()
+}
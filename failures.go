@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+type fetchFailure struct {
+	Device SwitchBotDevice
+	Err    error
+}
+
+func failureReason(err error) string {
+	var statusErr *switchBotStatusError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return tr("タイムアウト")
+	case errors.Is(err, errSwitchBotUnauthorized):
+		return tr("認証エラー")
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("statusCode %d", statusErr.Code)
+	}
+	return tr("エラー")
+}
+
+func failureSection(failures []fetchFailure) deviceSection {
+	names := make([]string, len(failures))
+	for i, f := range failures {
+		names[i] = fmt.Sprintf(tr("%s（%s）"), f.Device.DeviceName, failureReason(f.Err))
+	}
+	message := fmt.Sprintf(tr("⚠️ 取得できませんでした: %s"), strings.Join(names, tr("、"))) + "\n"
+	return deviceSection{Message: message, Notable: true}
+}
+
+// reportFetchFailures does not fail the run, which would be retried and post again.
+func reportFetchFailures(ctx context.Context, failures []fetchFailure, now time.Time) {
+	devices := make([]string, len(failures))
+	for i, f := range failures {
+		devices[i] = f.Device.DeviceName
+	}
+	entry := map[string]any{"type": "FetchFailures", "count": len(failures), "devices": devices, "timestamp": now}
+	if err := putRunMetric(ctx, "DevicesUnreadable", len(failures), entry, now); err != nil {
+		log.Printf("Failed to send the DevicesUnreadable metric: %v", err)
+	}
+}
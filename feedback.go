@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	alertPostsKey        = "alert_posts"
+	maxAlertPosts        = 200
+	alertFeedbackAge     = 7 * 24 * time.Hour
+	ignoredAlertMinPosts = 3
+)
+
+type alertPost struct {
+	ID       string    `json:"id"`
+	Alerts   []string  `json:"alerts"`
+	Device   string    `json:"device"`
+	PostedAt time.Time `json:"postedAt"`
+}
+
+type alertNotifier interface {
+	NotifyAlert(ctx context.Context, device SwitchBotDevice, alerts []string, message string) error
+}
+
+func notifyAlert(ctx context.Context, device SwitchBotDevice, alerts []string, message string) error {
+	var errs []error
+	for _, n := range notifiers {
+		var err error
+		switch c := n.(type) {
+		case alertNotifier:
+			err = c.NotifyAlert(ctx, device, alerts, message)
+		case urgentNotifier:
+			err = c.NotifyUrgent(ctx, message)
+		default:
+			err = n.Notify(ctx, message)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+			continue
+		}
+		recordOps(func(s *opsStats) { s.Posts++ })
+	}
+	return errors.Join(errs...)
+}
+
+func (mastodonNotifier) NotifyAlert(ctx context.Context, device SwitchBotDevice, alerts []string, message string) error {
+	id, err := postMastodonStatus(ctx, mastodonStatus(message, config.UrgentVisibility, spoilerFor(true)))
+	if err != nil {
+		return err
+	}
+	var posts []alertPost
+	if _, err := stateStore.Get(ctx, alertPostsKey, &posts); err != nil {
+		log.Printf("Failed to load alert posts: %v", err)
+		return nil
+	}
+	posts = append(posts, alertPost{ID: id, Alerts: alerts, Device: device.DeviceName, PostedAt: time.Now()})
+	if len(posts) > maxAlertPosts {
+		posts = posts[len(posts)-maxAlertPosts:]
+	}
+	if err := stateStore.Put(ctx, alertPostsKey, posts); err != nil {
+		log.Printf("Failed to save alert posts: %v", err)
+	}
+	return nil
+}
+
+type alertReaction struct {
+	posts, reacted int
+}
+
+func formatAlertFeedback(ctx context.Context, now time.Time) string {
+	if !usesMastodon() {
+		return ""
+	}
+	var posts []alertPost
+	if _, err := stateStore.Get(ctx, alertPostsKey, &posts); err != nil {
+		log.Printf("Failed to load alert posts: %v", err)
+		return ""
+	}
+	reactions := map[string]*alertReaction{}
+	for _, p := range posts {
+		if now.Sub(p.PostedAt) > alertFeedbackAge {
+			continue
+		}
+		var status struct {
+			FavouritesCount int `json:"favourites_count"`
+			ReblogsCount    int `json:"reblogs_count"`
+		}
+		if err := httpGet(ctx, "/statuses/"+p.ID, &status); err != nil {
+			log.Printf("Failed to fetch reactions to alert post %s: %v", p.ID, err)
+			continue
+		}
+		for _, name := range p.Alerts {
+			r := reactions[name]
+			if r == nil {
+				r = &alertReaction{}
+				reactions[name] = r
+			}
+			r.posts++
+			if status.FavouritesCount+status.ReblogsCount > 0 {
+				r.reacted++
+			}
+		}
+	}
+	if len(reactions) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(reactions))
+	for name := range reactions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var b strings.Builder
+	b.WriteString("\nアラートへの反応 (過去7日):\n")
+	for _, name := range names {
+		r := reactions[name]
+		fmt.Fprintf(&b, "%s: %s/%s件\n", name, formatInt(r.reacted), formatInt(r.posts))
+	}
+	for _, name := range names {
+		if r := reactions[name]; r.posts >= ignoredAlertMinPosts && r.reacted == 0 {
+			fmt.Fprintf(&b, "💡 %sには反応がありません。しきい値の緩和を検討してください\n", name)
+		}
+	}
+	return b.String()
+}
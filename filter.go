@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+)
+
+type ReadingFilters struct {
+	MaxRatePerMinute map[string]float64
+	Smoothing        map[string]float64
+}
+
+var filterMetrics = []string{"temperature", "humidity", "co2", "lightLevel", "power"}
+
+func validateReadingFilters(f *ReadingFilters) error {
+	if f == nil {
+		return nil
+	}
+	for name, rate := range f.MaxRatePerMinute {
+		if !slices.Contains(filterMetrics, name) {
+			return fmt.Errorf("MaxRatePerMinute: unknown metric %q", name)
+		}
+		if rate <= 0 {
+			return fmt.Errorf("MaxRatePerMinute: %s must be positive", name)
+		}
+	}
+	for name, alpha := range f.Smoothing {
+		if !slices.Contains(filterMetrics, name) {
+			return fmt.Errorf("Smoothing: unknown metric %q", name)
+		}
+		if alpha <= 0 || alpha > 1 {
+			return fmt.Errorf("Smoothing: %s must be greater than 0 and at most 1", name)
+		}
+	}
+	return nil
+}
+
+func readingValue(s SwitchBotDeviceStatus, name string) (float64, bool) {
+	switch name {
+	case "temperature":
+		return floatReading(s.Temperature)
+	case "humidity":
+		return floatReading(s.Humidity)
+	case "co2":
+		return floatReading(intReading(s.CO2))
+	case "lightLevel":
+		return floatReading(intReading(s.LightLevel))
+	case "power":
+		return floatReading(s.Power)
+	}
+	return 0, false
+}
+
+func floatReading(v *float64) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	return *v, true
+}
+
+func setReadingValue(s *SwitchBotDeviceStatus, name string, v float64) {
+	n := int(math.Round(v))
+	v = math.Round(v*100) / 100
+	switch name {
+	case "temperature":
+		s.Temperature = &v
+	case "humidity":
+		s.Humidity = &v
+	case "co2":
+		s.CO2 = &n
+	case "lightLevel":
+		s.LightLevel = &n
+	case "power":
+		s.Power = &v
+	}
+}
+
+func filterReadings(ctx context.Context, readings []deviceReading) []deviceReading {
+	if config.Filters == nil {
+		return readings
+	}
+	for i, r := range readings {
+		history, err := loadHistory(ctx, r.Device.DeviceID)
+		if err != nil {
+			log.Printf("Failed to load history for %s: %v", r.Device.DeviceName, err)
+			continue
+		}
+		readings[i].Status = filterReading(r.Device, r.Status, history)
+	}
+	return readings
+}
+
+// filterReading keeps the reading as received in Raw, so the next raw reading
+// can confirm a rejected jump.
+func filterReading(device SwitchBotDevice, status SwitchBotDeviceStatus, history []SwitchBotDeviceStatus) SwitchBotDeviceStatus {
+	if len(history) == 0 {
+		return status
+	}
+	prev := history[len(history)-1]
+	raw := status
+	raw.Raw = nil
+	minutes := status.ReadAt.Sub(prev.ReadAt).Minutes()
+	changed := false
+	for _, name := range filterMetrics {
+		v, ok := readingValue(status, name)
+		if !ok {
+			continue
+		}
+		p, ok := readingValue(prev, name)
+		if !ok {
+			continue
+		}
+		if limit, ok := config.Filters.MaxRatePerMinute[name]; ok && minutes > 0 && math.Abs(v-p) > limit*minutes && !confirmsJump(prev, name, v, limit*minutes) {
+			log.Printf("Rejected %s of %s: %g (was %g %s earlier)", name, device.DeviceName, v, p, time.Duration(minutes*float64(time.Minute)).Round(time.Second))
+			setReadingValue(&status, name, p)
+			changed = true
+			continue
+		}
+		if alpha, ok := config.Filters.Smoothing[name]; ok && alpha < 1 {
+			setReadingValue(&status, name, alpha*v+(1-alpha)*p)
+			changed = true
+		}
+	}
+	if changed {
+		status.Raw = &raw
+	}
+	return status
+}
+
+func confirmsJump(prev SwitchBotDeviceStatus, name string, v, limit float64) bool {
+	if prev.Raw == nil {
+		return false
+	}
+	rp, ok := readingValue(*prev.Raw, name)
+	return ok && math.Abs(v-rp) <= limit
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	gapIgnore      = "ignore"
+	gapInterpolate = "interpolate"
+	gapFlag        = "flag"
+)
+
+func validateGapPolicy(policy string) error {
+	switch policy {
+	case gapIgnore, gapInterpolate, gapFlag:
+		return nil
+	}
+	return fmt.Errorf("invalid GapPolicy %q", policy)
+}
+
+type timedValue struct {
+	At    time.Time
+	Value float64
+}
+
+type gapSpan struct {
+	From, To time.Time
+}
+
+type seriesCoverage struct {
+	Interval     time.Duration
+	Completeness float64
+	Gaps         []gapSpan
+}
+
+// medianInterval uses the median so that gaps and retries do not skew it.
+func medianInterval(times []time.Time) time.Duration {
+	if len(times) < 2 {
+		return 0
+	}
+	times = slices.Clone(times)
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	spacings := make([]time.Duration, 0, len(times)-1)
+	for i := 1; i < len(times); i++ {
+		spacings = append(spacings, times[i].Sub(times[i-1]))
+	}
+	slices.Sort(spacings)
+	return spacings[len(spacings)/2]
+}
+
+// coverage counts more than twice the interval without readings as a gap, including at either end.
+func coverage(times []time.Time, start, end time.Time, minInterval time.Duration) seriesCoverage {
+	c := seriesCoverage{Interval: medianInterval(times)}
+	if c.Interval == 0 {
+		return c
+	}
+	c.Interval = max(c.Interval, minInterval)
+	times = slices.Clone(times)
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	expected := float64(end.Sub(start)) / float64(c.Interval)
+	c.Completeness = min(float64(len(times))/max(expected, 1), 1)
+	prev := start
+	for _, t := range append(times, end) {
+		if t.Sub(prev) > 2*c.Interval {
+			c.Gaps = append(c.Gaps, gapSpan{From: prev, To: t})
+		}
+		prev = t
+	}
+	return c
+}
+
+func gapFill(from, to time.Time, interval time.Duration) []time.Time {
+	if to.Sub(from) <= 2*interval {
+		return nil
+	}
+	var fill []time.Time
+	for t := from.Add(interval); to.Sub(t) >= interval/2; t = t.Add(interval) {
+		fill = append(fill, t)
+	}
+	return fill
+}
+
+func lerp(a, b float64, from, to, at time.Time) float64 {
+	return a + (b-a)*float64(at.Sub(from))/float64(to.Sub(from))
+}
+
+func interpolateSeries(series []timedValue, interval time.Duration) []timedValue {
+	if interval <= 0 || len(series) < 2 {
+		return series
+	}
+	series = slices.Clone(series)
+	slices.SortFunc(series, func(a, b timedValue) int { return a.At.Compare(b.At) })
+	filled := []timedValue{series[0]}
+	for i, v := range series[1:] {
+		prev := series[i]
+		for _, t := range gapFill(prev.At, v.At, interval) {
+			filled = append(filled, timedValue{At: t, Value: lerp(prev.Value, v.Value, prev.At, v.At, t)})
+		}
+		filled = append(filled, v)
+	}
+	return filled
+}
+
+func interpolateHistory(history []SwitchBotDeviceStatus) []SwitchBotDeviceStatus {
+	times := make([]time.Time, len(history))
+	for i, h := range history {
+		times[i] = h.ReadAt
+	}
+	interval := medianInterval(times)
+	if interval == 0 {
+		return history
+	}
+	filled := []SwitchBotDeviceStatus{history[0]}
+	for i, h := range history[1:] {
+		prev := history[i]
+		for _, t := range gapFill(prev.ReadAt, h.ReadAt, interval) {
+			r := SwitchBotDeviceStatus{ReadAt: t, Quality: []string{qualityInterpolated}}
+			for _, name := range filterMetrics {
+				a, ok := readingValue(prev, name)
+				b, ok2 := readingValue(h, name)
+				if ok && ok2 {
+					setReadingValue(&r, name, lerp(a, b, prev.ReadAt, h.ReadAt, t))
+				}
+			}
+			filled = append(filled, r)
+		}
+		filled = append(filled, h)
+	}
+	return filled
+}
+
+func formatGaps(gaps []gapSpan) string {
+	var parts []string
+	for i, g := range gaps {
+		if i == 3 {
+			parts = append(parts, fmt.Sprintf(tr("他%d件"), len(gaps)-i))
+			break
+		}
+		parts = append(parts, g.From.In(timeLocation()).Format("15:04")+"〜"+g.To.In(timeLocation()).Format("15:04"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func metricCoverageLine(labels []string, covs []seriesCoverage) string {
+	same := true
+	for _, c := range covs[1:] {
+		if c.Completeness != covs[0].Completeness || !slices.Equal(c.Gaps, covs[0].Gaps) {
+			same = false
+		}
+	}
+	if same {
+		return coverageLine(covs[0])
+	}
+	parts := make([]string, len(covs))
+	for i, c := range covs {
+		parts[i] = labels[i] + " " + formatNumber(c.Completeness*100, 0) + "%"
+	}
+	line := fmt.Sprintf("%s: %s", tr("データ完全性"), strings.Join(parts, ", "))
+	if config.GapPolicy == gapFlag {
+		for i, c := range covs {
+			if len(c.Gaps) > 0 {
+				line += fmt.Sprintf("\n⚠️ %s: %s", fmt.Sprintf(tr("欠測（%s）"), labels[i]), formatGaps(c.Gaps))
+			}
+		}
+	}
+	return line
+}
+
+func coverageLine(c seriesCoverage) string {
+	line := fmt.Sprintf("%s: %s%%", tr("データ完全性"), formatNumber(c.Completeness*100, 0))
+	if config.GapPolicy == gapFlag && len(c.Gaps) > 0 {
+		line += fmt.Sprintf(" ⚠️ %s: %s", tr("欠測"), formatGaps(c.Gaps))
+	}
+	return line
+}
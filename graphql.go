@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+const graphQLSchemaSDL = `
+type Query {
+  devices: [Device!]!
+  readings(device: String!, from: String, to: String): [Reading!]!
+  aggregates(device: String!, metric: String!, from: String, to: String): Aggregate!
+}
+
+type Device {
+  id: String!
+  name: String!
+  type: String!
+}
+
+type Reading {
+  readAt: String!
+  temperature: Float
+  humidity: Float
+  co2: Int
+  battery: Int
+  quality: [String!]!
+}
+
+type Aggregate {
+  metric: String!
+  count: Int!
+  min: Float
+  max: Float
+  avg: Float
+  completeness: Float
+  gaps: Int!
+}
+`
+
+var graphQLSchema = gqlparser.MustLoadSchema(&ast.Source{Name: "schema.graphql", Input: graphQLSchemaSDL})
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type graphQLResponse struct {
+	Data   any            `json:"data"`
+	Errors []graphQLError `json:"errors,omitempty"`
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+type graphQLQueryRoot struct{}
+
+type graphQLAggregate struct {
+	Metric        string
+	Count         int
+	Min, Max, Avg *float64
+	Coverage      seriesCoverage
+}
+
+// graphQLObject keeps fields in selection order when encoded, as the spec requires.
+type graphQLObject []graphQLField
+
+type graphQLField struct {
+	Key   string
+	Value any
+}
+
+func (o graphQLObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.Key)
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	if !authorizedRead(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGraphQL(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	doc, errs := gqlparser.LoadQuery(graphQLSchema, req.Query)
+	if len(errs) > 0 {
+		writeGraphQL(w, http.StatusBadRequest, nil, errs)
+		return
+	}
+	op := doc.Operations.ForName(req.OperationName)
+	if op == nil || op.Operation != ast.Query {
+		writeGraphQL(w, http.StatusBadRequest, nil, fmt.Errorf("no query operation named %q", req.OperationName))
+		return
+	}
+	vars, err := validator.VariableValues(graphQLSchema, op, req.Variables)
+	if err != nil {
+		writeGraphQL(w, http.StatusBadRequest, nil, err)
+		return
+	}
+	data, err := resolveGraphQLSelection(r, op.SelectionSet, graphQLQueryRoot{}, vars)
+	if err != nil {
+		writeGraphQL(w, http.StatusOK, nil, err)
+		return
+	}
+	writeGraphQL(w, http.StatusOK, data, nil)
+}
+
+func writeGraphQL(w http.ResponseWriter, code int, data any, err error) {
+	resp := graphQLResponse{Data: data}
+	if err != nil {
+		resp.Errors = []graphQLError{{Message: err.Error()}}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func resolveGraphQLSelection(r *http.Request, set ast.SelectionSet, obj any, vars map[string]any) (graphQLObject, error) {
+	var out graphQLObject
+	for _, field := range collectGraphQLFields(set) {
+		key := field.Alias
+		if key == "" {
+			key = field.Name
+		}
+		value, err := resolveGraphQLField(r, field, obj, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if len(field.SelectionSet) > 0 {
+			if value, err = resolveGraphQLValue(r, field.SelectionSet, value, vars); err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		out = append(out, graphQLField{Key: key, Value: value})
+	}
+	return out, nil
+}
+
+func resolveGraphQLValue(r *http.Request, set ast.SelectionSet, value any, vars map[string]any) (any, error) {
+	switch v := value.(type) {
+	case []SwitchBotDevice:
+		list := make([]graphQLObject, 0, len(v))
+		for _, item := range v {
+			obj, err := resolveGraphQLSelection(r, set, item, vars)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, obj)
+		}
+		return list, nil
+	case []SwitchBotDeviceStatus:
+		list := make([]graphQLObject, 0, len(v))
+		for _, item := range v {
+			obj, err := resolveGraphQLSelection(r, set, item, vars)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, obj)
+		}
+		return list, nil
+	}
+	return resolveGraphQLSelection(r, set, value, vars)
+}
+
+func collectGraphQLFields(set ast.SelectionSet) []*ast.Field {
+	var fields []*ast.Field
+	for _, sel := range set {
+		switch s := sel.(type) {
+		case *ast.Field:
+			fields = append(fields, s)
+		case *ast.InlineFragment:
+			fields = append(fields, collectGraphQLFields(s.SelectionSet)...)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				fields = append(fields, collectGraphQLFields(s.Definition.SelectionSet)...)
+			}
+		}
+	}
+	return fields
+}
+
+func resolveGraphQLField(r *http.Request, field *ast.Field, obj any, vars map[string]any) (any, error) {
+	if field.Name == "__typename" {
+		return field.ObjectDefinition.Name, nil
+	}
+	args := field.ArgumentMap(vars)
+	switch o := obj.(type) {
+	case graphQLQueryRoot:
+		switch field.Name {
+		case "devices":
+			devices := []SwitchBotDevice{}
+			for _, reading := range latestDashboardReadings() {
+				devices = append(devices, reading.Device)
+			}
+			return devices, nil
+		case "readings":
+			return graphQLReadings(r, args)
+		case "aggregates":
+			readings, err := graphQLReadings(r, args)
+			if err != nil {
+				return nil, err
+			}
+			from, _ := graphQLTimeArg(args, "from", time.Time{})
+			to, _ := graphQLTimeArg(args, "to", time.Now())
+			return aggregateReadings(args["metric"].(string), readings, from, to), nil
+		}
+	case SwitchBotDevice:
+		switch field.Name {
+		case "id":
+			return o.DeviceID, nil
+		case "name":
+			return o.DeviceName, nil
+		case "type":
+			return o.DeviceType, nil
+		}
+	case SwitchBotDeviceStatus:
+		switch field.Name {
+		case "readAt":
+			return o.ReadAt.Format(time.RFC3339), nil
+		case "temperature":
+			return o.Temperature, nil
+		case "humidity":
+			return o.Humidity, nil
+		case "co2":
+			return o.CO2, nil
+		case "battery":
+			return o.Battery, nil
+		case "quality":
+			return append([]string{}, o.Quality...), nil
+		}
+	case graphQLAggregate:
+		switch field.Name {
+		case "metric":
+			return o.Metric, nil
+		case "count":
+			return o.Count, nil
+		case "min":
+			return o.Min, nil
+		case "max":
+			return o.Max, nil
+		case "avg":
+			return o.Avg, nil
+		case "completeness":
+			if o.Coverage.Interval == 0 {
+				return nil, nil
+			}
+			return o.Coverage.Completeness, nil
+		case "gaps":
+			return len(o.Coverage.Gaps), nil
+		}
+	}
+	return nil, fmt.Errorf("field %q is not resolvable", field.Name)
+}
+
+func graphQLReadings(r *http.Request, args map[string]any) ([]SwitchBotDeviceStatus, error) {
+	name, _ := args["device"].(string)
+	var deviceID string
+	for _, reading := range latestDashboardReadings() {
+		if name == reading.Device.DeviceID || name == reading.Device.DeviceName {
+			deviceID = reading.Device.DeviceID
+		}
+	}
+	if deviceID == "" {
+		return nil, fmt.Errorf("unknown device %q", name)
+	}
+	from, err := graphQLTimeArg(args, "from", time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	to, err := graphQLTimeArg(args, "to", time.Now())
+	if err != nil {
+		return nil, err
+	}
+	history, err := loadHistory(r.Context(), deviceID)
+	if err != nil {
+		return nil, err
+	}
+	readings := []SwitchBotDeviceStatus{}
+	for _, h := range history {
+		if !h.ReadAt.Before(from) && !h.ReadAt.After(to) {
+			readings = append(readings, h)
+		}
+	}
+	if config.GapPolicy == gapInterpolate && len(readings) > 0 {
+		readings = interpolateHistory(readings)
+	}
+	return readings, nil
+}
+
+func graphQLTimeArg(args map[string]any, name string, def time.Time) (time.Time, error) {
+	s, ok := args[name].(string)
+	if !ok || s == "" {
+		return def, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return t, nil
+}
+
+// aggregateReadings leaves interpolated readings out of Count and completeness.
+func aggregateReadings(metric string, readings []SwitchBotDeviceStatus, from, to time.Time) graphQLAggregate {
+	agg := graphQLAggregate{Metric: metric}
+	var sum float64
+	var n int
+	var measured []time.Time
+	for _, reading := range readings {
+		v, ok := metricValue(reading, metric)
+		if !ok {
+			continue
+		}
+		if n == 0 {
+			agg.Min, agg.Max = &v, &v
+		}
+		lo, hi := min(*agg.Min, v), max(*agg.Max, v)
+		agg.Min, agg.Max = &lo, &hi
+		sum += v
+		n++
+		if !slices.Contains(reading.Quality, qualityInterpolated) {
+			measured = append(measured, reading.ReadAt)
+			agg.Count++
+		}
+	}
+	if n > 0 {
+		avg := sum / float64(n)
+		agg.Avg = &avg
+	}
+	if len(measured) > 0 {
+		if from.IsZero() {
+			from = measured[0]
+		}
+		agg.Coverage = coverage(measured, from, to, 0)
+	}
+	return agg
+}
@@ -0,0 +1,135 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative api/switchbotpb/switchbot.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/shinderuman/switchbot_bot/api/switchbotpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type grpcServer struct {
+	switchbotpb.UnimplementedSwitchBotServer
+}
+
+func startGRPCServer(listen string) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, err
+	}
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			configMu.RLock()
+			defer configMu.RUnlock()
+			if err := authorizeRPC(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		// Streams hold the config lock only for the check, like the SSE endpoint.
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := readingConfig(func() error { return authorizeRPC(ss.Context(), info.FullMethod) }); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	switchbotpb.RegisterSwitchBotServer(srv, grpcServer{})
+	go func() {
+		log.Printf("gRPC listening on %s", listen)
+		if err := srv.Serve(lis); err != nil {
+			log.Printf("gRPC server error: %v", err)
+		}
+	}()
+	return srv, nil
+}
+
+func (grpcServer) ListDevices(_ context.Context, _ *switchbotpb.ListDevicesRequest) (*switchbotpb.ListDevicesResponse, error) {
+	resp := &switchbotpb.ListDevicesResponse{}
+	for _, r := range latestDashboardReadings() {
+		resp.Devices = append(resp.Devices, &switchbotpb.Device{
+			Id:   r.Device.DeviceID,
+			Name: r.Device.DeviceName,
+			Type: r.Device.DeviceType,
+		})
+	}
+	return resp, nil
+}
+
+func (grpcServer) GetLatestReading(_ context.Context, req *switchbotpb.GetLatestReadingRequest) (*switchbotpb.Reading, error) {
+	for _, r := range latestDashboardReadings() {
+		if req.GetDevice() == r.Device.DeviceID || req.GetDevice() == r.Device.DeviceName {
+			return toProtoReading(r), nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "no reading for device %q", req.GetDevice())
+}
+
+func (grpcServer) StreamReadings(_ *switchbotpb.StreamReadingsRequest, stream grpc.ServerStreamingServer[switchbotpb.Reading]) error {
+	ch := dashboardEvents.subscribe()
+	defer dashboardEvents.unsubscribe(ch)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case readings := <-ch:
+			for _, r := range readings {
+				if err := stream.Send(toProtoReading(r)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (grpcServer) TriggerPost(ctx context.Context, _ *switchbotpb.TriggerPostRequest) (*switchbotpb.TriggerPostResponse, error) {
+	if err := runSerialized(ctx); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "run failed: %v", err)
+	}
+	return &switchbotpb.TriggerPostResponse{}, nil
+}
+
+func authorizeRPC(ctx context.Context, method string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var got string
+	if values := md.Get("authorization"); len(values) > 0 {
+		got, _ = strings.CutPrefix(values[0], "Bearer ")
+	}
+	ok := validReadToken(got, time.Now())
+	if method == switchbotpb.SwitchBot_TriggerPost_FullMethodName {
+		ok = config.DashboardToken != "" && subtle.ConstantTimeCompare([]byte(got), []byte(config.DashboardToken)) == 1
+	}
+	if !ok {
+		return status.Error(codes.Unauthenticated, "invalid or missing token")
+	}
+	return nil
+}
+
+func toProtoReading(r deviceReading) *switchbotpb.Reading {
+	reading := &switchbotpb.Reading{
+		DeviceId:     r.Device.DeviceID,
+		DeviceName:   r.Device.DeviceName,
+		ReadAtUnixMs: r.Status.ReadAt.UnixMilli(),
+		Temperature:  r.Status.Temperature,
+		Humidity:     r.Status.Humidity,
+	}
+	if r.Status.CO2 != nil {
+		v := int32(*r.Status.CO2)
+		reading.Co2 = &v
+	}
+	if r.Status.Battery != nil {
+		v := int32(*r.Status.Battery)
+		reading.Battery = &v
+	}
+	return reading
+}
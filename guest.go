@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	statusAPIPath    = "/api/status"
+	guestTokenPrefix = "guest."
+)
+
+func guestTokenSignature(expires int64) string {
+	mac := hmac.New(sha256.New, []byte(config.GuestTokenSecret))
+	fmt.Fprintf(mac, "guest:read:%d", expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func mintGuestToken(expires int64) string {
+	return fmt.Sprintf("%s%d.%s", guestTokenPrefix, expires, guestTokenSignature(expires))
+}
+
+func validGuestToken(token string, now time.Time) bool {
+	if config.GuestTokenSecret == "" {
+		return false
+	}
+	rest, ok := strings.CutPrefix(token, guestTokenPrefix)
+	if !ok {
+		return false
+	}
+	exp, sig, ok := strings.Cut(rest, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() > expires {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(sig), []byte(guestTokenSignature(expires))) == 1
+}
+
+// authorizedRead must not guard endpoints that post or actuate devices.
+func authorizedRead(r *http.Request) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && validReadToken(got, time.Now())
+}
+
+func validReadToken(token string, now time.Time) bool {
+	if config.DashboardToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(config.DashboardToken)) == 1 {
+		return true
+	}
+	return validGuestToken(token, now)
+}
+
+func serveStatusAPI(w http.ResponseWriter, r *http.Request) {
+	if !authorizedRead(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	readings := latestDashboardReadings()
+	live := make([]liveReading, 0, len(readings))
+	for _, r := range readings {
+		live = append(live, liveReading{DeviceID: r.Device.DeviceID, DeviceName: r.Device.DeviceName, SwitchBotDeviceStatus: r.Status})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(live)
+}
+
+func runGuestTokenCommand(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("guest-token", flag.ContinueOnError)
+	ttl := fs.String("ttl", "90d", "how long the token stays valid")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if config.GuestTokenSecret == "" {
+		return fmt.Errorf("GuestTokenSecret is not set")
+	}
+	d, err := parseLookback(*ttl)
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(d)
+	fmt.Println(mintGuestToken(expires.Unix()))
+	fmt.Fprintf(os.Stderr, "Read-only until %s\n", expires.In(timeLocation()).Format(time.DateTime))
+	return nil
+}
+
+// runStatusCommand needs no config.json, only the URL and a guest token.
+func runStatusCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	url := fs.String("url", envString("SWITCHBOT_DAEMON_URL", "http://localhost:8080"), "base URL of the daemon")
+	token := fs.String("token", os.Getenv("SWITCHBOT_DAEMON_TOKEN"), "guest or dashboard token")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(*url, "/")+statusAPIPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+*token)
+	client := &http.Client{Timeout: 10 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("daemon returned %s", res.Status)
+	}
+	var readings []liveReading
+	if err := json.NewDecoder(res.Body).Decode(&readings); err != nil {
+		return err
+	}
+	for _, r := range readings {
+		fmt.Println(makeDeviceHeader(r.DeviceName))
+		for _, metric := range []string{"temperature", "humidity", "co2", "lightlevel", "power", "battery"} {
+			if v, ok := metricValue(r.SwitchBotDeviceStatus, metric); ok {
+				fmt.Printf("%s: %g\n", metric, v)
+			}
+		}
+	}
+	return nil
+}
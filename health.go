@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	tokenHealthKey        = "token_health"
+	switchBotTokenProblem = "SwitchBot: APIトークンまたはシークレットが拒否されました"
+	mastodonTokenProblem  = "Mastodon: アクセストークンが拒否されました（失効している可能性があります）"
+)
+
+var errMastodonUnauthorized = errors.New("Mastodon rejected the access token")
+
+type tokenHealth struct {
+	CheckedAt time.Time
+	Problems  []string
+}
+
+// checkTokens ignores network errors, which say nothing about the tokens.
+func checkTokens(ctx context.Context) []string {
+	var problems []string
+	if _, err := fetchDevices(ctx); errors.Is(err, errSwitchBotUnauthorized) {
+		log.Printf("SwitchBot token check failed: %v", err)
+		problems = append(problems, switchBotTokenProblem)
+	}
+	if config.MastodonURL != "" {
+		if err := verifyMastodonToken(ctx); errors.Is(err, errMastodonUnauthorized) {
+			log.Printf("Mastodon token check failed: %v", err)
+			problems = append(problems, mastodonTokenProblem)
+		}
+	}
+	return problems
+}
+
+func verifyMastodonToken(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", config.MastodonURL+"/accounts/verify_credentials", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.MastodonToken)
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	switch {
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w (HTTP %d): %s", errMastodonUnauthorized, res.StatusCode, body)
+	case res.StatusCode >= 300:
+		return fmt.Errorf("verify_credentials failed: %s", body)
+	}
+	return nil
+}
+
+func checkTokensPeriodically(ctx context.Context, now time.Time) {
+	var health tokenHealth
+	if _, err := stateStore.Get(ctx, tokenHealthKey, &health); err != nil {
+		log.Printf("Failed to load token health: %v", err)
+		return
+	}
+	if now.Sub(health.CheckedAt) < time.Duration(config.TokenCheckHours)*time.Hour {
+		return
+	}
+	reportTokenProblems(ctx, health, checkTokens(ctx), now)
+}
+
+func reportTokenProblems(ctx context.Context, previous tokenHealth, problems []string, now time.Time) {
+	var fresh []string
+	for _, p := range problems {
+		if !slices.Contains(previous.Problems, p) {
+			fresh = append(fresh, p)
+		}
+	}
+	if len(fresh) > 0 {
+		message := "🔑 認証エラーを検出しました\n" + strings.Join(fresh, "\n")
+		if err := notifyBreakGlass(ctx, message); err != nil {
+			log.Printf("Failed to send break-glass alert: %v", err)
+			return
+		}
+	}
+	if err := stateStore.Put(ctx, tokenHealthKey, tokenHealth{CheckedAt: now, Problems: problems}); err != nil {
+		log.Printf("Failed to save token health: %v", err)
+	}
+}
+
+func recordSwitchBotAuthFailure(ctx context.Context, err error) {
+	if !errors.Is(err, errSwitchBotUnauthorized) {
+		return
+	}
+	var health tokenHealth
+	if _, err := stateStore.Get(ctx, tokenHealthKey, &health); err != nil {
+		log.Printf("Failed to load token health: %v", err)
+	}
+	problems := health.Problems
+	if !slices.Contains(problems, switchBotTokenProblem) {
+		problems = append(slices.Clone(problems), switchBotTokenProblem)
+	}
+	reportTokenProblems(ctx, health, problems, time.Now())
+}
+
+func notifyBreakGlass(ctx context.Context, message string) error {
+	if config.BreakGlassNtfyURL == "" {
+		log.Printf("No break-glass channel configured: %s", message)
+		return nil
+	}
+	if err := postNtfy(ctx, config.BreakGlassNtfyURL, config.BreakGlassNtfyToken, "SwitchBot bot", "urgent", "rotating_light", message); err != nil {
+		return err
+	}
+	log.Println("Break-glass alert sent:", message)
+	return nil
+}
+
+func runHealthCommand(ctx context.Context, _ []string) error {
+	var health tokenHealth
+	if _, err := stateStore.Get(ctx, tokenHealthKey, &health); err != nil {
+		return err
+	}
+	problems := checkTokens(ctx)
+	reportTokenProblems(ctx, health, problems, time.Now())
+	if len(problems) == 0 {
+		fmt.Println("All tokens OK")
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	return fmt.Errorf("%d token problems found", len(problems))
+}
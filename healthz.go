@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	readyzProbeKey = "readyz_probe"
+	readyzTimeout  = 10 * time.Second
+)
+
+type healthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+type readyzProbe struct {
+	name  string
+	check func(context.Context) error
+}
+
+type readyzCheck struct {
+	name string
+	err  error
+}
+
+var readyzCache struct {
+	sync.Mutex
+	checkedAt time.Time
+	checks    []readyzCheck
+}
+
+func registerHealth(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", withConfig(serveHealthz))
+	mux.HandleFunc("GET /readyz", withConfig(serveReadyz))
+}
+
+// serveHealthz checks no dependencies, since restarting would not fix them.
+func serveHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+func serveReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := readyzChecks(r.Context(), time.Now())
+	var b strings.Builder
+	failed := false
+	for _, c := range checks {
+		if c.err != nil {
+			failed = true
+			fmt.Fprintf(&b, "[-]%s failed: %v\n", c.name, c.err)
+		} else {
+			fmt.Fprintf(&b, "[+]%s ok\n", c.name)
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if failed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		b.WriteString("readyz check failed\n")
+	} else {
+		b.WriteString("readyz check passed\n")
+	}
+	w.Write([]byte(b.String()))
+}
+
+func readyzChecks(ctx context.Context, now time.Time) []readyzCheck {
+	readyzCache.Lock()
+	defer readyzCache.Unlock()
+	if readyzCache.checks != nil && now.Sub(readyzCache.checkedAt) < time.Duration(config.ReadinessCacheSeconds)*time.Second {
+		return readyzCache.checks
+	}
+	ctx, cancel := context.WithTimeout(ctx, readyzTimeout)
+	defer cancel()
+	readyzCache.checks = runReadyzChecks(ctx, now)
+	readyzCache.checkedAt = now
+	return readyzCache.checks
+}
+
+// switchBotHealth saves probes from spending the daily SwitchBot quota.
+var switchBotHealth struct {
+	sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+func recordSwitchBotHealth(err error, now time.Time) {
+	switchBotHealth.Lock()
+	defer switchBotHealth.Unlock()
+	switchBotHealth.checkedAt = now
+	switchBotHealth.err = err
+}
+
+func checkSwitchBotHealth(context.Context) error {
+	switchBotHealth.Lock()
+	defer switchBotHealth.Unlock()
+	if switchBotHealth.checkedAt.IsZero() {
+		return fmt.Errorf("no collection run has finished yet")
+	}
+	if switchBotHealth.err != nil {
+		return fmt.Errorf("last run at %s: %w", switchBotHealth.checkedAt.Format(time.RFC3339), switchBotHealth.err)
+	}
+	return nil
+}
+
+func runReadyzChecks(ctx context.Context, now time.Time) []readyzCheck {
+	probes := []readyzProbe{
+		{"switchbot", checkSwitchBotHealth},
+		{"state", probeStateStore},
+	}
+	for _, n := range notifiers {
+		if hc, ok := n.(healthChecker); ok {
+			probes = append(probes, readyzProbe{"notifier:" + n.Name(), hc.CheckHealth})
+		}
+	}
+
+	checks := make([]readyzCheck, len(probes))
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = readyzCheck{name: p.name, err: p.check(ctx)}
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+func probeStateStore(ctx context.Context) error {
+	var probe struct{}
+	_, err := stateStore.Get(ctx, readyzProbeKey, &probe)
+	return err
+}
+
+func dialHealth(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (mastodonNotifier) CheckHealth(ctx context.Context) error {
+	return verifyMastodonToken(ctx)
+}
+
+func (misskeyNotifier) CheckHealth(context.Context) error {
+	return misskeyRequest("i", map[string]any{}, nil)
+}
+
+func (n matrixNotifier) CheckHealth(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(n.homeserver, "/")+"/_matrix/client/v3/account/whoami", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+n.token)
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("whoami returned %s", res.Status)
+	}
+	return nil
+}
+
+func (n slackNotifier) CheckHealth(ctx context.Context) error {
+	return dialHealth(ctx, n.webhookURL)
+}
+
+func (n googleChatNotifier) CheckHealth(ctx context.Context) error {
+	return dialHealth(ctx, n.webhookURL)
+}
+
+func (n teamsNotifier) CheckHealth(ctx context.Context) error {
+	return dialHealth(ctx, n.webhookURL)
+}
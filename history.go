@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+func historyKey(deviceID string) string {
+	return "history:" + deviceID
+}
+
+func loadHistory(ctx context.Context, deviceID string) ([]SwitchBotDeviceStatus, error) {
+	var history []SwitchBotDeviceStatus
+	if _, err := stateStore.Get(ctx, historyKey(deviceID), &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// recordReading adds the reading to the history in time order, since webhook
+// samples may arrive later than readings taken after them.
+func recordReading(ctx context.Context, deviceID string, history []SwitchBotDeviceStatus, status SwitchBotDeviceStatus) error {
+	latest := status.ReadAt
+	if n := len(history); n > 0 && history[n-1].ReadAt.After(latest) {
+		latest = history[n-1].ReadAt
+	}
+	cutoff := latest.Add(-time.Duration(config.HistoryHours) * time.Hour)
+	if !status.ReadAt.After(cutoff) {
+		return nil
+	}
+	kept := make([]SwitchBotDeviceStatus, 0, len(history)+1)
+	for _, h := range history {
+		if h.ReadAt.After(cutoff) {
+			kept = append(kept, h)
+		}
+	}
+	i, _ := slices.BinarySearchFunc(kept, status.ReadAt, func(h SwitchBotDeviceStatus, t time.Time) int { return h.ReadAt.Compare(t) })
+	return stateStore.Put(ctx, historyKey(deviceID), slices.Insert(kept, i, status))
+}
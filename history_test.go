@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func useTestState(t *testing.T) {
+	t.Helper()
+	oldConfig, oldStore := config, stateStore
+	t.Cleanup(func() { config, stateStore = oldConfig, oldStore })
+	config = defaultConfig()
+	store, err := newFileStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stateStore = store
+}
+
+func TestRecordReading(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hours int) SwitchBotDeviceStatus {
+		return SwitchBotDeviceStatus{ReadAt: base.Add(time.Duration(hours) * time.Hour)}
+	}
+	tests := []struct {
+		name    string
+		history []SwitchBotDeviceStatus
+		status  SwitchBotDeviceStatus
+		want    []int
+	}{
+		{"empty history", nil, at(0), []int{0}},
+		{"appends newest", []SwitchBotDeviceStatus{at(-2), at(-1)}, at(0), []int{-2, -1, 0}},
+		{"inserts late sample in order", []SwitchBotDeviceStatus{at(-2), at(0)}, at(-1), []int{-2, -1, 0}},
+		{"drops entries past the cutoff", []SwitchBotDeviceStatus{at(-30), at(-1)}, at(0), []int{-1, 0}},
+		{"ignores sample older than the cutoff", []SwitchBotDeviceStatus{at(-1), at(0)}, at(-25), []int{-1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestState(t)
+			config.HistoryHours = 24
+			ctx := context.Background()
+			if err := stateStore.Put(ctx, historyKey("dev"), tt.history); err != nil {
+				t.Fatal(err)
+			}
+			if err := recordReading(ctx, "dev", tt.history, tt.status); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadHistory(ctx, "dev")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d", len(got), len(tt.want))
+			}
+			for i, hours := range tt.want {
+				if want := at(hours).ReadAt; !got[i].ReadAt.Equal(want) {
+					t.Errorf("entry %d: got %v, want %v", i, got[i].ReadAt, want)
+				}
+			}
+		})
+	}
+}
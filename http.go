@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	httpClient     *http.Client
+	httpClientOnce sync.Once
+)
+
+func sharedHTTPClient() *http.Client {
+	httpClientOnce.Do(func() {
+		httpClient = newHTTPClient()
+	})
+	return httpClient
+}
+
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = config.HTTPMaxIdleConns
+	transport.MaxIdleConnsPerHost = config.HTTPMaxIdleConns
+	transport.IdleConnTimeout = time.Duration(config.HTTPIdleConnTimeoutSeconds) * time.Second
+	transport.ForceAttemptHTTP2 = config.HTTPForceHTTP2
+	// Without HTTPProxy, the cloned transport keeps using HTTPS_PROXY and
+	// NO_PROXY from the environment.
+	if proxy, err := url.Parse(config.HTTPProxy); err == nil && config.HTTPProxy != "" {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	timeout := time.Duration(config.HTTPTimeoutSeconds) * time.Second
+	if config.Chaos != nil {
+		return &http.Client{Transport: newChaosTransport(transport, *config.Chaos), Timeout: timeout}
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+func validateHTTPProxy(proxy string) error {
+	if proxy == "" {
+		return nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid HTTPProxy %q", proxy)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const localizedNumberPattern = `(-?[\d.,'’\x{00a0}\x{202f} ]*\d)`
+
+var (
+	localeOnce       sync.Once
+	localeTag        language.Tag
+	localePrinter    *message.Printer
+	localeGroup      string
+	localeDecimal    string
+	localeLang       string
+	localeFahrenheit bool
+)
+
+var messageCatalog = map[string]map[string]string{
+	"en": {
+		"温度":                   "Temperature",
+		"湿度":                   "Humidity",
+		"照度":                   "Light level",
+		"電力":                   "Power",
+		"電圧":                   "Voltage",
+		"露点":                   "Dew point",
+		"不快指数":                 "Discomfort index",
+		"絶対湿度":                 "Absolute humidity",
+		"電流":                   "Current",
+		"度":                    "°C",
+		"最低":                   "min ",
+		"最高":                   "max ",
+		"平均":                   "avg ",
+		"過去24時間":               "past 24 hours",
+		"CO2ピーク":               "CO2 peak",
+		"現在":                   "Now",
+		"電池":                   "Battery",
+		"SwitchBot 日次レポート（%s）": "SwitchBot daily report (%s)",
+		"🌙 %s〜%sのまとめ":          "🌙 Summary %s–%s",
+		"最終更新":                 "Last updated",
+		"🎬 シーン「%s」を実行しました":    "🎬 Ran scene \"%s\"",
+		"🎬 シーン「%s」の実行に失敗しました": "🎬 Failed to run scene \"%s\"",
+		"🪫 そろそろ電池交換（%s頃）":     "🪫 Replace battery soon (~%s)",
+		"📝 実行予定: %s":          "📝 Would run: %s",
+		"シーン「%s」":             "scene \"%s\"",
+		"%s を %s":             "%s %s",
+		"🚪 開いています":            "🚪 Open",
+		"🚪 閉まっています":           "🚪 Closed",
+		"⚠️ 開いたままです":          "⚠️ Left open",
+		"🔒 施錠":                "🔒 Locked",
+		"🔓 解錠":                "🔓 Unlocked",
+		"⚠️ 施錠エラー":            "⚠️ Lock jammed",
+		"🚪 ドアが開いています":         "🚪 Door open",
+		"🚪 ドアが閉まっています":        "🚪 Door closed",
+		"👀 動きを検知":             "👀 Motion detected",
+		"💤 動きなし":              "💤 No motion",
+		"📡 BLEとクラウドの測定値が一致しません（%s）": "📡 BLE and cloud readings disagree (%s)",
+		"クラウド":              "cloud",
+		"再試行後の値":            "value after retrying",
+		"キャッシュされた値":         "cached value",
+		"校正済みの値":            "calibrated value",
+		"補間された値":            "interpolated value",
+		"データ完全性":            "Completeness",
+		"欠測":                "Missing",
+		"欠測（%s）":            "Missing (%s)",
+		"他%d件":              "%d more",
+		"⚠️ オフライン（%s）":      "⚠️ Offline (%s)",
+		"対応していないデバイス":       "device not supported",
+		"デバイスが見つかりません":      "device not found",
+		"対応していない操作":         "operation not supported",
+		"デバイスがオフライン":        "device offline",
+		"ハブがオフライン":          "hub offline",
+		"⚠️ 取得できませんでした: %s": "⚠️ Could not read: %s",
+		"%s（%s）":            "%s (%s)",
+		"タイムアウト":            "timeout",
+		"認証エラー":             "authentication error",
+		"エラー":               "error",
+		"、":                 ", ",
+	},
+}
+
+func initLocale() {
+	localeOnce.Do(func() {
+		if config.Locale == "" {
+			return
+		}
+		tag, err := language.Parse(config.Locale)
+		if err != nil {
+			return
+		}
+		localeTag = tag
+		base, _ := tag.Base()
+		localeLang = base.String()
+		region, _ := tag.Region()
+		switch region.String() {
+		case "US", "LR", "MM", "BS", "KY", "PW", "FM", "MH":
+			localeFahrenheit = true
+		}
+		localePrinter = message.NewPrinter(tag)
+		sample := []rune(localePrinter.Sprintf("%.1f", 1234.5))
+		localeGroup = string(sample[1])
+		localeDecimal = string(sample[len(sample)-2])
+	})
+}
+
+func tr(ja string) string {
+	initLocale()
+	if s, ok := messageCatalog[localeLang][ja]; ok {
+		return s
+	}
+	return ja
+}
+
+func usesFahrenheit() bool {
+	switch strings.ToUpper(config.TemperatureUnit) {
+	case "F":
+		return true
+	case "C":
+		return false
+	}
+	initLocale()
+	return localeFahrenheit
+}
+
+func displayTemperature(c float64) (float64, string) {
+	if usesFahrenheit() {
+		return c*9/5 + 32, "°F"
+	}
+	return c, tr("度")
+}
+
+func celsiusFromDisplay(v float64) float64 {
+	if usesFahrenheit() {
+		return (v - 32) * 5 / 9
+	}
+	return v
+}
+
+// configuredInFahrenheit ignores Locale, which must not change what thresholds mean.
+func configuredInFahrenheit() bool {
+	return strings.EqualFold(config.TemperatureUnit, "F")
+}
+
+func celsiusFromConfig(v float64) float64 {
+	if configuredInFahrenheit() {
+		return (v - 32) * 5 / 9
+	}
+	return v
+}
+
+func formatNumber(v float64, decimals int) string {
+	initLocale()
+	if localePrinter == nil {
+		return strconv.FormatFloat(v, 'f', decimals, 64)
+	}
+	return localePrinter.Sprintf("%.*f", decimals, v)
+}
+
+func formatInt(v int) string {
+	initLocale()
+	if localePrinter == nil {
+		return strconv.Itoa(v)
+	}
+	return localePrinter.Sprintf("%d", v)
+}
+
+func parseLocalizedNumber(s string) (float64, error) {
+	initLocale()
+	s = strings.TrimSpace(s)
+	if localePrinter != nil {
+		s = strings.ReplaceAll(s, localeGroup, "")
+		s = strings.ReplaceAll(s, localeDecimal, ".")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func formatDate(t time.Time) string {
+	initLocale()
+	t = t.In(timeLocation())
+	if localePrinter == nil {
+		return t.Format(time.DateOnly)
+	}
+	base, _ := localeTag.Base()
+	region, _ := localeTag.Region()
+	switch base.String() {
+	case "ja", "zh", "ko":
+		return t.Format("2006/01/02")
+	case "en":
+		if region.String() == "US" {
+			return t.Format("01/02/2006")
+		}
+		return t.Format("02/01/2006")
+	case "de", "ru", "pl", "cs", "fi", "nb", "da", "tr", "uk":
+		return t.Format("02.01.2006")
+	case "fr", "es", "it", "pt", "nl", "el":
+		return t.Format("02/01/2006")
+	}
+	return t.Format(time.DateOnly)
+}
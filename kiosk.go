@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const kioskPath = "/kiosk.json"
+
+// kioskResponse holds one object per room with the KioskFields it reports.
+type kioskResponse struct {
+	Rooms []map[string]any `json:"rooms"`
+}
+
+var kioskFieldNames = []string{"temperature", "humidity", "co2", "battery", "readAt"}
+
+func validateKioskFields(fields []string) error {
+	for _, field := range fields {
+		if !slices.Contains(kioskFieldNames, field) {
+			return fmt.Errorf("unknown kiosk field %q", field)
+		}
+	}
+	return nil
+}
+
+func serveKiosk(w http.ResponseWriter, r *http.Request) {
+	if config.KioskSecret != "" && !validKioskSignature(r, time.Now()) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	rooms := []map[string]any{}
+	for _, reading := range latestDashboardReadings() {
+		if len(config.KioskDevices) > 0 && !slices.Contains(config.KioskDevices, reading.Device.DeviceName) {
+			continue
+		}
+		rooms = append(rooms, kioskRoom(reading))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	json.NewEncoder(w).Encode(kioskResponse{Rooms: rooms})
+}
+
+func kioskRoom(reading deviceReading) map[string]any {
+	room := map[string]any{"name": reading.Device.DeviceName}
+	for _, field := range config.KioskFields {
+		if field == "readAt" {
+			room[field] = reading.Status.ReadAt
+			continue
+		}
+		if v, ok := metricValue(reading.Status, field); ok {
+			room[field] = v
+		}
+	}
+	return room
+}
+
+func kioskSignature(expires int64) string {
+	mac := hmac.New(sha256.New, []byte(config.KioskSecret))
+	fmt.Fprintf(mac, "%s?expires=%d", kioskPath, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func validKioskSignature(r *http.Request, now time.Time) bool {
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || now.Unix() > expires {
+		return false
+	}
+	want := kioskSignature(expires)
+	return subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("sig")), []byte(want)) == 1
+}
+
+func runKioskCommand(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("kiosk-url", flag.ContinueOnError)
+	base := fs.String("base", "http://localhost"+config.DaemonListen, "base URL of the daemon")
+	ttl := fs.String("ttl", "30d", "how long the URL stays valid")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if config.KioskSecret == "" {
+		fmt.Println(strings.TrimRight(*base, "/") + kioskPath)
+		return nil
+	}
+	d, err := parseLookback(*ttl)
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(d).Unix()
+	fmt.Printf("%s%s?expires=%d&sig=%s\n", strings.TrimRight(*base, "/"), kioskPath, expires, kioskSignature(expires))
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func configPath() string {
+	if dir := os.Getenv("CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	return "config.json"
+}
+
+// secretFiles skips the kubelet's dot-prefixed entries such as ..data.
+func secretFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+func loadSecretFiles(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	names, err := secretFiles(dir)
+	if err != nil {
+		return fmt.Errorf("reading SECRETS_DIR failed: %w", err)
+	}
+	for _, name := range names {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		value := strings.TrimSpace(string(b))
+		asString, _ := json.Marshal(value)
+		err = decodeSecretField(name, asString)
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			err = decodeSecretField(name, []byte(value))
+		}
+		if err != nil {
+			return fmt.Errorf("secret file %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func decodeSecretField(name string, value []byte) error {
+	doc, err := json.Marshal(map[string]json.RawMessage{name: value})
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(doc)))
+	dec.DisallowUnknownFields()
+	return dec.Decode(&config)
+}
+
+func configFingerprint() (string, error) {
+	h := sha256.New()
+	b, err := os.ReadFile(configPath())
+	if err != nil {
+		return "", err
+	}
+	h.Write(b)
+	if dir := os.Getenv("SECRETS_DIR"); dir != "" {
+		names, err := secretFiles(dir)
+		if err != nil {
+			return "", err
+		}
+		for _, name := range names {
+			b, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "\x00%s\x00%d\x00", name, len(b))
+			h.Write(b)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// configMu guards config and the globals applyConfig builds from it. Never
+// take it twice: a pending reload blocks a second read lock.
+var configMu sync.RWMutex
+
+// withConfig must not wrap streams, or a reload would wait for them to end.
+func withConfig(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		configMu.RLock()
+		defer configMu.RUnlock()
+		h(w, r)
+	}
+}
+
+func readingConfig[T any](f func() T) T {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return f()
+}
+
+func reloadConfig() error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	previous := config
+	err := loadConfig()
+	if err == nil {
+		if changed := restartOnlyChanges(previous, config); len(changed) > 0 {
+			err = fmt.Errorf("changing %s requires a restart", strings.Join(changed, ", "))
+		}
+	}
+	if err == nil {
+		err = applyConfig()
+	}
+	if err != nil {
+		config = previous
+		if restoreErr := applyConfig(); restoreErr != nil {
+			log.Printf("Restoring the previous config failed: %v", restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// restartOnlyChanges lists changed fields that are only read at startup.
+func restartOnlyChanges(old, cur Config) []string {
+	var changed []string
+	for _, f := range []struct {
+		name    string
+		changed bool
+	}{
+		{"HTTPMaxIdleConns", old.HTTPMaxIdleConns != cur.HTTPMaxIdleConns},
+		{"HTTPIdleConnTimeoutSeconds", old.HTTPIdleConnTimeoutSeconds != cur.HTTPIdleConnTimeoutSeconds},
+		{"HTTPForceHTTP2", old.HTTPForceHTTP2 != cur.HTTPForceHTTP2},
+		{"HTTPTimeoutSeconds", old.HTTPTimeoutSeconds != cur.HTTPTimeoutSeconds},
+		{"HTTPProxy", old.HTTPProxy != cur.HTTPProxy},
+		{"RoleARN", old.RoleARN != cur.RoleARN},
+		{"Chaos", !reflect.DeepEqual(old.Chaos, cur.Chaos)},
+	} {
+		if f.changed {
+			changed = append(changed, f.name)
+		}
+	}
+	return changed
+}
+
+func watchConfig(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	last, err := configFingerprint()
+	if err != nil {
+		log.Printf("Failed to fingerprint the config: %v", err)
+	}
+	var tick <-chan time.Time
+	if config.ConfigReloadSeconds > 0 {
+		ticker := time.NewTicker(time.Duration(config.ConfigReloadSeconds) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-tick:
+			current, err := configFingerprint()
+			if err != nil {
+				log.Printf("Failed to fingerprint the config: %v", err)
+				continue
+			}
+			if current == last {
+				continue
+			}
+		}
+		if current, err := configFingerprint(); err == nil {
+			last = current
+		}
+		if err := reloadConfig(); err != nil {
+			log.Printf("Config reload failed, keeping the previous config: %v", err)
+			continue
+		}
+		log.Printf("Config reloaded from %s", configPath())
+	}
+}
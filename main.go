@@ -6,69 +6,126 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	maxPostPages    = 10
+	maxPostPageSize = 40
 )
 
 var (
-	batteryCheckPostCount = 7
-	config                = Config{}
-	htmlTagRe             = regexp.MustCompile(`<.*?>`)
-	targetDeviceTypes     = map[string]struct{}{
-		"Meter":         {},
-		"MeterPro(CO2)": {},
+	batteryCheckPostCount    = 7
+	config                   = Config{}
+	htmlTagRe                = regexp.MustCompile(`<.*?>`)
+	defaultTargetDeviceTypes = []string{
+		"Meter",
+		"MeterPro(CO2)",
+		"Hub 2",
+		"Plug Mini (US)",
+		"Plug Mini (JP)",
+		"Smart Lock",
+		"Contact Sensor",
 	}
 )
 
-type Config struct {
-	SwitchBotToken        string
-	SwitchBotSecret       string
-	MastodonURL           string
-	MastodonToken         string
-	BatteryCheckPostCount int
-}
-
 type SwitchBotDevice struct {
 	DeviceID   string `json:"deviceId"`
 	DeviceType string `json:"deviceType"`
 	DeviceName string `json:"deviceName"`
+	RemoteType string `json:"remoteType,omitempty"`
 }
 
 type SwitchBotDeviceListBody struct {
-	DeviceList []SwitchBotDevice `json:"deviceList"`
+	DeviceList         []SwitchBotDevice `json:"deviceList"`
+	InfraredRemoteList []SwitchBotDevice `json:"infraredRemoteList"`
 }
 
 type SwitchBotDeviceStatus struct {
-	Battery     *int     `json:"battery,omitempty"`
-	Temperature *float64 `json:"temperature,omitempty"`
-	Humidity    *float64 `json:"humidity,omitempty"`
-	CO2         *int     `json:"CO2,omitempty"`
+	Battery     *int      `json:"battery,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Humidity    *float64  `json:"humidity,omitempty"`
+	CO2         *int      `json:"CO2,omitempty"`
+	LightLevel  *int      `json:"lightLevel,omitempty"`
+	Voltage     *float64  `json:"voltage,omitempty"`
+	Power       *float64  `json:"weight,omitempty"`
+	Current     *float64  `json:"electricCurrent,omitempty"`
+	PowerState  *string   `json:"power,omitempty"`
+	LockState   *string   `json:"lockState,omitempty"`
+	DoorState   *string   `json:"doorState,omitempty"`
+	OpenState   *string   `json:"openState,omitempty"`
+	Moving      *bool     `json:"moveDetected,omitempty"`
+	ReadAt      time.Time `json:"readAt,omitzero"`
+	// Source is "cloud" or "ble" when BLEEnabled, and empty otherwise.
+	Source string `json:"source,omitempty"`
+	// SourceConflict describes how the cloud and BLE readings disagreed.
+	SourceConflict string `json:"-"`
+	// Raw is the reading as received when Filters changed any value.
+	Raw *SwitchBotDeviceStatus `json:"raw,omitempty"`
+	// Quality holds quality* flags for less trustworthy readings.
+	Quality []string `json:"quality,omitempty"`
+	// Offline is set, with no values, when the device or its hub is offline.
+	Offline string `json:"-"`
+}
+
+type deviceReading struct {
+	Device SwitchBotDevice
+	Status SwitchBotDeviceStatus
 }
 
 type SwitchBotResponse[T any] struct {
 	StatusCode int    `json:"statusCode"`
 	Message    string `json:"message"`
 	Body       T      `json:"body"`
+	// Retries counts the busy or rate-limited responses before this one.
+	Retries int `json:"-"`
 }
 
 type MastodonPost struct {
-	Content string `json:"content"`
+	ID        string    `json:"id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type mastodonAccountCache struct {
+	Fingerprint string `json:"fingerprint"`
+	ID          string `json:"id"`
+}
+
+type cachedResponse struct {
+	URL          string          `json:"url"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"lastModified,omitempty"`
+	Body         json.RawMessage `json:"body"`
 }
 
 func main() {
 	if isLambda() {
-		lambda.Start(handler)
+		lambda.Start(lambdaHandler)
+	} else if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), os.Args[1:]); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
 	} else if err := handler(context.Background()); err != nil {
 		fmt.Println("Error:", err)
 	}
@@ -78,119 +135,400 @@ func isLambda() bool {
 	return os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != ""
 }
 
-func handler(ctx context.Context) error {
+func setup() error {
 	if err := loadConfig(); err != nil {
 		return fmt.Errorf("loadConfig error: %w", err)
 	}
+	return applyConfig()
+}
 
-	devices, err := fetchDevices()
-	if err != nil {
-		return fmt.Errorf("fetchDevices error: %w", err)
+func applyConfig() error {
+	if config.Chaos != nil {
+		log.Printf("Chaos failure injection is enabled: %+v", *config.Chaos)
 	}
 
-	posts, err := fetchRecentMastodonPosts()
+	store, err := newStateStore()
 	if err != nil {
-		return fmt.Errorf("fetchRecentMastodonPosts error: %w", err)
+		return fmt.Errorf("newStateStore error: %w", err)
+	}
+	stateStore = store
+	if plugins, err = discoverPlugins(config.PluginDir); err != nil {
+		return fmt.Errorf("discoverPlugins error: %w", err)
+	}
+	if notifiers, err = newNotifiers(); err != nil {
+		return fmt.Errorf("newNotifiers error: %w", err)
+	}
+	if err := validateDestinationBudgets(config.DestinationBudgets); err != nil {
+		return err
 	}
 
-	var messages []string
-	for _, device := range devices {
-		if !isTargetDevice(device.DeviceType) {
-			continue
+	built, err := buildConditions(config.Conditions)
+	if err != nil {
+		return fmt.Errorf("buildConditions error: %w", err)
+	}
+	conditions = built
+	if err := validateAlertRules(config.Alerts); err != nil {
+		return fmt.Errorf("validateAlertRules error: %w", err)
+	}
+	if config.Away != nil {
+		if err := validateAlertRules(config.Away.Alerts); err != nil {
+			return fmt.Errorf("validateAlertRules error: away: %w", err)
 		}
-		message, err := generateStatusMessage(ctx, device, posts)
+	}
+	if err := validateOfficeProfile(config.Office); err != nil {
+		return fmt.Errorf("validateOfficeProfile error: %w", err)
+	}
+	if err := validateEnergyAdvisor(config.EnergyAdvisor); err != nil {
+		return fmt.Errorf("validateEnergyAdvisor error: %w", err)
+	}
+	if err := validateTimeOfUse(config.TimeOfUse); err != nil {
+		return fmt.Errorf("validateTimeOfUse error: %w", err)
+	}
+	if err := validateVisibility("PostVisibility", config.PostVisibility); err != nil {
+		return err
+	}
+	if err := validateVisibility("UrgentVisibility", config.UrgentVisibility); err != nil {
+		return err
+	}
+	if err := validateProfileFields(config.ProfileFields); err != nil {
+		return fmt.Errorf("validateProfileFields error: %w", err)
+	}
+	if err := validatePinnedStatus(config.PinnedStatus); err != nil {
+		return fmt.Errorf("validatePinnedStatus error: %w", err)
+	}
+	if err := validateQuietHours(config.QuietHours); err != nil {
+		return fmt.Errorf("validateQuietHours error: %w", err)
+	}
+	if err := validateKioskFields(config.KioskFields); err != nil {
+		return fmt.Errorf("validateKioskFields error: %w", err)
+	}
+	if err := validateSceneBindings(config.Scenes); err != nil {
+		return fmt.Errorf("validateSceneBindings error: %w", err)
+	}
+	if derivedPrograms, err = compileDerivedMetrics(config.DerivedMetrics); err != nil {
+		return fmt.Errorf("compileDerivedMetrics error: %w", err)
+	}
+	if err := validateReadingFilters(config.Filters); err != nil {
+		return fmt.Errorf("validateReadingFilters error: %w", err)
+	}
+	if err := validateHTTPProxy(config.HTTPProxy); err != nil {
+		return err
+	}
+	if err := validateMetricsDestinations(config.MetricsDestinations); err != nil {
+		return err
+	}
+	if config.ChartHour < 0 || config.ChartHour > 23 {
+		return fmt.Errorf("invalid ChartHour %d", config.ChartHour)
+	}
+	if config.AlertmanagerEnabled && config.AlertmanagerToken == "" {
+		return fmt.Errorf("AlertmanagerToken is required with AlertmanagerEnabled")
+	}
+	if config.SwitchBotRetryBaseMillis < 0 {
+		return fmt.Errorf("SwitchBotRetryBaseMillis must not be negative")
+	}
+	if err := validateGapPolicy(config.GapPolicy); err != nil {
+		return err
+	}
+	if err := validateBatteryTiers(config.BatteryTiers); err != nil {
+		return fmt.Errorf("validateBatteryTiers error: %w", err)
+	}
+	if err := validateCommandRoles(config.CommandRoles); err != nil {
+		return fmt.Errorf("validateCommandRoles error: %w", err)
+	}
+	if (config.MetricsBackend == "timestream" || config.TimestreamDatabase != "") && (config.TimestreamDatabase == "" || config.TimestreamTable == "") {
+		return fmt.Errorf("TimestreamDatabase and TimestreamTable are both required for Timestream")
+	}
+	if (config.MetricsBackend == "remote_write" || config.MetricsBackend == "pushgateway") && config.PrometheusURL == "" {
+		return fmt.Errorf("PrometheusURL is required for MetricsBackend %q", config.MetricsBackend)
+	}
+	switch strings.ToUpper(config.TemperatureUnit) {
+	case "", "C", "F":
+	default:
+		return fmt.Errorf("invalid TemperatureUnit %q", config.TemperatureUnit)
+	}
+	return nil
+}
+
+func handler(ctx context.Context) error {
+	if err := setup(); err != nil {
+		return err
+	}
+	return run(ctx)
+}
+
+func run(ctx context.Context) (err error) {
+	recordOps(func(s *opsStats) { s.Runs++ })
+	defer func() {
 		if err != nil {
-			continue
+			recordOps(func(s *opsStats) { s.Errors++ })
+		}
+		now := time.Now()
+		flushOps(ctx, now)
+		postOpsSummary(ctx, now)
+	}()
+
+	remaining := checkSwitchBotBudget(ctx, time.Now())
+	devices, err := fetchDevices(ctx)
+	recordSwitchBotHealth(err, time.Now())
+	if err != nil {
+		recordSwitchBotAuthFailure(ctx, err)
+		devices = bleFallbackDevices(time.Now())
+		if len(devices) == 0 {
+			return fmt.Errorf("fetchDevices error: %w", err)
 		}
-		log.Println("Generated status message:", message)
-		messages = append(messages, message)
+		log.Printf("fetchDevices failed, continuing with %d devices seen over BLE: %v", len(devices), err)
+	} else {
+		rememberDevices(devices)
 	}
+	checkTokensPeriodically(ctx, time.Now())
+	checkForRelease(ctx, time.Now())
+	refreshAwayMode(ctx, time.Now())
+	processMentions(ctx)
+	processCommandQueue(ctx)
+
+	fetched, failed := fetchReadings(ctx, budgetDevices(devices, remaining))
+	reportFetchFailures(ctx, failed, time.Now())
+	readings, offline := splitOffline(fetched)
+	bootstrapHistoryFromPosts(ctx, readings)
+	readings = filterReadings(ctx, readings)
 
-	if len(messages) > 0 {
-		return postToMastodon(strings.Join(messages, "\n"))
+	recordDashboardReadings(readings)
+	latest := latestReadings(readings)
+	var sections []deviceSection
+	var posted []deviceReading
+	var all []string
+	for _, r := range readings {
+		section := generateStatusMessage(ctx, r.Device, r.Status, latest)
+		all = append(all, section.Message)
+		if !section.Notable && quietSuppressed(ctx, r.Device, r.Status, time.Now()) {
+			log.Printf("Omitting %s from the post: no meaningful change", r.Device.DeviceName)
+			continue
+		}
+		log.Println("Generated status message:", section.Message)
+		sections = append(sections, section)
+		posted = append(posted, r)
+	}
+	for _, r := range offline {
+		section := offlineSection(ctx, r)
+		all = append(all, section.Message)
+		log.Println("Generated status message:", section.Message)
+		sections = append(sections, section)
+	}
+	if len(failed) > 0 {
+		section := failureSection(failed)
+		all = append(all, section.Message)
+		log.Println("Generated status message:", section.Message)
+		sections = append(sections, section)
 	}
-	return nil
-}
 
-func loadConfig() error {
-	if isLambda() {
-		if envPostCount := os.Getenv("BATTERY_CHECK_POST_COUNT"); envPostCount != "" {
-			if count, err := strconv.Atoi(envPostCount); err == nil && count > 0 {
-				batteryCheckPostCount = count
-			}
+	// Edited in place, so kept current even in quiet or away mode.
+	if config.PinnedStatus != "" && usesMastodon() && len(all) > 0 {
+		if err := updatePinnedStatus(ctx, strings.Join(all, "\n")); err != nil {
+			log.Printf("Failed to update pinned status: %v", err)
 		}
-		config = Config{
-			SwitchBotToken:        os.Getenv("SWITCHBOT_API_TOKEN"),
-			SwitchBotSecret:       os.Getenv("SWITCHBOT_API_SECRET"),
-			MastodonURL:           os.Getenv("MASTODON_API_URL"),
-			MastodonToken:         os.Getenv("MASTODON_ACCESS_TOKEN"),
-			BatteryCheckPostCount: batteryCheckPostCount,
+	}
+	if len(config.ProfileFields) > 0 && usesMastodon() {
+		if err := updateProfileFields(ctx, readings, time.Now()); err != nil {
+			log.Printf("Failed to update profile fields: %v", err)
 		}
+	}
+
+	publishStatusEvents(ctx, readings)
+	publishMQTT(ctx, readings)
+
+	pruneDaily(ctx, time.Now())
+	if config.EnergyAdvisor != nil {
+		runEnergyAdvisor(ctx, readings, time.Now())
+	}
+	if config.TimeOfUse != nil {
+		runTimeOfUse(ctx, readings, time.Now())
+	}
+
+	if config.Office != nil {
+		now := time.Now()
+		runOfficeProfile(ctx, readings, now)
+		if !config.Office.isWorkday(now) {
+			return nil
+		}
+	}
+
+	if holdForQuietHours(ctx, time.Now()) {
+		log.Println("Holding back the post during quiet hours")
 		return nil
 	}
-	file, err := os.Open("config.json")
+	postQuietHoursCatchUp(ctx, readings, time.Now())
+
+	if len(sections) > 0 && awayPostDue(ctx, time.Now()) {
+		charts, chartDay := dailyCharts(ctx, readings)
+		if err := notifySections(ctx, sections, charts); err != nil {
+			return err
+		}
+		markChartsPosted(ctx, chartDay)
+		recordQuietPosts(ctx, posted, time.Now())
+	}
+	return nil
+}
+
+func fetchDevices(ctx context.Context) ([]SwitchBotDevice, error) {
+	body, err := fetchDeviceList(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer file.Close()
-	return json.NewDecoder(file).Decode(&config)
+	return body.DeviceList, nil
 }
 
-func fetchDevices() ([]SwitchBotDevice, error) {
+func fetchDeviceList(ctx context.Context) (SwitchBotDeviceListBody, error) {
 	url := "https://api.switch-bot.com/v1.1/devices"
 	var resp SwitchBotResponse[SwitchBotDeviceListBody]
-	if err := requestWithBackoff(url, generateSwitchBotHeaders(), &resp); err != nil {
-		return nil, err
+	if err := requestWithBackoff(ctx, url, &resp); err != nil {
+		return SwitchBotDeviceListBody{}, err
 	}
-	return resp.Body.DeviceList, nil
+	return resp.Body, nil
+}
+
+func requestWithBackoff[T any](ctx context.Context, url string, out *SwitchBotResponse[T]) error {
+	return switchBotRequest(ctx, "GET", url, nil, out)
 }
 
-func requestWithBackoff[T any](url string, headers map[string]string, out *SwitchBotResponse[T]) error {
-	for attempt := range 5 {
-		req, err := http.NewRequest("GET", url, nil)
+// switchBotRequest retries on 429, 5xx, and statusCode 190 (device busy).
+func switchBotRequest[T any](ctx context.Context, method, url string, payload []byte, out *SwitchBotResponse[T]) error {
+	maxAttempts := destinationAttempts("switchbot", config.SwitchBotMaxAttempts)
+	retries := 0
+	clockCorrected := false
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := destinationTimeout(ctx, "switchbot")
+		req, err := http.NewRequestWithContext(attemptCtx, method, url, bytes.NewReader(payload))
 		if err != nil {
+			cancel()
 			return fmt.Errorf("request creation failed: %w", err)
 		}
-		for k, v := range headers {
+		for k, v := range generateSwitchBotHeaders() {
 			req.Header.Set(k, v)
 		}
 
-		res, err := http.DefaultClient.Do(req)
+		sentAt := time.Now()
+		recordOps(func(s *opsStats) { s.SwitchBotCalls++ })
+		res, err := sharedHTTPClient().Do(req)
 		if err != nil {
+			cancel()
 			return fmt.Errorf("HTTP request failed: %w", err)
 		}
-		defer res.Body.Close()
-
 		bodyBytes, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		cancel()
 		if err != nil {
 			return fmt.Errorf("reading response failed: %w", err)
 		}
 
+		skew, corrected := observeSwitchBotClock(res, sentAt)
+		if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+			if corrected && !clockCorrected {
+				log.Printf("SwitchBot rejected the signature; retrying with clock corrected by %v", skew)
+				clockCorrected = true
+				continue
+			}
+			return signatureError(res.StatusCode, bodyBytes, skew)
+		}
+
+		if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+			if !retryableStatus(method, res) {
+				return fmt.Errorf("HTTP %s: %s", res.Status, bodyBytes)
+			}
+			if attempt >= maxAttempts {
+				return fmt.Errorf("max retries reached for HTTP %s: %s", res.Status, bodyBytes)
+			}
+			retries++
+			recordOps(func(s *opsStats) { s.Retries++ })
+			wait := switchBotRetryDelay(res.Header.Get("Retry-After"), retries)
+			fmt.Printf("[Retry %d/%d] HTTP %s received. Retrying after %v...\n", attempt, maxAttempts, res.Status, wait)
+			if err := waitForRetry(ctx, wait); err != nil {
+				return fmt.Errorf("giving up on HTTP %s: %w", res.Status, err)
+			}
+			continue
+		}
+
 		if err := json.Unmarshal(bodyBytes, out); err != nil {
 			return fmt.Errorf("unmarshal failed: %w\nResponse body: %s", err, string(bodyBytes))
 		}
 
 		switch out.StatusCode {
 		case 100:
+			out.Retries = retries
 			return nil
 		case 190:
-			if attempt < 4 {
-				wait := time.Duration(1<<attempt) * time.Second
-				fmt.Printf("[Retry %d/5] statusCode 190 received. Retrying after %v...\n", attempt+1, wait)
-				time.Sleep(wait)
-				continue
+			if attempt >= maxAttempts {
+				return fmt.Errorf("max retries reached for statusCode 190: %s", out.Message)
+			}
+			retries++
+			recordOps(func(s *opsStats) { s.Retries++ })
+			wait := switchBotRetryDelay("", retries)
+			fmt.Printf("[Retry %d/%d] statusCode 190 received. Retrying after %v...\n", attempt, maxAttempts, wait)
+			if err := waitForRetry(ctx, wait); err != nil {
+				return fmt.Errorf("giving up on statusCode 190: %w", err)
 			}
-			return fmt.Errorf("max retries reached for statusCode 190: %s", out.Message)
 		default:
-			return fmt.Errorf("unexpected statusCode %d: %s", out.StatusCode, out.Message)
+			return &switchBotStatusError{Code: out.StatusCode, Message: out.Message}
 		}
 	}
-	return fmt.Errorf("unreachable: requestWithBackoff fell through")
+}
+
+// retryableStatus only retries a POST on 429 or on 503 with Retry-After,
+// since one that failed otherwise may already have run.
+func retryableStatus(method string, res *http.Response) bool {
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests:
+		return true
+	case method == http.MethodGet:
+		return true
+	}
+	return res.StatusCode == http.StatusServiceUnavailable && res.Header.Get("Retry-After") != ""
+}
+
+const switchBotMaxRetryAfter = time.Minute
+
+// switchBotRetryDelay adds jitter so devices fetched concurrently do not retry in lockstep.
+func switchBotRetryDelay(retryAfter string, retry int) time.Duration {
+	if d, ok := parseRetryAfter(retryAfter, time.Now()); ok {
+		return min(d, switchBotMaxRetryAfter)
+	}
+	wait := time.Duration(config.SwitchBotRetryBaseMillis) * time.Millisecond << min(retry-1, 10)
+	return wait + rand.N(wait/2+1)
+}
+
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func waitForRetry(ctx context.Context, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return fmt.Errorf("retry in %v would pass the deadline", d)
+	}
+	return sleepContext(ctx, d)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func generateSwitchBotHeaders() map[string]string {
 	nonce := uuid.New().String()
-	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	timestamp := strconv.FormatInt(switchBotNow().UnixMilli(), 10)
 	message := config.SwitchBotToken + timestamp + nonce
 
 	h := hmac.New(sha256.New, []byte(config.SwitchBotSecret))
@@ -207,29 +545,108 @@ func generateSwitchBotHeaders() map[string]string {
 	}
 }
 
-func fetchRecentMastodonPosts() ([]MastodonPost, error) {
-	var verifyResp struct {
-		ID string `json:"id"`
-	}
-	if err := httpGet("/accounts/verify_credentials", &verifyResp); err != nil {
+func fetchRecentMastodonPosts(ctx context.Context, devices []SwitchBotDevice) ([]MastodonPost, error) {
+	accountID, err := fetchMastodonAccountID(ctx)
+	if err != nil {
 		return nil, err
 	}
+	return collectRecentPosts(devices, func(maxID string, limit int) ([]MastodonPost, error) {
+		query := url.Values{
+			"limit":           {strconv.Itoa(limit)},
+			"exclude_replies": {"true"},
+			"exclude_reblogs": {"true"},
+		}
+		if maxID != "" {
+			query.Set("max_id", maxID)
+		}
+		endpoint := fmt.Sprintf("/accounts/%s/statuses?%s", accountID, query.Encode())
+		var batch []MastodonPost
+		if maxID == "" {
+			err = httpGetConditional(ctx, endpoint, "mastodon_recent_posts", &batch)
+		} else {
+			err = httpGet(ctx, endpoint, &batch)
+		}
+		return batch, err
+	})
+}
+
+// collectRecentPosts calls fetchPage with "" for the newest posts.
+func collectRecentPosts(devices []SwitchBotDevice, fetchPage func(olderThan string, limit int) ([]MastodonPost, error)) ([]MastodonPost, error) {
+	needed := make(map[string]int, len(devices))
+	for _, d := range devices {
+		needed[d.DeviceName] = batteryCheckPostCount
+	}
+	cutoff := time.Now().Add(-time.Duration(config.HistoryHours) * time.Hour)
+	pageSize := min(max(batteryCheckPostCount, 1), maxPostPageSize)
 
 	var posts []MastodonPost
-	if err := httpGet(fmt.Sprintf("/accounts/%s/statuses?limit=%d", verifyResp.ID, batteryCheckPostCount), &posts); err != nil {
-		return nil, err
+	olderThan := ""
+	for page := 0; page < maxPostPages && len(needed) > 0; page++ {
+		batch, err := fetchPage(olderThan, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, post := range batch {
+			if post.CreatedAt.Before(cutoff) {
+				return posts, nil
+			}
+			posts = append(posts, post)
+			text := stripHTMLTags(post.Content)
+			for name := range needed {
+				if !strings.Contains(text, makeDeviceHeader(name)) {
+					continue
+				}
+				if needed[name]--; needed[name] <= 0 {
+					delete(needed, name)
+				}
+			}
+		}
+		olderThan = batch[len(batch)-1].ID
 	}
 	return posts, nil
 }
 
-func httpGet(endpoint string, result any) error {
+func fetchMastodonAccountID(ctx context.Context) (string, error) {
+	if config.MastodonAccountID != "" {
+		return config.MastodonAccountID, nil
+	}
+
+	fingerprint := credentialFingerprint(config.MastodonURL, config.MastodonToken)
+	var cached mastodonAccountCache
+	if ok, err := stateStore.Get(ctx, "mastodon_account", &cached); err != nil {
+		log.Printf("Failed to read cached Mastodon account: %v", err)
+	} else if ok && cached.Fingerprint == fingerprint && cached.ID != "" {
+		return cached.ID, nil
+	}
+
+	var verifyResp struct {
+		ID string `json:"id"`
+	}
+	if err := httpGet(ctx, "/accounts/verify_credentials", &verifyResp); err != nil {
+		return "", err
+	}
+	if err := stateStore.Put(ctx, "mastodon_account", mastodonAccountCache{Fingerprint: fingerprint, ID: verifyResp.ID}); err != nil {
+		log.Printf("Failed to cache Mastodon account: %v", err)
+	}
+	return verifyResp.ID, nil
+}
+
+func credentialFingerprint(url, token string) string {
+	sum := sha256.Sum256([]byte(url + "\x00" + token))
+	return hex.EncodeToString(sum[:8])
+}
+
+func httpGet(ctx context.Context, endpoint string, result any) error {
 	url := config.MastodonURL + endpoint
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Authorization", "Bearer "+config.MastodonToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := sharedHTTPClient().Do(req)
 	if err != nil {
 		return err
 	}
@@ -241,184 +658,425 @@ func httpGet(endpoint string, result any) error {
 	return json.NewDecoder(res.Body).Decode(result)
 }
 
-func isTargetDevice(deviceType string) bool {
-	_, ok := targetDeviceTypes[deviceType]
-	return ok
-}
+func httpGetConditional(ctx context.Context, endpoint, cacheKey string, result any) error {
+	url := config.MastodonURL + endpoint
+	var cached cachedResponse
+	hit, err := stateStore.Get(ctx, cacheKey, &cached)
+	if err != nil {
+		log.Printf("Failed to read cached response for %s: %v", endpoint, err)
+	}
+	hit = hit && cached.URL == url
 
-func generateStatusMessage(ctx context.Context, device SwitchBotDevice, posts []MastodonPost) (string, error) {
-	status, err := fetchDeviceStatus(device)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return "", err
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.MastodonToken)
+	if hit && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if hit && cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if hit && res.StatusCode == http.StatusNotModified {
+		return json.Unmarshal(cached.Body, result)
+	}
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("GET %s failed: %s", url, body)
 	}
 
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return err
+	}
+	etag, lastModified := res.Header.Get("ETag"), res.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return nil
+	}
+	entry := cachedResponse{URL: url, ETag: etag, LastModified: lastModified, Body: body}
+	if err := stateStore.Put(ctx, cacheKey, entry); err != nil {
+		log.Printf("Failed to cache response for %s: %v", endpoint, err)
+	}
+	return nil
+}
+
+func isTargetDevice(device SwitchBotDevice) bool {
+	return slices.Contains(config.TargetDeviceTypes, device.DeviceType) && allowedDevice(device)
+}
+
+func allowedDevice(device SwitchBotDevice) bool {
+	listed := func(list []string) bool {
+		return slices.Contains(list, device.DeviceID) || slices.Contains(list, device.DeviceName)
+	}
+	if len(config.DeviceAllowlist) > 0 && !listed(config.DeviceAllowlist) {
+		return false
+	}
+	return !listed(config.DeviceDenylist)
+}
+
+func latestReadings(readings []deviceReading) map[string]SwitchBotDeviceStatus {
+	latest := make(map[string]SwitchBotDeviceStatus, len(readings)*2)
+	for _, r := range readings {
+		latest[r.Device.DeviceID] = r.Status
+		latest[r.Device.DeviceName] = r.Status
+	}
+	return latest
+}
+
+func generateStatusMessage(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus, latest map[string]SwitchBotDeviceStatus) deviceSection {
+	section := deviceSection{Device: device}
 	if err := PutMetric(ctx, device, status); err != nil {
 		log.Printf("Failed to send metrics to CloudWatch: %v", err)
 	}
 
+	history, err := loadHistory(ctx, device.DeviceID)
+	if err != nil {
+		log.Printf("Failed to load history for %s: %v", device.DeviceName, err)
+	} else if err := recordReading(ctx, device.DeviceID, history, status); err != nil {
+		log.Printf("Failed to record reading for %s: %v", device.DeviceName, err)
+	}
+
 	var b strings.Builder
 	b.WriteString(makeDeviceHeader(device.DeviceName))
 	if status.Battery != nil {
-		emoji, err := batteryStatusEmoji(device, posts, status)
-		if err != nil {
-			return "", err
-		}
-		fmt.Fprintf(&b, " (%s%d%%)", emoji, *status.Battery)
+		emoji := batteryStatusEmoji(status, history)
+		fmt.Fprintf(&b, " (%s%s%%)", emoji, formatInt(*status.Battery))
 	}
 	b.WriteByte('\n')
+	prev := previousReading(history)
 	if status.Temperature != nil {
-		fmt.Fprintf(&b, "温度: %.1f度\n", *status.Temperature)
+		t, unit := displayTemperature(*status.Temperature)
+		var prevT *float64
+		if prev.Temperature != nil {
+			p, _ := displayTemperature(*prev.Temperature)
+			prevT = &p
+		}
+		fmt.Fprintf(&b, "%s: %s%s%s\n", tr("温度"), formatNumber(t, 1), unit, trend(t, prevT, 1))
 	}
 	if status.Humidity != nil {
-		fmt.Fprintf(&b, "湿度: %.1f%%\n", *status.Humidity)
+		fmt.Fprintf(&b, "%s: %s%%%s\n", tr("湿度"), formatNumber(*status.Humidity, 1), trend(*status.Humidity, prev.Humidity, 1))
+	}
+	if dew, abs, heat, ok := comfortValues(status); ok && config.ComfortMetrics {
+		d, unit := displayTemperature(dew)
+		w, _ := displayTemperature(heat)
+		fmt.Fprintf(&b, "%s: %s%s / %s: %sg/m³ / WBGT: %s%s\n",
+			tr("露点"), formatNumber(d, 1), unit, tr("絶対湿度"), formatNumber(abs, 1), formatNumber(w, 1), unit)
+	}
+	if status.Temperature != nil && status.Humidity != nil && config.DiscomfortIndex {
+		di := discomfortIndex(*status.Temperature, *status.Humidity)
+		fmt.Fprintf(&b, "%s: %s %s\n", tr("不快指数"), formatNumber(di, 0), discomfortEmoji(di))
+	}
+	for _, v := range derivedValues(status, prev) {
+		if line := derivedLine(v); line != "" {
+			b.WriteString(line + "\n")
+		}
 	}
 	if status.CO2 != nil {
-		var icon string
-		switch {
-		case *status.CO2 >= 1500:
-			icon = "🔥"
-		case *status.CO2 >= 1000:
-			icon = "💨"
-		default:
-			icon = "🌳"
+		fmt.Fprintf(&b, "CO2: %sppm%s %s\n", formatInt(*status.CO2), intTrend(*status.CO2, prev.CO2), co2Icon(*status.CO2))
+	}
+	if status.LightLevel != nil {
+		fmt.Fprintf(&b, "%s: %s%s\n", tr("照度"), formatInt(*status.LightLevel), intTrend(*status.LightLevel, prev.LightLevel))
+	}
+	if status.Power != nil {
+		fmt.Fprintf(&b, "%s: %sW%s\n", tr("電力"), formatNumber(*status.Power, 1), trend(*status.Power, prev.Power, 1))
+	}
+	if status.Voltage != nil {
+		fmt.Fprintf(&b, "%s: %sV\n", tr("電圧"), formatNumber(*status.Voltage, 1))
+	}
+	if status.Current != nil {
+		fmt.Fprintf(&b, "%s: %sA\n", tr("電流"), formatNumber(*status.Current, 2))
+	}
+	for _, line := range stateLines(status) {
+		b.WriteString(line + "\n")
+	}
+	if line := batteryForecastLine(ctx, device, status); line != "" {
+		b.WriteString(line + "\n")
+	}
+	if line := qualityLine(status); line != "" {
+		b.WriteString(line + "\n")
+	}
+	if status.SourceConflict != "" {
+		fmt.Fprintf(&b, tr("📡 BLEとクラウドの測定値が一致しません（%s）")+"\n", status.SourceConflict)
+		section.Notable = true
+	}
+	var alerts []string
+	for _, alert := range evaluateDeviceAlerts(ctx, device, status, history, latest) {
+		fmt.Fprintf(&b, "⚠️ %s\n", alert.text())
+		alerts = append(alerts, alert.text())
+		section.Alerting = true
+		section.Notable = true
+	}
+	for _, line := range runSceneBindings(ctx, device, status) {
+		b.WriteString(line + "\n")
+		section.Notable = true
+	}
+	section.Message = b.String()
+	section.Message = formatSection(ctx, section, status, alerts)
+	return section
+}
+
+func fetchReadings(ctx context.Context, devices []SwitchBotDevice) ([]deviceReading, []fetchFailure) {
+	var targets []SwitchBotDevice
+	for _, device := range devices {
+		if isTargetDevice(device) {
+			targets = append(targets, device)
+		}
+	}
+
+	results := make([]*deviceReading, len(targets))
+	errs := make([]error, len(targets))
+	var g errgroup.Group
+	g.SetLimit(max(config.FetchConcurrency, 1))
+	for i, device := range targets {
+		g.Go(func() error {
+			status, err := fetchDeviceStatus(ctx, device)
+			if err == nil {
+				status = reconcileBLE(device, status, time.Now())
+			} else {
+				if status, ok := bleReading(device.DeviceID, time.Now()); ok {
+					log.Printf("Failed to fetch status for %s, using its BLE reading: %v", device.DeviceName, err)
+					addQuality(&status, qualityStale)
+					results[i] = &deviceReading{Device: device, Status: status}
+					return nil
+				}
+				if reason, ok := offlineReason(err); ok {
+					log.Printf("%s is offline: %v", device.DeviceName, err)
+					results[i] = &deviceReading{Device: device, Status: SwitchBotDeviceStatus{ReadAt: time.Now(), Offline: reason}}
+					return nil
+				}
+				log.Printf("Failed to fetch status for %s: %v", device.DeviceName, err)
+				recordOps(func(s *opsStats) { s.Errors++ })
+				errs[i] = err
+				return nil
+			}
+			results[i] = &deviceReading{Device: device, Status: status}
+			return nil
+		})
+	}
+	g.Wait()
+
+	var readings []deviceReading
+	var failures []fetchFailure
+	for i, r := range results {
+		if r != nil {
+			if r.Status.Offline == "" {
+				r.Status = calibrateStatus(r.Device, r.Status)
+			}
+			readings = append(readings, *r)
+		} else if errs[i] != nil {
+			failures = append(failures, fetchFailure{Device: targets[i], Err: errs[i]})
 		}
-		fmt.Fprintf(&b, "CO2: %dppm %s\n", *status.CO2, icon)
 	}
-	return b.String(), nil
+	return readings, failures
 }
 
-func fetchDeviceStatus(device SwitchBotDevice) (SwitchBotDeviceStatus, error) {
+func fetchDeviceStatus(ctx context.Context, device SwitchBotDevice) (SwitchBotDeviceStatus, error) {
 	url := fmt.Sprintf("https://api.switch-bot.com/v1.1/devices/%s/status", device.DeviceID)
 	var resp SwitchBotResponse[SwitchBotDeviceStatus]
-	if err := requestWithBackoff(url, generateSwitchBotHeaders(), &resp); err != nil {
+	if err := requestWithBackoff(ctx, url, &resp); err != nil {
 		return SwitchBotDeviceStatus{}, err
 	}
+	resp.Body.ReadAt = time.Now()
+	if resp.Retries > 0 {
+		addQuality(&resp.Body, qualityRetried)
+	}
 	return resp.Body, nil
 }
 
-func PutMetric(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus) error {
-	type MetricLog struct {
-		Type        string   `json:"type"`
-		DeviceID    string   `json:"deviceId"`
-		DeviceName  string   `json:"deviceName"`
-		Temperature *float64 `json:"temperature,omitempty"`
-		Humidity    *float64 `json:"humidity,omitempty"`
-		CO2         *int     `json:"co2,omitempty"`
-	}
+func makeDeviceHeader(deviceName string) string {
+	return fmt.Sprintf("# %s", deviceName)
+}
 
-	metric := MetricLog{
-		Type:        "Metric",
-		DeviceID:    device.DeviceID,
-		DeviceName:  device.DeviceName,
-		Temperature: status.Temperature,
-		Humidity:    status.Humidity,
-		CO2:         status.CO2,
-	}
+func stripHTMLTags(input string) string {
+	return htmlTagRe.ReplaceAllString(input, "")
+}
 
-	b, err := json.Marshal(metric)
-	if err != nil {
-		return fmt.Errorf("failed to marshal metric log: %w", err)
+func batteryStatusEmoji(status SwitchBotDeviceStatus, history []SwitchBotDeviceStatus) string {
+	emoji := batteryTierEmoji(*status.Battery)
+	if len(history) >= batteryCheckPostCount && isRepeatedReading(status, history[len(history)-batteryCheckPostCount:]) {
+		emoji = config.BatteryStaleEmoji + emoji
 	}
-
-	fmt.Println(string(b))
-	return nil
+	return emoji
 }
 
-func makeDeviceHeader(deviceName string) string {
-	return fmt.Sprintf("# %s", deviceName)
+type BatteryTier struct {
+	Min   int
+	Emoji string
 }
 
-func batteryStatusEmoji(device SwitchBotDevice, posts []MastodonPost, status SwitchBotDeviceStatus) (string, error) {
-	previousMessages, err := extractRecentMessagesForDevice(device.DeviceName, posts)
-	if err != nil {
-		return "", fmt.Errorf("extractRecentMessagesForDevice failed: %w", err)
-	}
-	if isRepeated(status, previousMessages) {
-		return "⚠️", nil
+func co2Icon(ppm int) string {
+	switch {
+	case ppm >= 1500:
+		return "🔥"
+	case ppm >= 1000:
+		return "💨"
 	}
-	return "🔋", nil
+	return "🌳"
 }
 
-func extractRecentMessagesForDevice(deviceName string, posts []MastodonPost) ([]string, error) {
-	var messages []string
-	for _, post := range posts {
-		text := stripHTMLTags(post.Content)
-		if idx := strings.Index(text, makeDeviceHeader(deviceName)); idx != -1 {
-			messages = append(messages, text[idx:])
+func batteryTierEmoji(level int) string {
+	for _, tier := range config.BatteryTiers {
+		if level >= tier.Min {
+			return tier.Emoji
 		}
 	}
-	return messages, nil
+	return ""
 }
 
-func stripHTMLTags(input string) string {
-	return htmlTagRe.ReplaceAllString(input, "")
+var defaultBatteryTiers = []BatteryTier{{60, "🔋"}, {20, "🪫"}, {0, "⚠️"}}
+
+func validateBatteryTiers(tiers []BatteryTier) error {
+	for i := 1; i < len(tiers); i++ {
+		if tiers[i].Min >= tiers[i-1].Min {
+			return fmt.Errorf("BatteryTiers must be sorted by Min in descending order")
+		}
+	}
+	return nil
 }
 
-func isRepeated(current SwitchBotDeviceStatus, previousMessages []string) bool {
-	for _, msg := range previousMessages {
-		temp := extractFloatValue(msg, `温度: ([\d.]+)度`)
-		hum := extractFloatValue(msg, `湿度: ([\d.]+)%`)
-		co2 := extractIntValue(msg, `CO2: (\d+)ppm`)
-		if !ptrEquals(temp, current.Temperature) ||
-			!ptrEquals(hum, current.Humidity) ||
-			!ptrEquals(co2, current.CO2) {
+func isRepeatedReading(current SwitchBotDeviceStatus, previous []SwitchBotDeviceStatus) bool {
+	for _, p := range previous {
+		if !ptrEquals(p.Temperature, current.Temperature) ||
+			!ptrEquals(p.Humidity, current.Humidity) ||
+			!ptrEquals(p.CO2, current.CO2) ||
+			!ptrEquals(p.LightLevel, current.LightLevel) ||
+			!ptrEquals(p.LockState, current.LockState) ||
+			!ptrEquals(p.DoorState, current.DoorState) ||
+			!ptrEquals(p.OpenState, current.OpenState) ||
+			!ptrEquals(p.Moving, current.Moving) {
 			return false
 		}
 	}
 	return true
 }
 
-func extractFloatValue(text, pattern string) *float64 {
-	matches := regexp.MustCompile(pattern).FindStringSubmatch(text)
-	if len(matches) < 2 {
-		return nil
+func ptrEquals[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
-	v, err := strconv.ParseFloat(matches[1], 64)
-	if err != nil {
-		return nil
+	return *a == *b
+}
+
+func postToMastodon(ctx context.Context, message string) error {
+	return postToMastodonWithVisibility(ctx, message, config.PostVisibility, config.SpoilerText)
+}
+
+func postToMastodonWithVisibility(ctx context.Context, message, visibility, spoiler string) error {
+	_, err := postMastodonStatus(ctx, mastodonStatus(message, visibility, spoiler))
+	return err
+}
+
+func mastodonStatus(message, visibility, spoiler string) map[string]any {
+	payload := map[string]any{
+		"status":     withHashtags(message, visibility),
+		"visibility": visibility,
+	}
+	if spoiler != "" {
+		payload["spoiler_text"] = spoiler
 	}
-	return &v
+	return payload
 }
 
-func extractIntValue(text, pattern string) *int {
-	matches := regexp.MustCompile(pattern).FindStringSubmatch(text)
-	if len(matches) < 2 {
-		return nil
+func withHashtags(message, visibility string) string {
+	if len(config.Hashtags) == 0 || visibility == "direct" {
+		return message
 	}
-	v, err := strconv.Atoi(matches[1])
-	if err != nil {
-		return nil
+	tags := make([]string, len(config.Hashtags))
+	for i, tag := range config.Hashtags {
+		tags[i] = "#" + strings.TrimPrefix(tag, "#")
 	}
-	return &v
+	return message + "\n\n" + strings.Join(tags, " ")
 }
 
-func ptrEquals[T comparable](a, b *T) bool {
-	if a == nil || b == nil {
-		return a == b
+func spoilerFor(alerting bool) string {
+	if alerting && config.AlertSpoilerText != "" {
+		return config.AlertSpoilerText
 	}
-	return *a == *b
+	return config.SpoilerText
 }
 
-func postToMastodon(message string) error {
-	url := config.MastodonURL + "/statuses"
-	payload := map[string]string{
-		"status":     message,
-		"visibility": "unlisted",
+func validateVisibility(name, visibility string) error {
+	switch visibility {
+	case "public", "unlisted", "private", "direct":
+		return nil
+	}
+	return fmt.Errorf("invalid %s %q", name, visibility)
+}
+
+// postMastodonStatus repeats leading mentions so each part of a direct message keeps its recipients.
+func postMastodonStatus(ctx context.Context, payload map[string]any) (string, error) {
+	message, _ := payload["status"].(string)
+	limit := defaultMastodonCharLimit
+	if utf8.RuneCountInString(message) > limit {
+		limit = mastodonCharLimit(ctx)
+	}
+	parts := splitStatus(message, leadingMentions(message), limit)
+	if len(parts) > 1 {
+		log.Printf("Splitting a %d-character post into %d statuses", utf8.RuneCountInString(message), len(parts))
 	}
+	var first string
+	for i, part := range parts {
+		payload["status"] = part
+		id, err := createMastodonStatus(ctx, payload)
+		if err != nil {
+			if i > 0 {
+				return first, fmt.Errorf("part %d of %d: %w", i+1, len(parts), err)
+			}
+			return "", err
+		}
+		if i == 0 {
+			first = id
+		}
+		delete(payload, "media_ids")
+		payload["in_reply_to_id"] = id
+	}
+	return first, nil
+}
+
+var errMastodonNotFound = errors.New("mastodon status not found")
+
+func createMastodonStatus(ctx context.Context, payload map[string]any) (string, error) {
+	return mastodonStatusRequest(ctx, "POST", "/statuses", payload)
+}
+
+func mastodonStatusRequest(ctx context.Context, method, endpoint string, payload map[string]any) (string, error) {
+	url := config.MastodonURL + endpoint
+	message := payload["status"]
 	buf, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(buf))
+	req, _ := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(buf))
 	req.Header.Set("Authorization", "Bearer "+config.MastodonToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := sharedHTTPClient().Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		body, _ := io.ReadAll(res.Body)
+		return "", fmt.Errorf("%w: %s", errMastodonNotFound, body)
+	}
 	if res.StatusCode >= 300 {
 		body, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("mastodon API error: %s", body)
+		return "", fmt.Errorf("mastodon API error: %s", body)
 	}
-	log.Println("Post successful:", message)
-	return nil
+	var status struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&status); err != nil {
+		return "", err
+	}
+	if message != nil {
+		log.Println("Post successful:", message)
+	}
+	return status.ID, nil
 }
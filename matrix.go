@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type matrixNotifier struct {
+	homeserver string
+	token      string
+	room       string
+}
+
+func (matrixNotifier) Name() string { return "matrix" }
+
+func (n matrixNotifier) Notify(ctx context.Context, message string) error {
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(n.homeserver, "/"), url.PathEscape(n.room), uuid.New().String())
+	buf, _ := json.Marshal(map[string]string{
+		"msgtype": "m.text",
+		"body":    message,
+	})
+	req, err := http.NewRequestWithContext(ctx, "PUT", endpoint, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+n.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("matrix API error: %s", body)
+	}
+	log.Println("Matrix post successful:", n.room)
+	return nil
+}
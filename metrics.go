@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+const (
+	metricBufferKey    = "metric_buffer"
+	maxBufferedMetrics = 5000
+	// Under DynamoDB's 400 KB item limit.
+	maxBufferedMetricBytes = 350 << 10
+	putMetricDataBatchSize = 1000
+)
+
+var (
+	cloudWatchClient     *cloudwatch.Client
+	cloudWatchClientOnce sync.Once
+	metricBufferMu       sync.Mutex
+)
+
+type metricPoint struct {
+	DeviceID   string    `json:"deviceId"`
+	DeviceName string    `json:"deviceName,omitempty"`
+	Name       string    `json:"name"`
+	Unit       string    `json:"unit"`
+	Value      float64   `json:"value"`
+	Timestamp  time.Time `json:"timestamp"`
+	Source     string    `json:"source,omitempty"`
+}
+
+func PutMetric(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus) error {
+	derived := derivedValues(status, readingBefore(ctx, device, status))
+	var timestreamErr error
+	if config.TimestreamDatabase != "" {
+		timestreamErr = putTimestreamRecord(ctx, device, metricPoints(device, status, derived))
+	}
+	switch config.MetricsBackend {
+	case "cloudwatch":
+		return errors.Join(putCloudWatchMetrics(ctx, metricPoints(device, status, derived)), timestreamErr)
+	case "emf":
+		points := metricPoints(device, status, derived)
+		return errors.Join(putEMFMetrics(device, status, points), putDestinationMetrics(ctx, points), timestreamErr)
+	case "plugin":
+		return errors.Join(putPluginMetrics(ctx, metricPoints(device, status, derived)), timestreamErr)
+	case "timestream":
+		return timestreamErr
+	case "remote_write":
+		return errors.Join(putRemoteWrite(ctx, device, status, metricPoints(device, status, derived)), timestreamErr)
+	case "pushgateway":
+		return errors.Join(putPushgateway(ctx, device, status, metricPoints(device, status, derived)), timestreamErr)
+	}
+
+	type MetricLog struct {
+		Type         string             `json:"type"`
+		DeviceID     string             `json:"deviceId"`
+		DeviceName   string             `json:"deviceName"`
+		Temperature  *float64           `json:"temperature,omitempty"`
+		TemperatureF *float64           `json:"temperatureF,omitempty"`
+		Humidity     *float64           `json:"humidity,omitempty"`
+		CO2          *int               `json:"co2,omitempty"`
+		LightLevel   *int               `json:"lightLevel,omitempty"`
+		PowerWatts   *float64           `json:"powerWatts,omitempty"`
+		Voltage      *float64           `json:"voltage,omitempty"`
+		DewPoint     *float64           `json:"dewPoint,omitempty"`
+		AbsHumidity  *float64           `json:"absoluteHumidity,omitempty"`
+		WBGT         *float64           `json:"wbgt,omitempty"`
+		Derived      map[string]float64 `json:"derived,omitempty"`
+		Timestamp    time.Time          `json:"timestamp"`
+		Source       string             `json:"source,omitempty"`
+		Quality      []string           `json:"quality,omitempty"`
+		Offline      string             `json:"offline,omitempty"`
+	}
+
+	metric := MetricLog{
+		Type:        "Metric",
+		DeviceID:    device.DeviceID,
+		DeviceName:  device.DeviceName,
+		Temperature: status.Temperature,
+		Humidity:    status.Humidity,
+		CO2:         status.CO2,
+		LightLevel:  status.LightLevel,
+		PowerWatts:  status.Power,
+		Voltage:     status.Voltage,
+		Timestamp:   status.ReadAt,
+		Source:      status.Source,
+		Quality:     status.Quality,
+		Offline:     status.Offline,
+	}
+	if f, ok := fahrenheitMetric(status); ok {
+		metric.TemperatureF = &f
+	}
+	if dew, abs, heat, ok := comfortMetrics(status); ok {
+		metric.DewPoint, metric.AbsHumidity, metric.WBGT = &dew, &abs, &heat
+	}
+	if len(derived) > 0 {
+		metric.Derived = make(map[string]float64, len(derived))
+		for _, v := range derived {
+			metric.Derived[v.Metric.Name] = v.Value
+		}
+	}
+
+	b, err := json.Marshal(metric)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metric log: %w", err)
+	}
+
+	fmt.Println(string(b))
+	return timestreamErr
+}
+
+// metricDefinition uses None for units CloudWatch lacks, such as temperature and ppm.
+type metricDefinition struct {
+	Name  string
+	Unit  types.StandardUnit
+	Value func(SwitchBotDeviceStatus) (float64, bool)
+}
+
+var metricDefinitions = []metricDefinition{
+	{"Temperature", types.StandardUnitNone, func(s SwitchBotDeviceStatus) (float64, bool) { return floatReading(s.Temperature) }},
+	{"TemperatureF", types.StandardUnitNone, fahrenheitMetric},
+	{"Humidity", types.StandardUnitPercent, func(s SwitchBotDeviceStatus) (float64, bool) { return floatReading(s.Humidity) }},
+	{"DewPoint", types.StandardUnitNone, func(s SwitchBotDeviceStatus) (float64, bool) {
+		dew, _, _, ok := comfortMetrics(s)
+		return dew, ok
+	}},
+	{"AbsoluteHumidity", types.StandardUnitNone, func(s SwitchBotDeviceStatus) (float64, bool) {
+		_, abs, _, ok := comfortMetrics(s)
+		return abs, ok
+	}},
+	{"WBGT", types.StandardUnitNone, func(s SwitchBotDeviceStatus) (float64, bool) {
+		_, _, heat, ok := comfortMetrics(s)
+		return heat, ok
+	}},
+	{"CO2", types.StandardUnitNone, func(s SwitchBotDeviceStatus) (float64, bool) { return floatReading(intReading(s.CO2)) }},
+	{"LightLevel", types.StandardUnitNone, func(s SwitchBotDeviceStatus) (float64, bool) { return floatReading(intReading(s.LightLevel)) }},
+	{"PowerWatts", types.StandardUnitNone, func(s SwitchBotDeviceStatus) (float64, bool) { return floatReading(s.Power) }},
+	{"Voltage", types.StandardUnitNone, func(s SwitchBotDeviceStatus) (float64, bool) { return floatReading(s.Voltage) }},
+	{"DeviceOffline", types.StandardUnitCount, func(s SwitchBotDeviceStatus) (float64, bool) {
+		if s.Offline != "" {
+			return 1, true
+		}
+		return 0, true
+	}},
+}
+
+func comfortMetrics(status SwitchBotDeviceStatus) (dew, abs, heat float64, ok bool) {
+	if !config.ComfortMetrics {
+		return 0, 0, 0, false
+	}
+	return comfortValues(status)
+}
+
+func metricPoints(device SwitchBotDevice, status SwitchBotDeviceStatus, derived []derivedValue) []metricPoint {
+	var points []metricPoint
+	add := func(name string, unit types.StandardUnit, value float64) {
+		points = append(points, metricPoint{
+			DeviceID:   device.DeviceID,
+			DeviceName: device.DeviceName,
+			Name:       name,
+			Unit:       string(unit),
+			Value:      value,
+			Timestamp:  status.ReadAt,
+			Source:     status.Source,
+		})
+	}
+	for _, d := range metricDefinitions {
+		if v, ok := d.Value(status); ok {
+			add(d.Name, d.Unit, v)
+		}
+	}
+	for _, v := range derived {
+		add(v.Metric.Name, types.StandardUnitNone, v.Value)
+	}
+	return points
+}
+
+func fahrenheitMetric(status SwitchBotDeviceStatus) (float64, bool) {
+	if !config.TemperatureUnitMetric || !usesFahrenheit() || status.Temperature == nil {
+		return 0, false
+	}
+	f, _ := displayTemperature(*status.Temperature)
+	return f, true
+}
+
+func putEMFMetrics(device SwitchBotDevice, status SwitchBotDeviceStatus, points []metricPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	type emfMetric struct {
+		Name string `json:"Name"`
+		Unit string `json:"Unit"`
+	}
+	metrics := make([]emfMetric, 0, len(points))
+	record := map[string]any{}
+	var dimensions []string
+	for _, d := range metricDimensions(device.DeviceID, device.DeviceName) {
+		record[*d.Name] = *d.Value
+		dimensions = append(dimensions, *d.Name)
+	}
+	for _, p := range points {
+		metrics = append(metrics, emfMetric{Name: p.Name, Unit: p.Unit})
+		record[p.Name] = p.Value
+	}
+	if status.Source != "" {
+		record["Source"] = status.Source
+	}
+	if len(status.Quality) > 0 {
+		record["Quality"] = status.Quality
+	}
+	record["_aws"] = map[string]any{
+		"Timestamp": status.ReadAt.UnixMilli(),
+		"CloudWatchMetrics": []map[string]any{{
+			"Namespace":  config.MetricsNamespace,
+			"Dimensions": [][]string{dimensions},
+			"Metrics":    metrics,
+		}},
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal EMF log: %w", err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func putCloudWatchMetrics(ctx context.Context, points []metricPoint) error {
+	return errors.Join(putBufferedMetrics(ctx, nil, points), putDestinationMetrics(ctx, points))
+}
+
+func putDestinationMetrics(ctx context.Context, points []metricPoint) error {
+	var errs []error
+	for _, d := range config.MetricsDestinations {
+		if err := putBufferedMetrics(ctx, &d, points); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func putBufferedMetrics(ctx context.Context, dest *MetricsDestination, points []metricPoint) error {
+	metricBufferMu.Lock()
+	defer metricBufferMu.Unlock()
+
+	key := dest.bufferKey()
+	var buffered []metricPoint
+	if _, err := stateStore.Get(ctx, key, &buffered); err != nil {
+		log.Printf("Failed to read buffered metrics: %v", err)
+	}
+	pending := append(dropExpiredMetrics(buffered, time.Now()), points...)
+	if skipDestination(ctx, "cloudwatch") {
+		if err := stateStore.Put(ctx, key, capBufferedMetrics(pending)); err != nil {
+			return fmt.Errorf("buffering metrics failed: %w", err)
+		}
+		return nil
+	}
+
+	attemptCtx, cancel := destinationTimeout(ctx, "cloudwatch")
+	defer cancel()
+	sent, err := sendMetricData(attemptCtx, dest, pending)
+	if err != nil {
+		remaining := capBufferedMetrics(pending[sent:])
+		if perr := stateStore.Put(ctx, key, remaining); perr != nil {
+			return fmt.Errorf("PutMetricData failed: %w (buffering also failed: %v)", err, perr)
+		}
+		return fmt.Errorf("PutMetricData failed, buffered %d datapoints: %w", len(remaining), err)
+	}
+	if len(buffered) > 0 {
+		if err := stateStore.Put(ctx, key, []metricPoint{}); err != nil {
+			return fmt.Errorf("clearing metric buffer failed: %w", err)
+		}
+		log.Printf("Flushed %d buffered datapoints to CloudWatch", len(buffered))
+	}
+	return nil
+}
+
+func capBufferedMetrics(points []metricPoint) []metricPoint {
+	if len(points) > maxBufferedMetrics {
+		points = points[len(points)-maxBufferedMetrics:]
+	}
+	size := 2 // the brackets of the JSON array
+	for i := len(points) - 1; i >= 0; i-- {
+		raw, err := json.Marshal(points[i])
+		if err != nil {
+			continue
+		}
+		size += len(raw) + 1
+		if size > maxBufferedMetricBytes {
+			log.Printf("Metric buffer is full; dropping the %d oldest datapoints", i+1)
+			return points[i+1:]
+		}
+	}
+	return points
+}
+
+func dropExpiredMetrics(points []metricPoint, now time.Time) []metricPoint {
+	kept := points[:0]
+	for _, p := range points {
+		if now.Sub(p.Timestamp) < time.Duration(config.MetricBufferDays)*24*time.Hour {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func sendMetricData(ctx context.Context, dest *MetricsDestination, points []metricPoint) (int, error) {
+	client, err := dest.client(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for sent < len(points) {
+		end := min(sent+putMetricDataBatchSize, len(points))
+		data := make([]types.MetricDatum, 0, end-sent)
+		for _, p := range points[sent:end] {
+			data = append(data, types.MetricDatum{
+				MetricName: aws.String(p.Name),
+				Unit:       types.StandardUnit(p.Unit),
+				Value:      aws.Float64(p.Value),
+				Timestamp:  aws.Time(p.Timestamp),
+				Dimensions: metricDimensions(p.DeviceID, p.DeviceName),
+			})
+		}
+		if _, err := client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(config.MetricsNamespace),
+			MetricData: data,
+		}); err != nil {
+			return sent, err
+		}
+		sent = end
+	}
+	return sent, nil
+}
+
+func metricDimensions(deviceID, deviceName string) []types.Dimension {
+	dims := []types.Dimension{{Name: aws.String("DeviceId"), Value: aws.String(deviceID)}}
+	if deviceName != "" {
+		dims = append(dims, types.Dimension{Name: aws.String("DeviceName"), Value: aws.String(deviceName)})
+		if room := config.Rooms[deviceName]; room != "" {
+			dims = append(dims, types.Dimension{Name: aws.String("Room"), Value: aws.String(room)})
+		}
+	}
+	return dims
+}
+
+func cloudWatchRetries(o *cloudwatch.Options) {
+	if n := config.DestinationBudgets["cloudwatch"].MaxAttempts; n > 0 {
+		o.RetryMaxAttempts = n
+	}
+}
+
+func cloudWatch(ctx context.Context) (*cloudwatch.Client, error) {
+	cfg, err := loadRoleAWSConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config failed: %w", err)
+	}
+	cloudWatchClientOnce.Do(func() {
+		cloudWatchClient = cloudwatch.NewFromConfig(cfg, cloudWatchRetries)
+	})
+	return cloudWatchClient, nil
+}
+
+func putRunMetric(ctx context.Context, name string, value int, entry map[string]any, now time.Time) error {
+	switch config.MetricsBackend {
+	case "cloudwatch":
+		client, err := cloudWatch(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace: aws.String(config.MetricsNamespace),
+			MetricData: []types.MetricDatum{{
+				MetricName: aws.String(name),
+				Unit:       types.StandardUnitCount,
+				Value:      aws.Float64(float64(value)),
+				Timestamp:  aws.Time(now),
+			}},
+		})
+		return err
+	case "emf":
+		b, err := json.Marshal(map[string]any{
+			name: value,
+			"_aws": map[string]any{
+				"Timestamp": now.UnixMilli(),
+				"CloudWatchMetrics": []map[string]any{{
+					"Namespace":  config.MetricsNamespace,
+					"Dimensions": [][]string{{}},
+					"Metrics":    []map[string]string{{"Name": name, "Unit": string(types.StandardUnitCount)}},
+				}},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "log":
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCapBufferedMetrics(t *testing.T) {
+	points := func(n int, name string) []metricPoint {
+		out := make([]metricPoint, n)
+		for i := range out {
+			out[i] = metricPoint{DeviceID: "dev", Name: name, Value: float64(i), Timestamp: time.Unix(int64(i), 0).UTC()}
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		points []metricPoint
+	}{
+		{"under both caps", points(10, "Temperature")},
+		{"over the count cap", points(maxBufferedMetrics+10, "Temperature")},
+		{"over the byte cap", points(2000, strings.Repeat("x", 300))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := capBufferedMetrics(tt.points)
+			if len(got) > maxBufferedMetrics {
+				t.Errorf("kept %d datapoints, more than %d", len(got), maxBufferedMetrics)
+			}
+			raw, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(raw) > maxBufferedMetricBytes {
+				t.Errorf("encoded buffer is %d bytes, more than %d", len(raw), maxBufferedMetricBytes)
+			}
+			if len(got) == 0 || got[len(got)-1] != tt.points[len(tt.points)-1] {
+				t.Errorf("newest datapoint was dropped")
+			}
+			if size := len(tt.points); size <= 10 && len(got) != size {
+				t.Errorf("kept %d of %d datapoints", len(got), size)
+			}
+		})
+	}
+}
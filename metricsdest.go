@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+)
+
+type MetricsDestination struct {
+	Region  string
+	RoleARN string
+}
+
+var (
+	destinationClients   = map[MetricsDestination]*cloudwatch.Client{}
+	destinationClientsMu sync.Mutex
+)
+
+func validateMetricsDestinations(dests []MetricsDestination) error {
+	for i, d := range dests {
+		if d.Region == "" && d.RoleARN == "" {
+			return fmt.Errorf("MetricsDestinations[%d]: Region or RoleARN is required", i)
+		}
+	}
+	return nil
+}
+
+func (d MetricsDestination) String() string {
+	switch {
+	case d.RoleARN == "":
+		return d.Region
+	case d.Region == "":
+		return d.RoleARN
+	}
+	return d.Region + " " + d.RoleARN
+}
+
+func (d *MetricsDestination) bufferKey() string {
+	if d == nil {
+		return metricBufferKey
+	}
+	return metricBufferKey + ":" + d.String()
+}
+
+func (d *MetricsDestination) client(ctx context.Context) (*cloudwatch.Client, error) {
+	if d == nil {
+		return cloudWatch(ctx)
+	}
+	destinationClientsMu.Lock()
+	defer destinationClientsMu.Unlock()
+	if c, ok := destinationClients[*d]; ok {
+		return c, nil
+	}
+	// Its own RoleARN is assumed with the function's own credentials.
+	load := loadRoleAWSConfig
+	if d.RoleARN != "" {
+		load = loadAWSConfig
+	}
+	cfg, err := load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config failed: %w", err)
+	}
+	cfg = cfg.Copy()
+	if d.Region != "" {
+		cfg.Region = d.Region
+	}
+	if d.RoleARN != "" {
+		cfg.Credentials = assumeRole(cfg, d.RoleARN)
+	}
+	c := cloudwatch.NewFromConfig(cfg, cloudWatchRetries)
+	destinationClients[*d] = c
+	return c, nil
+}
+
+func metricBufferKeys() []string {
+	keys := []string{metricBufferKey}
+	for _, d := range config.MetricsDestinations {
+		keys = append(keys, d.bufferKey())
+	}
+	return keys
+}
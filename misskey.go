@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type misskeyNotifier struct{}
+
+func (misskeyNotifier) Name() string { return "misskey" }
+
+func (misskeyNotifier) Notify(_ context.Context, message string) error {
+	return postMisskeyNote(message, config.PostVisibility, config.SpoilerText)
+}
+
+func (misskeyNotifier) NotifyUrgent(_ context.Context, message string) error {
+	return postMisskeyNote(message, config.UrgentVisibility, spoilerFor(true))
+}
+
+func usesMisskey() bool {
+	for _, n := range notifiers {
+		if _, ok := n.(misskeyNotifier); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var misskeyVisibilities = map[string]string{
+	"public":   "public",
+	"unlisted": "home",
+	"private":  "followers",
+	"direct":   "specified",
+}
+
+func postMisskeyNote(message, visibility, cw string) error {
+	payload := map[string]any{
+		"text":       withHashtags(message, visibility),
+		"visibility": misskeyVisibilities[visibility],
+	}
+	if cw != "" {
+		payload["cw"] = cw
+	}
+	if err := misskeyRequest("notes/create", payload, nil); err != nil {
+		return err
+	}
+	log.Println("Note successful:", message)
+	return nil
+}
+
+func misskeyRequest(endpoint string, payload map[string]any, result any) error {
+	buf, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSuffix(config.MisskeyURL, "/") + "/api/" + endpoint
+	req, err := http.NewRequest("POST", url, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.MisskeyToken)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("misskey API error: %s", body)
+	}
+	if result == nil || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+func fetchMisskeyUserID(ctx context.Context) (string, error) {
+	fingerprint := credentialFingerprint(config.MisskeyURL, config.MisskeyToken)
+	var cached mastodonAccountCache
+	if ok, err := stateStore.Get(ctx, "misskey_account", &cached); err != nil {
+		log.Printf("Failed to read cached Misskey account: %v", err)
+	} else if ok && cached.Fingerprint == fingerprint && cached.ID != "" {
+		return cached.ID, nil
+	}
+
+	var me struct {
+		ID string `json:"id"`
+	}
+	if err := misskeyRequest("i", map[string]any{}, &me); err != nil {
+		return "", err
+	}
+	if err := stateStore.Put(ctx, "misskey_account", mastodonAccountCache{Fingerprint: fingerprint, ID: me.ID}); err != nil {
+		log.Printf("Failed to cache Misskey account: %v", err)
+	}
+	return me.ID, nil
+}
+
+func fetchRecentMisskeyNotes(ctx context.Context, devices []SwitchBotDevice) ([]MastodonPost, error) {
+	userID, err := fetchMisskeyUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return collectRecentPosts(devices, func(untilID string, limit int) ([]MastodonPost, error) {
+		payload := map[string]any{
+			"userId":      userID,
+			"limit":       limit,
+			"withReplies": false,
+			"withRenotes": false,
+		}
+		if untilID != "" {
+			payload["untilId"] = untilID
+		}
+		var notes []struct {
+			ID        string    `json:"id"`
+			Text      string    `json:"text"`
+			CreatedAt time.Time `json:"createdAt"`
+		}
+		if err := misskeyRequest("users/notes", payload, &notes); err != nil {
+			return nil, err
+		}
+		posts := make([]MastodonPost, len(notes))
+		for i, n := range notes {
+			posts[i] = MastodonPost{ID: n.ID, Content: n.Text, CreatedAt: n.CreatedAt}
+		}
+		return posts, nil
+	})
+}
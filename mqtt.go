@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const mqttTimeout = 10 * time.Second
+
+type haSensor struct {
+	Key         string
+	Name        string
+	Unit        string
+	DeviceClass string
+}
+
+var haSensors = []haSensor{
+	{"temperature", "Temperature", "°C", "temperature"},
+	{"humidity", "Humidity", "%", "humidity"},
+	{"co2", "CO2", "ppm", "carbon_dioxide"},
+	{"lightLevel", "Light level", "", ""},
+	{"battery", "Battery", "%", "battery"},
+	{"power", "Power", "W", "power"},
+	{"voltage", "Voltage", "V", "voltage"},
+	{"current", "Current", "A", "current"},
+}
+
+func mqttDiscoveryKey(deviceID string) string {
+	return "mqtt_discovery:" + deviceID
+}
+
+func mqttStateTopic(deviceID string) string {
+	return config.MQTTTopicPrefix + "/" + deviceID + "/state"
+}
+
+func mqttState(status SwitchBotDeviceStatus) map[string]any {
+	state := map[string]any{}
+	for key, v := range derivedVariables(status) {
+		state[key] = v
+	}
+	return state
+}
+
+func haDiscoveryConfig(device SwitchBotDevice, sensor haSensor) (string, []byte, error) {
+	node := "switchbot_" + strings.ToLower(device.DeviceID)
+	payload := map[string]any{
+		"name":           sensor.Name,
+		"unique_id":      node + "_" + strings.ToLower(sensor.Key),
+		"state_topic":    mqttStateTopic(device.DeviceID),
+		"value_template": fmt.Sprintf("{{ value_json.%s }}", sensor.Key),
+		"state_class":    "measurement",
+		"device": map[string]any{
+			"identifiers":  []string{node},
+			"name":         device.DeviceName,
+			"manufacturer": "SwitchBot",
+			"model":        device.DeviceType,
+		},
+	}
+	if sensor.Unit != "" {
+		payload["unit_of_measurement"] = sensor.Unit
+	}
+	if sensor.DeviceClass != "" {
+		payload["device_class"] = sensor.DeviceClass
+	}
+	body, err := json.Marshal(payload)
+	topic := fmt.Sprintf("%s/sensor/%s/%s/config", config.MQTTDiscoveryPrefix, node, strings.ToLower(sensor.Key))
+	return topic, body, err
+}
+
+func mqttTLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if config.MQTTCAFile != "" {
+		pem, err := os.ReadFile(config.MQTTCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", config.MQTTCAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if config.MQTTCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.MQTTCertFile, config.MQTTKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// mqttClientID is unique per process, since a broker drops a session when
+// another connects with the same ID.
+func mqttClientID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return strings.Trim(config.MQTTClientID+"-"+host, "-") + "-" + hex.EncodeToString(b)
+}
+
+func connectMQTT() (mqtt.Client, error) {
+	tlsConfig, err := mqttTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading MQTT TLS config failed: %w", err)
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(config.MQTTBrokerURL).
+		SetClientID(mqttClientID()).
+		SetUsername(config.MQTTUsername).
+		SetPassword(config.MQTTPassword).
+		SetTLSConfig(tlsConfig).
+		SetConnectTimeout(mqttTimeout).
+		SetAutoReconnect(false)
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(mqttTimeout) {
+		return nil, errors.New("MQTT connect timed out")
+	}
+	if err := token.Error(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func mqttPublish(client mqtt.Client, topic string, retained bool, payload []byte) error {
+	token := client.Publish(topic, 1, retained, payload)
+	if !token.WaitTimeout(mqttTimeout) {
+		return fmt.Errorf("publishing to %s timed out", topic)
+	}
+	return token.Error()
+}
+
+func publishMQTT(ctx context.Context, readings []deviceReading) {
+	if config.MQTTBrokerURL == "" || len(readings) == 0 {
+		return
+	}
+	client, err := connectMQTT()
+	if err != nil {
+		log.Printf("Failed to connect to the MQTT broker: %v", err)
+		return
+	}
+	defer client.Disconnect(250)
+
+	for _, r := range readings {
+		state := mqttState(r.Status)
+		if err := publishDiscovery(ctx, client, r.Device, state); err != nil {
+			log.Printf("Failed to publish MQTT discovery for %s: %v", r.Device.DeviceName, err)
+		}
+		body, err := json.Marshal(state)
+		if err != nil {
+			log.Printf("Failed to marshal MQTT state for %s: %v", r.Device.DeviceName, err)
+			continue
+		}
+		if err := mqttPublish(client, mqttStateTopic(r.Device.DeviceID), true, body); err != nil {
+			log.Printf("Failed to publish MQTT state for %s: %v", r.Device.DeviceName, err)
+		}
+	}
+}
+
+type mqttDiscoveryState struct {
+	Broker string   `json:"broker"`
+	Prefix string   `json:"prefix"`
+	Keys   []string `json:"keys"`
+}
+
+func publishDiscovery(ctx context.Context, client mqtt.Client, device SwitchBotDevice, state map[string]any) error {
+	current := mqttDiscoveryState{Broker: config.MQTTBrokerURL, Prefix: config.MQTTDiscoveryPrefix}
+	for _, sensor := range haSensors {
+		if _, ok := state[sensor.Key]; ok {
+			current.Keys = append(current.Keys, sensor.Key)
+		}
+	}
+	var published mqttDiscoveryState
+	if _, err := stateStore.Get(ctx, mqttDiscoveryKey(device.DeviceID), &published); err != nil {
+		log.Printf("Failed to load MQTT discovery state for %s: %v", device.DeviceName, err)
+	}
+	if published.Broker != current.Broker || published.Prefix != current.Prefix {
+		published.Keys = nil
+	} else if slices.Equal(current.Keys, published.Keys) {
+		return nil
+	}
+	for _, sensor := range haSensors {
+		topic, body, err := haDiscoveryConfig(device, sensor)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(current.Keys, sensor.Key) {
+			if !slices.Contains(published.Keys, sensor.Key) {
+				continue
+			}
+			// An empty retained payload removes the entity from Home Assistant.
+			body = nil
+		}
+		if err := mqttPublish(client, topic, true, body); err != nil {
+			return err
+		}
+	}
+	return stateStore.Put(ctx, mqttDiscoveryKey(device.DeviceID), current)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, message string) error
+}
+
+var notifiers []Notifier
+
+func newNotifiers() ([]Notifier, error) {
+	var list []Notifier
+	switch config.Notifier {
+	case "":
+		if config.MastodonURL != "" {
+			list = append(list, mastodonNotifier{})
+		}
+		if config.SlackWebhookURL != "" {
+			list = append(list, slackNotifier{webhookURL: config.SlackWebhookURL})
+		}
+	case "mastodon":
+		list = append(list, mastodonNotifier{})
+	case "slack":
+		list = append(list, slackNotifier{webhookURL: config.SlackWebhookURL})
+	case "both":
+		list = append(list, mastodonNotifier{}, slackNotifier{webhookURL: config.SlackWebhookURL})
+	case "misskey", "bluesky":
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", config.Notifier)
+	}
+	if slices.ContainsFunc(list, isSlack) && config.SlackWebhookURL == "" {
+		return nil, fmt.Errorf("notifier %q requires SlackWebhookURL", config.Notifier)
+	}
+	if config.MisskeyURL != "" {
+		list = append(list, misskeyNotifier{})
+	} else if config.Notifier == "misskey" {
+		return nil, fmt.Errorf("notifier %q requires MisskeyURL", config.Notifier)
+	}
+	if config.BlueskyHandle != "" {
+		list = append(list, blueskyNotifier{})
+	} else if config.Notifier == "bluesky" {
+		return nil, fmt.Errorf("notifier %q requires BlueskyHandle", config.Notifier)
+	}
+	for _, p := range plugins {
+		if p.can("notify") {
+			list = append(list, pluginNotifier{plugin: p})
+		}
+	}
+	if config.MatrixHomeserver != "" {
+		list = append(list, matrixNotifier{homeserver: config.MatrixHomeserver, token: config.MatrixAccessToken, room: config.MatrixRoomID})
+	}
+	if config.XMPPJID != "" {
+		list = append(list, xmppNotifier{jid: config.XMPPJID, password: config.XMPPPassword, server: config.XMPPServer, recipient: config.XMPPRecipient})
+	}
+	if config.GoogleChatWebhookURL != "" {
+		list = append(list, googleChatNotifier{webhookURL: config.GoogleChatWebhookURL})
+	}
+	if config.TeamsWebhookURL != "" {
+		list = append(list, teamsNotifier{webhookURL: config.TeamsWebhookURL})
+	}
+	return list, nil
+}
+
+func isSlack(n Notifier) bool {
+	_, ok := n.(slackNotifier)
+	return ok
+}
+
+func usesMastodon() bool {
+	return slices.ContainsFunc(notifiers, func(n Notifier) bool {
+		_, ok := n.(mastodonNotifier)
+		return ok
+	})
+}
+
+func notify(ctx context.Context, message string) error {
+	var errs []error
+	for _, n := range notifiers {
+		err := callNotifier(ctx, n, func(ctx context.Context) error { return n.Notify(ctx, message) })
+		if errors.Is(err, errDestinationSkipped) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+			continue
+		}
+		recordOps(func(s *opsStats) { s.Posts++ })
+	}
+	return errors.Join(errs...)
+}
+
+type urgentNotifier interface {
+	NotifyUrgent(ctx context.Context, message string) error
+}
+
+func notifyUrgent(ctx context.Context, message string) error {
+	var errs []error
+	for _, n := range notifiers {
+		err := callNotifier(ctx, n, func(ctx context.Context) error {
+			if u, ok := n.(urgentNotifier); ok {
+				return u.NotifyUrgent(ctx, message)
+			}
+			return n.Notify(ctx, message)
+		})
+		if errors.Is(err, errDestinationSkipped) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+			continue
+		}
+		recordOps(func(s *opsStats) { s.Posts++ })
+	}
+	return errors.Join(errs...)
+}
+
+type mastodonNotifier struct{}
+
+func (mastodonNotifier) Name() string { return "mastodon" }
+
+func (mastodonNotifier) Notify(ctx context.Context, message string) error {
+	return postToMastodon(ctx, message)
+}
+
+func (mastodonNotifier) NotifyUrgent(ctx context.Context, message string) error {
+	return postToMastodonWithVisibility(ctx, message, config.UrgentVisibility, spoilerFor(true))
+}
+
+type slackNotifier struct {
+	webhookURL string
+}
+
+func (slackNotifier) Name() string { return "slack" }
+
+func (n slackNotifier) Notify(ctx context.Context, message string) error {
+	var b strings.Builder
+	for i, s := range splitMessageSections(message) {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		if s.Title != "" {
+			fmt.Fprintf(&b, "*%s*\n", slackEscaper.Replace(s.Title))
+		}
+		for _, line := range s.Lines {
+			b.WriteString(slackEscaper.Replace(line) + "\n")
+		}
+	}
+	return postWebhookJSON(ctx, "Slack", n.webhookURL, map[string]string{"text": b.String()})
+}
+
+// slackEscaper escapes the characters Slack reserves for links, mentions and
+// entities, so that device names and messages are shown as written.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+type OfficeProfile struct {
+	Workdays       []string
+	Start          string
+	End            string
+	CO2Threshold   int
+	Rooms          map[string]OfficeRoom
+	RankingWeekday string
+	RankingHour    *int
+}
+
+type OfficeRoom struct {
+	Start        string
+	End          string
+	CO2Threshold int
+}
+
+type officeRoomStats struct {
+	Name     string
+	Sum      float64
+	Count    int
+	Over     int
+	Expected int
+	Gaps     int
+	Missing  bool
+}
+
+const officeRankingKey = "office_ranking_posted"
+
+func validateOfficeProfile(p *OfficeProfile) error {
+	if p == nil {
+		return nil
+	}
+	for _, day := range append(slices.Clone(p.Workdays), p.RankingWeekday) {
+		if day == "" {
+			continue
+		}
+		if _, err := parseWeekday(day); err != nil {
+			return err
+		}
+	}
+	for name, room := range p.Rooms {
+		start, end, _ := p.hours(room)
+		if _, err := parseClock(start); err != nil {
+			return fmt.Errorf("room %q: %w", name, err)
+		}
+		if _, err := parseClock(end); err != nil {
+			return fmt.Errorf("room %q: %w", name, err)
+		}
+	}
+	if h := p.rankingHour(); h < 0 || h > 23 {
+		return fmt.Errorf("invalid RankingHour %d", h)
+	}
+	return nil
+}
+
+func (p *OfficeProfile) hours(room OfficeRoom) (string, string, int) {
+	return cmp.Or(room.Start, p.Start, "09:00"), cmp.Or(room.End, p.End, "18:00"), cmp.Or(room.CO2Threshold, p.CO2Threshold, 1000)
+}
+
+func (p *OfficeProfile) isWorkday(now time.Time) bool {
+	days := p.Workdays
+	if len(days) == 0 {
+		days = []string{"Mon", "Tue", "Wed", "Thu", "Fri"}
+	}
+	weekday := now.In(timeLocation()).Weekday()
+	return slices.ContainsFunc(days, func(day string) bool {
+		d, err := parseWeekday(day)
+		return err == nil && d == weekday
+	})
+}
+
+func (p *OfficeProfile) room(device SwitchBotDevice) (string, OfficeRoom, bool) {
+	if room, ok := p.Rooms[device.DeviceName]; ok {
+		return device.DeviceName, room, true
+	}
+	room, ok := p.Rooms[device.DeviceID]
+	return device.DeviceID, room, ok
+}
+
+func (p *OfficeProfile) occupied(room OfficeRoom, now time.Time) bool {
+	if !p.isWorkday(now) {
+		return false
+	}
+	start, end, _ := p.hours(room)
+	s, _ := parseClock(start)
+	e, _ := parseClock(end)
+	local := now.In(timeLocation())
+	return inClockRange(local.Hour()*60+local.Minute(), s, e)
+}
+
+func runOfficeProfile(ctx context.Context, readings []deviceReading, now time.Time) {
+	p := config.Office
+	week := officeWeek(now)
+	statsKey := "office_stats:" + week
+	stats := map[string]officeRoomStats{}
+	if _, err := stateStore.Get(ctx, statsKey, &stats); err != nil {
+		log.Printf("Failed to load office stats: %v", err)
+	}
+
+	var ventilate []string
+	read := map[string]bool{}
+	for _, r := range readings {
+		name, room, ok := p.room(r.Device)
+		if !ok || r.Status.CO2 == nil {
+			continue
+		}
+		_, _, threshold := p.hours(room)
+		co2 := *r.Status.CO2
+		occupied := p.occupied(room, now)
+		if occupied {
+			s := stats[name]
+			s.Name = r.Device.DeviceName
+			s.Sum += float64(co2)
+			s.Count++
+			if co2 >= threshold {
+				s.Over++
+			}
+			stats[name] = s
+			read[name] = true
+		}
+
+		key := "office_ventilate:" + r.Device.DeviceID
+		var alerted bool
+		if _, err := stateStore.Get(ctx, key, &alerted); err != nil {
+			log.Printf("Failed to load ventilation state for %s: %v", r.Device.DeviceName, err)
+		}
+		high := occupied && co2 >= threshold
+		if high && !alerted {
+			ventilate = append(ventilate, fmt.Sprintf("🪟 %s: CO2 %sppm（基準 %sppm）", r.Device.DeviceName, formatInt(co2), formatInt(threshold)))
+		}
+		if high != alerted {
+			if err := stateStore.Put(ctx, key, high); err != nil {
+				log.Printf("Failed to save ventilation state for %s: %v", r.Device.DeviceName, err)
+			}
+		}
+	}
+	for name, room := range p.Rooms {
+		if !p.occupied(room, now) {
+			continue
+		}
+		s := stats[name]
+		s.Expected++
+		if !read[name] && !s.Missing {
+			s.Gaps++
+		}
+		s.Missing = !read[name]
+		stats[name] = s
+	}
+	if err := stateStore.Put(ctx, statsKey, stats); err != nil {
+		log.Printf("Failed to save office stats: %v", err)
+	}
+
+	if len(ventilate) > 0 {
+		message := makeDeviceHeader("今すぐ換気してください") + "\n" + strings.Join(ventilate, "\n") + "\n"
+		if err := notify(ctx, message); err != nil {
+			log.Printf("Failed to post ventilation alert: %v", err)
+		}
+	}
+	postOfficeRanking(ctx, now)
+}
+
+func postOfficeRanking(ctx context.Context, now time.Time) {
+	p := config.Office
+	local := now.In(timeLocation())
+	weekday, err := parseWeekday(cmp.Or(p.RankingWeekday, "Mon"))
+	if err != nil || local.Weekday() != weekday || local.Hour() < p.rankingHour() {
+		return
+	}
+	week := officeWeek(now)
+	var posted string
+	if _, err := stateStore.Get(ctx, officeRankingKey, &posted); err != nil {
+		log.Printf("Failed to load office ranking state: %v", err)
+		return
+	}
+	if posted == week {
+		return
+	}
+
+	previous := officeWeek(now.AddDate(0, 0, -7))
+	stats := map[string]officeRoomStats{}
+	if _, err := stateStore.Get(ctx, "office_stats:"+previous, &stats); err != nil {
+		log.Printf("Failed to load office stats: %v", err)
+		return
+	}
+	if len(stats) > 0 {
+		if err := notify(ctx, formatOfficeRanking(previous, stats)); err != nil {
+			log.Printf("Failed to post office ranking: %v", err)
+			return
+		}
+	}
+	if err := stateStore.Put(ctx, officeRankingKey, week); err != nil {
+		log.Printf("Failed to save office ranking state: %v", err)
+	}
+}
+
+func (p *OfficeProfile) rankingHour() int {
+	if p.RankingHour == nil {
+		return 9
+	}
+	return *p.RankingHour
+}
+
+func formatOfficeRanking(week string, stats map[string]officeRoomStats) string {
+	rooms := make([]string, 0, len(stats))
+	for name, s := range stats {
+		if s.Count > 0 {
+			rooms = append(rooms, name)
+		}
+	}
+	average := func(name string) float64 { return stats[name].Sum / float64(stats[name].Count) }
+	slices.SortFunc(rooms, func(a, b string) int { return cmp.Compare(average(a), average(b)) })
+
+	var b strings.Builder
+	b.WriteString(makeDeviceHeader(fmt.Sprintf("会議室の空気質ランキング (%s)", week)) + "\n")
+	for i, name := range rooms {
+		s := stats[name]
+		line := fmt.Sprintf("%d. %s 平均CO2 %sppm（基準超過 %s%%", i+1, cmp.Or(s.Name, name), formatNumber(average(name), 0), formatInt(s.Over*100/s.Count))
+		if s.Expected > 0 {
+			line += fmt.Sprintf("、データ完全性 %s%%", formatNumber(min(float64(s.Count)/float64(s.Expected), 1)*100, 0))
+		}
+		line += "）"
+		if config.GapPolicy == gapFlag && s.Gaps > 0 {
+			line += fmt.Sprintf(" ⚠️ 欠測 %s回", formatInt(s.Gaps))
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func officeWeek(t time.Time) string {
+	year, week := t.In(timeLocation()).ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
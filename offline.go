@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+type switchBotStatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *switchBotStatusError) Error() string {
+	return fmt.Sprintf("unexpected statusCode %d: %s", e.Code, e.Message)
+}
+
+var switchBotOfflineReasons = map[int]string{
+	151: "対応していないデバイス",
+	152: "デバイスが見つかりません",
+	160: "対応していない操作",
+	161: "デバイスがオフライン",
+	171: "ハブがオフライン",
+}
+
+func offlineReason(err error) (string, bool) {
+	var statusErr *switchBotStatusError
+	if !errors.As(err, &statusErr) {
+		return "", false
+	}
+	reason, ok := switchBotOfflineReasons[statusErr.Code]
+	return reason, ok
+}
+
+func splitOffline(readings []deviceReading) (online, offline []deviceReading) {
+	for _, r := range readings {
+		if r.Status.Offline != "" {
+			offline = append(offline, r)
+		} else {
+			online = append(online, r)
+		}
+	}
+	return online, offline
+}
+
+func offlineSection(ctx context.Context, r deviceReading) deviceSection {
+	if err := PutMetric(ctx, r.Device, r.Status); err != nil {
+		log.Printf("Failed to send metrics to CloudWatch: %v", err)
+	}
+	message := makeDeviceHeader(r.Device.DeviceName) + "\n" + fmt.Sprintf(tr("⚠️ オフライン（%s）"), tr(r.Status.Offline)) + "\n"
+	section := deviceSection{Device: r.Device, Message: message, Notable: true}
+	section.Message = formatSection(ctx, section, r.Status, nil)
+	return section
+}
@@ -0,0 +1,477 @@
+package main
+
+//go:generate sh -c "go run . openapi > api/openapi.json"
+//go:generate sh -c "go run . openapi -client > api/client/client.go"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const openAPIPath = "/openapi.json"
+
+// apiOperations registers the routes and generates the OpenAPI document and client.
+type apiOperation struct {
+	Method  string
+	Path    string
+	ID      string
+	Summary string
+	// Auth is "dashboard", "read", "alertmanager", or empty.
+	Auth        string
+	Query       []string
+	Request     any
+	Response    any
+	Status      int
+	ContentType string
+	Enabled     func() bool
+	Handler     http.HandlerFunc
+}
+
+func apiOperations() []apiOperation {
+	return []apiOperation{
+		{
+			Method: "GET", Path: statusAPIPath, ID: "getStatus",
+			Summary:  "Latest reading of every device",
+			Auth:     "read",
+			Response: []liveReading{},
+			Status:   http.StatusOK,
+			Handler:  serveStatusAPI,
+		},
+		{
+			Method: "GET", Path: "/events", ID: "streamReadings",
+			Summary:     "Server-sent events with the readings of each collection run",
+			Auth:        "read",
+			Response:    []liveReading{},
+			Status:      http.StatusOK,
+			ContentType: "text/event-stream",
+			Handler:     serveEvents,
+		},
+		{
+			Method: "POST", Path: "/post-now", ID: "postNow",
+			Summary: "Collect readings and post them immediately",
+			Auth:    "dashboard",
+			Status:  http.StatusNoContent,
+			Handler: servePostNow,
+		},
+		{
+			Method: "GET", Path: kioskPath, ID: "getKiosk",
+			Summary:  "Readings for kiosk displays, signed with KioskSecret when set",
+			Query:    []string{"expires", "sig"},
+			Response: kioskResponse{},
+			Status:   http.StatusOK,
+			Enabled:  func() bool { return config.KioskEnabled },
+			Handler:  serveKiosk,
+		},
+		{
+			Method: "POST", Path: "/graphql", ID: "queryGraphQL",
+			Summary:  "Run a read-only GraphQL query",
+			Auth:     "read",
+			Request:  graphQLRequest{},
+			Response: graphQLResponse{},
+			Status:   http.StatusOK,
+			Enabled:  func() bool { return config.GraphQLEnabled },
+			Handler:  serveGraphQL,
+		},
+		{
+			Method: "POST", Path: "/alertmanager", ID: "postAlertmanager",
+			Summary: "Receive a Prometheus Alertmanager webhook",
+			Auth:    "alertmanager",
+			Request: alertmanagerPayload{},
+			Status:  http.StatusNoContent,
+			Enabled: func() bool { return config.AlertmanagerEnabled },
+			Handler: serveAlertmanager,
+		},
+	}
+}
+
+func registerAPI(mux *http.ServeMux) {
+	for _, op := range apiOperations() {
+		if op.Enabled == nil || op.Enabled() {
+			handler := op.Handler
+			if op.ContentType != "text/event-stream" {
+				handler = withConfig(handler)
+			}
+			mux.HandleFunc(op.Method+" "+op.Path, handler)
+		}
+	}
+	mux.HandleFunc("GET "+openAPIPath, withConfig(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPIDocument())
+	}))
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+type openAPISchemas map[string]*openAPISchema
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func (c openAPISchemas) schemaFor(t reflect.Type) *openAPISchema {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.String:
+		return &openAPISchema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &openAPISchema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	case t.Kind() == reflect.Slice:
+		return &openAPISchema{Type: "array", Items: c.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: c.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		name := exportedName(t.Name())
+		if _, ok := c[name]; !ok {
+			s := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+			c[name] = s
+			c.addFields(s, t)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + name}
+	}
+	return &openAPISchema{}
+}
+
+func (c openAPISchemas) addFields(s *openAPISchema, t reflect.Type) {
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || len(f.Index) > 1 {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			c.addFields(s, f.Type)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = c.schemaFor(f.Type)
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func exportedName(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	s := string(r)
+	if base, ok := strings.CutSuffix(s, "Id"); ok {
+		s = base + "ID"
+	}
+	return s
+}
+
+func openAPIDocument() []byte {
+	schemas := openAPISchemas{}
+	paths := map[string]map[string]any{}
+	for _, op := range apiOperations() {
+		operation := map[string]any{
+			"operationId": op.ID,
+			"summary":     op.Summary,
+		}
+		if op.Auth != "" {
+			operation["security"] = []map[string][]string{{"bearerAuth": {}}}
+		}
+		var params []map[string]any
+		for _, q := range op.Query {
+			params = append(params, map[string]any{"name": q, "in": "query", "schema": map[string]string{"type": "string"}})
+		}
+		if params != nil {
+			operation["parameters"] = params
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": schemas.schemaFor(reflect.TypeOf(op.Request))}},
+			}
+		}
+		response := map[string]any{"description": http.StatusText(op.Status)}
+		if op.Response != nil {
+			contentType := op.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			response["content"] = map[string]any{contentType: map[string]any{"schema": schemas.schemaFor(reflect.TypeOf(op.Response))}}
+		}
+		operation["responses"] = map[string]any{fmt.Sprint(op.Status): response}
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]any{}
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = operation
+	}
+	doc := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "switchbot_bot daemon API",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas":         schemas,
+			"securitySchemes": map[string]any{"bearerAuth": map[string]string{"type": "http", "scheme": "bearer"}},
+		},
+	}
+	b, _ := json.MarshalIndent(doc, "", "  ")
+	return append(b, '\n')
+}
+
+func generateAPIClient() ([]byte, error) {
+	schemas := openAPISchemas{}
+	var ops bytes.Buffer
+	for _, op := range apiOperations() {
+		if op.ContentType != "" {
+			continue
+		}
+		name := exportedName(op.ID)
+		var args []string
+		args = append(args, "ctx context.Context")
+		if op.Query != nil {
+			fmt.Fprintf(&ops, "// %sParams are the query parameters of %s.\ntype %sParams struct {\n", name, name, name)
+			for _, q := range op.Query {
+				fmt.Fprintf(&ops, "\t%s string\n", exportedName(q))
+			}
+			ops.WriteString("}\n\n")
+			args = append(args, "params "+name+"Params")
+		}
+		if op.Request != nil {
+			args = append(args, "body "+goType(schemas.schemaFor(reflect.TypeOf(op.Request)), true))
+		}
+		result, ret, out := "error", "return c.do(req, nil)", ""
+		if op.Response != nil {
+			typ := goType(schemas.schemaFor(reflect.TypeOf(op.Response)), true)
+			result = "(" + typ + ", error)"
+			out = "var out " + typ + "\n"
+			ret = "return out, c.do(req, &out)"
+		}
+		fmt.Fprintf(&ops, "// %s calls %s %s: %s.\nfunc (c *Client) %s(%s) %s {\n%s", name, op.Method, op.Path, op.Summary, name, strings.Join(args, ", "), result, out)
+		path := fmt.Sprintf("%q", op.Path)
+		if op.Query != nil {
+			ops.WriteString("q := url.Values{}\n")
+			for _, q := range op.Query {
+				fmt.Fprintf(&ops, "if params.%s != \"\" {\nq.Set(%q, params.%s)\n}\n", exportedName(q), q, exportedName(q))
+			}
+			path += ` + "?" + q.Encode()`
+		}
+		body := "nil"
+		if op.Request != nil {
+			body = "body"
+		}
+		errResult := "err"
+		if op.Response != nil {
+			errResult = "out, err"
+		}
+		fmt.Fprintf(&ops, "req, err := c.newRequest(ctx, %q, %s, %s)\nif err != nil {\nreturn %s\n}\n%s\n}\n\n", op.Method, path, body, errResult, ret)
+	}
+
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var types bytes.Buffer
+	for _, name := range names {
+		s := schemas[name]
+		fmt.Fprintf(&types, "type %s struct {\n", name)
+		props := make([]string, 0, len(s.Properties))
+		for p := range s.Properties {
+			props = append(props, p)
+		}
+		slices.Sort(props)
+		for _, p := range props {
+			required := slices.Contains(s.Required, p)
+			tag := p
+			if !required {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&types, "\t%s %s `json:%q`\n", exportedName(p), goType(s.Properties[p], required), tag)
+		}
+		types.WriteString("}\n\n")
+	}
+
+	imports := []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "strings"}
+	if bytes.Contains(ops.Bytes(), []byte("url.Values")) {
+		imports = append(imports, "net/url")
+	}
+	if bytes.Contains(types.Bytes(), []byte("time.Time")) {
+		imports = append(imports, "time")
+	}
+	var b bytes.Buffer
+	b.WriteString(`// Code generated by "switchbot_bot openapi -client". DO NOT EDIT.
+
+// Package client is a Go client for the switchbot_bot daemon API described
+// in api/openapi.json.
+package client
+
+import (
+`)
+	for _, imp := range imports {
+		fmt.Fprintf(&b, "\t%q\n", imp)
+	}
+	b.WriteString(`)
+
+// Client calls the daemon at BaseURL, sending Token as a bearer token when set.
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// APIError is returned for responses with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("switchbot_bot API: %d %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+func (c *Client) do(req *http.Request, out any) error {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return &APIError{StatusCode: res.StatusCode, Body: string(body)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+`)
+	b.Write(ops.Bytes())
+	b.Write(types.Bytes())
+	return format.Source(b.Bytes())
+}
+
+func goType(s *openAPISchema, required bool) string {
+	ptr := ""
+	if !required {
+		ptr = "*"
+	}
+	switch {
+	case s.Ref != "":
+		return ptr + strings.TrimPrefix(s.Ref, "#/components/schemas/")
+	case s.Type == "array":
+		return "[]" + goType(s.Items, true)
+	case s.Type == "object":
+		return "map[string]" + goType(s.AdditionalProperties, true)
+	case s.Format == "date-time":
+		return ptr + "time.Time"
+	case s.Type == "string":
+		return ptr + "string"
+	case s.Type == "integer":
+		return ptr + "int"
+	case s.Type == "number":
+		return ptr + "float64"
+	case s.Type == "boolean":
+		return ptr + "bool"
+	}
+	return "any"
+}
+
+func runOpenAPICommand(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	client := fs.Bool("client", false, "print the generated Go client instead of the OpenAPI document")
+	check := fs.Bool("check", false, "fail if api/openapi.json or api/client/client.go is out of date")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *check {
+		return checkAPIArtifacts()
+	}
+	if !*client {
+		_, err := os.Stdout.Write(openAPIDocument())
+		return err
+	}
+	src, err := generateAPIClient()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(src)
+	return err
+}
+
+func checkAPIArtifacts() error {
+	src, err := generateAPIClient()
+	if err != nil {
+		return err
+	}
+	var stale []string
+	for path, want := range map[string][]byte{
+		"api/openapi.json":     openAPIDocument(),
+		"api/client/client.go": src,
+	} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, want) {
+			stale = append(stale, path)
+		}
+	}
+	if len(stale) > 0 {
+		slices.Sort(stale)
+		return fmt.Errorf("%s out of date; run go generate", strings.Join(stale, " and "))
+	}
+	return nil
+}
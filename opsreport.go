@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	opsSummaryPostedKey   = "ops_summary_posted"
+	switchBotDailyQuota   = 10000
+	opsStatsRetentionDays = 31
+)
+
+type opsStats struct {
+	Runs            int
+	SwitchBotCalls  int
+	Retries         int
+	NotifierRetries int
+	Posts           int
+	Alerts          int
+	Errors          int
+}
+
+var (
+	opsMu      sync.Mutex
+	opsPending opsStats
+)
+
+func recordOps(update func(*opsStats)) {
+	opsMu.Lock()
+	update(&opsPending)
+	opsMu.Unlock()
+}
+
+func opsDate(t time.Time) string {
+	return t.In(timeLocation()).Format(time.DateOnly)
+}
+
+func opsStatsKey(date string) string {
+	return "ops_stats:" + date
+}
+
+func flushOps(ctx context.Context, now time.Time) {
+	opsMu.Lock()
+	pending := opsPending
+	opsPending = opsStats{}
+	opsMu.Unlock()
+
+	key := opsStatsKey(opsDate(now))
+	var day opsStats
+	if _, err := stateStore.Get(ctx, key, &day); err != nil {
+		log.Printf("Failed to load operations stats: %v", err)
+	}
+	day.Runs += pending.Runs
+	day.SwitchBotCalls += pending.SwitchBotCalls
+	day.Retries += pending.Retries
+	day.NotifierRetries += pending.NotifierRetries
+	day.Posts += pending.Posts
+	day.Alerts += pending.Alerts
+	day.Errors += pending.Errors
+	if err := stateStore.Put(ctx, key, day); err != nil {
+		log.Printf("Failed to save operations stats: %v", err)
+	}
+}
+
+func postOpsSummary(ctx context.Context, now time.Time) {
+	if !config.OpsSummaryEnabled {
+		return
+	}
+	yesterday := opsDate(now.AddDate(0, 0, -1))
+	var posted string
+	if _, err := stateStore.Get(ctx, opsSummaryPostedKey, &posted); err != nil {
+		log.Printf("Failed to load operations summary state: %v", err)
+		return
+	}
+	if posted == yesterday {
+		return
+	}
+	var day opsStats
+	ok, err := stateStore.Get(ctx, opsStatsKey(yesterday), &day)
+	if err != nil {
+		log.Printf("Failed to load operations stats: %v", err)
+		return
+	}
+	if ok {
+		message := formatOpsSummary(formatDate(now.AddDate(0, 0, -1)), day) + releaseSummaryLine(ctx)
+		if now.In(timeLocation()).Weekday() == time.Monday {
+			message += formatAlertFeedback(ctx, now)
+		}
+		if err := sendOpsSummary(ctx, message); err != nil {
+			log.Printf("Failed to post operations summary: %v", err)
+			return
+		}
+	}
+	if err := stateStore.Put(ctx, opsSummaryPostedKey, yesterday); err != nil {
+		log.Printf("Failed to save operations summary state: %v", err)
+	}
+}
+
+func formatOpsSummary(date string, s opsStats) string {
+	var b strings.Builder
+	b.WriteString(makeDeviceHeader(fmt.Sprintf("稼働レポート (%s)", date)) + "\n")
+	fmt.Fprintf(&b, "実行回数: %s\n", formatInt(s.Runs))
+	fmt.Fprintf(&b, "SwitchBot API: %s/%s回 (%s%%)\n", formatInt(s.SwitchBotCalls), formatInt(switchBotDailyQuota), formatNumber(float64(s.SwitchBotCalls)*100/switchBotDailyQuota, 1))
+	fmt.Fprintf(&b, "リトライ: %s\n", formatInt(s.Retries))
+	if s.NotifierRetries > 0 {
+		fmt.Fprintf(&b, "通知リトライ: %s\n", formatInt(s.NotifierRetries))
+	}
+	fmt.Fprintf(&b, "投稿: %s\n", formatInt(s.Posts))
+	fmt.Fprintf(&b, "アラート: %s\n", formatInt(s.Alerts))
+	fmt.Fprintf(&b, "エラー: %s\n", formatInt(s.Errors))
+	return b.String()
+}
+
+func sendOpsSummary(ctx context.Context, message string) error {
+	if config.OpsSummaryMention != "" && usesMastodon() {
+		return postToMastodonWithVisibility(ctx, config.OpsSummaryMention+"\n"+message, "direct", "")
+	}
+	return notify(ctx, message)
+}
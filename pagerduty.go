@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string         `json:"summary"`
+	Source        string         `json:"source"`
+	Severity      string         `json:"severity"`
+	Timestamp     time.Time      `json:"timestamp"`
+	Component     string         `json:"component,omitempty"`
+	CustomDetails map[string]any `json:"custom_details,omitempty"`
+}
+
+func pagerDutyIncidentsKey(deviceID string) string {
+	return "pagerduty_incidents:" + deviceID
+}
+
+// notifyPagerDuty only records events PagerDuty accepted, so a failed one is resent.
+func notifyPagerDuty(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus, alerts []triggeredAlert) {
+	if config.PagerDutyRoutingKey == "" {
+		return
+	}
+	key := pagerDutyIncidentsKey(device.DeviceID)
+	var open []string
+	if _, err := stateStore.Get(ctx, key, &open); err != nil {
+		log.Printf("Failed to load PagerDuty incidents for %s: %v", device.DeviceName, err)
+		return
+	}
+	changed := false
+	active := map[string]bool{}
+	for _, alert := range alerts {
+		active[alert.Rule.Name] = true
+		if alert.Rule.Severity == "" || slices.Contains(open, alert.Rule.Name) {
+			continue
+		}
+		event := pagerDutyEvent{
+			RoutingKey:  config.PagerDutyRoutingKey,
+			EventAction: "trigger",
+			DedupKey:    pagerDutyDedupKey(device, alert.Rule),
+			Payload: &pagerDutyPayload{
+				Summary:       alert.text(),
+				Source:        device.DeviceName,
+				Severity:      alert.Rule.Severity,
+				Timestamp:     status.ReadAt,
+				Component:     device.DeviceType,
+				CustomDetails: pagerDutyDetails(device, status, alert.Rule),
+			},
+		}
+		if err := sendPagerDutyEvent(ctx, event); err != nil {
+			log.Printf("Failed to trigger PagerDuty incident for %s: %v", alert.Rule.Name, err)
+			continue
+		}
+		open = append(open, alert.Rule.Name)
+		changed = true
+	}
+	// Removing a rule or its Severity must not leave its incident open.
+	for _, name := range slices.Clone(open) {
+		if active[name] {
+			continue
+		}
+		event := pagerDutyEvent{
+			RoutingKey:  config.PagerDutyRoutingKey,
+			EventAction: "resolve",
+			DedupKey:    pagerDutyDedupKey(device, AlertRule{Name: name}),
+		}
+		if err := sendPagerDutyEvent(ctx, event); err != nil {
+			log.Printf("Failed to resolve PagerDuty incident for %s: %v", name, err)
+			continue
+		}
+		open = slices.DeleteFunc(open, func(n string) bool { return n == name })
+		changed = true
+	}
+	if changed {
+		if err := stateStore.Put(ctx, key, open); err != nil {
+			log.Printf("Failed to save PagerDuty incidents for %s: %v", device.DeviceName, err)
+		}
+	}
+}
+
+func pagerDutyDedupKey(device SwitchBotDevice, rule AlertRule) string {
+	return "switchbot:" + device.DeviceID + ":" + rule.Name
+}
+
+func pagerDutyDetails(device SwitchBotDevice, status SwitchBotDeviceStatus, rule AlertRule) map[string]any {
+	details := map[string]any{
+		"deviceId":  device.DeviceID,
+		"rule":      rule.Name,
+		"condition": rule.Condition,
+	}
+	for _, metric := range []string{"temperature", "humidity", "co2", "battery"} {
+		if v, ok := metricValue(status, metric); ok {
+			details[metric] = v
+		}
+	}
+	return details
+}
+
+func sendPagerDutyEvent(ctx context.Context, event pagerDutyEvent) error {
+	buf, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", pagerDutyEventsURL, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("pagerduty API error: %s", body)
+	}
+	log.Printf("PagerDuty %s sent: %s", event.EventAction, event.DedupKey)
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"unicode/utf8"
+)
+
+const (
+	pinnedStatusKey = "pinned_status"
+
+	pinnedStatusAlso = "also"
+	pinnedStatusOnly = "only"
+)
+
+func validatePinnedStatus(mode string) error {
+	switch mode {
+	case "", pinnedStatusAlso, pinnedStatusOnly:
+		return nil
+	}
+	return fmt.Errorf("invalid PinnedStatus %q (want %q or %q)", mode, pinnedStatusAlso, pinnedStatusOnly)
+}
+
+func updatePinnedStatus(ctx context.Context, message string) error {
+	if limit := mastodonCharLimit(ctx); utf8.RuneCountInString(message) > limit {
+		message = splitStatus(message, "", limit)[0]
+	}
+	var id string
+	if _, err := stateStore.Get(ctx, pinnedStatusKey, &id); err != nil {
+		log.Printf("Failed to load pinned status: %v", err)
+	}
+	if id != "" {
+		_, err := mastodonStatusRequest(ctx, "PUT", "/statuses/"+id, map[string]any{"status": message})
+		if !errors.Is(err, errMastodonNotFound) {
+			return err
+		}
+		log.Printf("Pinned status %s is gone; posting a new one", id)
+	}
+
+	id, err := createMastodonStatus(ctx, map[string]any{
+		"status":     message,
+		"visibility": config.PostVisibility,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := mastodonStatusRequest(ctx, "POST", "/statuses/"+id+"/pin", nil); err != nil {
+		return fmt.Errorf("pin status %s: %w", id, err)
+	}
+	return stateStore.Put(ctx, pinnedStatusKey, id)
+}
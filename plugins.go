@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const pluginTimeout = 10 * time.Second
+
+// plugin speaks one JSON request and response per call over stdio; see the README.
+type plugin struct {
+	Path         string
+	Name         string
+	Capabilities []string
+}
+
+type pluginRequest struct {
+	Type    string        `json:"type"`
+	Message string        `json:"message,omitempty"`
+	Urgent  bool          `json:"urgent,omitempty"`
+	Points  []metricPoint `json:"points,omitempty"`
+}
+
+type pluginResponse struct {
+	Error        string   `json:"error"`
+	Name         string   `json:"name"`
+	Capabilities []string `json:"capabilities"`
+}
+
+var plugins []plugin
+
+func discoverPlugins(dir string) ([]plugin, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var found []plugin
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		p := plugin{Path: filepath.Join(dir, e.Name())}
+		resp, err := p.call(context.Background(), pluginRequest{Type: "describe"})
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", e.Name(), err)
+		}
+		p.Name = resp.Name
+		if p.Name == "" {
+			p.Name = e.Name()
+		}
+		p.Capabilities = resp.Capabilities
+		log.Printf("Loaded plugin %s (%v)", p.Name, p.Capabilities)
+		found = append(found, p)
+	}
+	return found, nil
+}
+
+func (p plugin) call(ctx context.Context, req pluginRequest) (pluginResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+	in, err := json.Marshal(req)
+	if err != nil {
+		return pluginResponse{}, err
+	}
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = bytes.NewReader(append(in, '\n'))
+	cmd.Stderr = log.Writer()
+	out, err := cmd.Output()
+	if err != nil {
+		return pluginResponse{}, err
+	}
+	var resp pluginResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return pluginResponse{}, fmt.Errorf("invalid response %q: %w", out, err)
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+func (p plugin) can(capability string) bool {
+	return slices.Contains(p.Capabilities, capability)
+}
+
+type pluginNotifier struct {
+	plugin plugin
+}
+
+func (n pluginNotifier) Name() string { return "plugin:" + n.plugin.Name }
+
+func (n pluginNotifier) Notify(ctx context.Context, message string) error {
+	_, err := n.plugin.call(ctx, pluginRequest{Type: "notify", Message: message})
+	return err
+}
+
+func (n pluginNotifier) NotifyUrgent(ctx context.Context, message string) error {
+	_, err := n.plugin.call(ctx, pluginRequest{Type: "notify", Message: message, Urgent: true})
+	return err
+}
+
+func putPluginMetrics(ctx context.Context, points []metricPoint) error {
+	for _, p := range plugins {
+		if !p.can("metrics") {
+			continue
+		}
+		if _, err := p.call(ctx, pluginRequest{Type: "metrics", Points: points}); err != nil {
+			return fmt.Errorf("plugin %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const maxProfileFields = 4
+
+func validateProfileFields(devices []string) error {
+	if len(devices) > maxProfileFields-1 {
+		return fmt.Errorf("ProfileFields accepts at most %d devices", maxProfileFields-1)
+	}
+	return nil
+}
+
+func updateProfileFields(ctx context.Context, readings []deviceReading, now time.Time) error {
+	form := url.Values{}
+	i := 0
+	for _, name := range config.ProfileFields {
+		for _, r := range readings {
+			if r.Device.DeviceName != name {
+				continue
+			}
+			if value := profileFieldValue(r.Status); value != "" {
+				form.Set(fmt.Sprintf("fields_attributes[%d][name]", i), name)
+				form.Set(fmt.Sprintf("fields_attributes[%d][value]", i), value)
+				i++
+			}
+			break
+		}
+	}
+	form.Set(fmt.Sprintf("fields_attributes[%d][name]", i), tr("最終更新"))
+	form.Set(fmt.Sprintf("fields_attributes[%d][value]", i), now.In(timeLocation()).Format("15:04"))
+
+	req, err := http.NewRequestWithContext(ctx, "PATCH", config.MastodonURL+"/accounts/update_credentials", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.MastodonToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("mastodon API error: %s", body)
+	}
+	log.Printf("Updated %d profile fields", i+1)
+	return nil
+}
+
+func profileFieldValue(status SwitchBotDeviceStatus) string {
+	var parts []string
+	if status.Temperature != nil {
+		t, unit := displayTemperature(*status.Temperature)
+		parts = append(parts, formatNumber(t, 1)+unit)
+	}
+	if status.Humidity != nil {
+		parts = append(parts, formatNumber(*status.Humidity, 0)+"%")
+	}
+	if status.CO2 != nil {
+		parts = append(parts, formatInt(*status.CO2)+"ppm")
+	}
+	if status.Power != nil {
+		parts = append(parts, formatNumber(*status.Power, 1)+"W")
+	}
+	return strings.Join(parts, " / ")
+}
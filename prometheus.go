@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type promSample struct {
+	Name  string
+	Value float64
+}
+
+func promSamples(status SwitchBotDeviceStatus, points []metricPoint) []promSample {
+	samples := make([]promSample, 0, len(points)+1)
+	for _, p := range points {
+		samples = append(samples, promSample{Name: promMetricName(p.Name), Value: p.Value})
+	}
+	if status.Battery != nil {
+		samples = append(samples, promSample{Name: "switchbot_battery", Value: float64(*status.Battery)})
+	}
+	return samples
+}
+
+func promMetricName(name string) string {
+	var b strings.Builder
+	b.WriteString("switchbot_")
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func promRequest(ctx context.Context, method, target string, body []byte, header map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if config.PrometheusUsername != "" {
+		req.SetBasicAuth(config.PrometheusUsername, config.PrometheusPassword)
+	}
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		b, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("%s returned %s: %s", target, res.Status, b)
+	}
+	return nil
+}
+
+// putRemoteWrite encodes the WriteRequest by hand rather than depend on the Prometheus module.
+func putRemoteWrite(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus, points []metricPoint) error {
+	var req []byte
+	for _, s := range promSamples(status, points) {
+		labels := [][2]string{{"__name__", s.Name}, {"device_id", device.DeviceID}, {"device_name", device.DeviceName}, {"job", config.PrometheusJob}}
+		slices.SortFunc(labels, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+		var series []byte
+		for _, l := range labels {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, l[0])
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l[1])
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, label)
+		}
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.Value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(status.ReadAt.UnixMilli()))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, series)
+	}
+	return promRequest(ctx, "POST", config.PrometheusURL, snappy.Encode(nil, req), map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	})
+}
+
+func putPushgateway(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus, points []metricPoint) error {
+	var b strings.Builder
+	name := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(device.DeviceName)
+	for _, s := range promSamples(status, points) {
+		fmt.Fprintf(&b, "# TYPE %s gauge\n%s{device_name=\"%s\"} %g\n", s.Name, s.Name, name, s.Value)
+	}
+	target := fmt.Sprintf("%s/metrics/job/%s/device_id/%s", strings.TrimRight(config.PrometheusURL, "/"),
+		url.PathEscape(config.PrometheusJob), url.PathEscape(device.DeviceID))
+	return promRequest(ctx, "PUT", target, []byte(b.String()), map[string]string{
+		"Content-Type": "text/plain; version=0.0.4",
+	})
+}
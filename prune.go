@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const lastPrunedKey = "last_pruned"
+
+var deviceStatePrefixes = []string{"condition_timers:", "active_alerts:", "office_ventilate:", "scene_active:", "quiet_last:", "device_thread:", "mqtt_discovery:"}
+
+type pruneAction struct {
+	Key     string
+	Removed int
+	Keep    any
+}
+
+func (a pruneAction) String() string {
+	if a.Keep == nil {
+		return "delete " + a.Key
+	}
+	return fmt.Sprintf("compact %s (drop %d entries)", a.Key, a.Removed)
+}
+
+func planPrune(ctx context.Context, now time.Time) ([]pruneAction, error) {
+	var actions []pruneAction
+
+	historyKeys, err := stateStore.Keys(ctx, "history:")
+	if err != nil {
+		return nil, err
+	}
+	cutoff := now.Add(-time.Duration(config.HistoryHours) * time.Hour)
+	alive := map[string]bool{}
+	for _, key := range historyKeys {
+		var history []SwitchBotDeviceStatus
+		if _, err := stateStore.Get(ctx, key, &history); err != nil {
+			return nil, err
+		}
+		kept := make([]SwitchBotDeviceStatus, 0, len(history))
+		for _, h := range history {
+			if h.ReadAt.After(cutoff) {
+				kept = append(kept, h)
+			}
+		}
+		switch {
+		case len(kept) == 0:
+			actions = append(actions, pruneAction{Key: key, Removed: len(history)})
+		case len(kept) < len(history):
+			actions = append(actions, pruneAction{Key: key, Removed: len(history) - len(kept), Keep: kept})
+			fallthrough
+		default:
+			alive[strings.TrimPrefix(key, "history:")] = true
+		}
+	}
+
+	for _, prefix := range deviceStatePrefixes {
+		keys, err := stateStore.Keys(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if !alive[strings.TrimPrefix(key, prefix)] {
+				actions = append(actions, pruneAction{Key: key})
+			}
+		}
+	}
+
+	oldest := officeWeek(now.AddDate(0, 0, -7*config.OfficeStatsWeeks))
+	for _, prefix := range []string{"office_stats:", "energy_stats:"} {
+		statsKeys, err := stateStore.Keys(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range statsKeys {
+			if strings.TrimPrefix(key, prefix) < oldest {
+				actions = append(actions, pruneAction{Key: key})
+			}
+		}
+	}
+
+	opsKeys, err := stateStore.Keys(ctx, "ops_stats:")
+	if err != nil {
+		return nil, err
+	}
+	oldestDay := opsDate(now.AddDate(0, 0, -opsStatsRetentionDays))
+	for _, key := range opsKeys {
+		if strings.TrimPrefix(key, "ops_stats:") < oldestDay {
+			actions = append(actions, pruneAction{Key: key})
+		}
+	}
+
+	batteryKeys, err := stateStore.Keys(ctx, "battery_history:")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range batteryKeys {
+		var samples []batterySample
+		if _, err := stateStore.Get(ctx, key, &samples); err != nil {
+			return nil, err
+		}
+		if len(samples) == 0 || now.Sub(samples[len(samples)-1].At) > batteryHistoryDays*24*time.Hour {
+			actions = append(actions, pruneAction{Key: key})
+		}
+	}
+
+	touKeys, err := stateStore.Keys(ctx, "tou_stats:")
+	if err != nil {
+		return nil, err
+	}
+	oldestMonth := touMonth(now.AddDate(0, -touStatsRetentionMonths, 0))
+	for _, key := range touKeys {
+		if strings.TrimPrefix(key, "tou_stats:") < oldestMonth {
+			actions = append(actions, pruneAction{Key: key})
+		}
+	}
+
+	rollupKeys, err := rollupKeysToPrune(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range rollupKeys {
+		actions = append(actions, pruneAction{Key: key})
+	}
+
+	for _, key := range metricBufferKeys() {
+		var buffered []metricPoint
+		if _, err := stateStore.Get(ctx, key, &buffered); err != nil {
+			return nil, err
+		}
+		if kept := dropExpiredMetrics(buffered, now); len(kept) < len(buffered) {
+			actions = append(actions, pruneAction{Key: key, Removed: len(buffered) - len(kept), Keep: kept})
+		}
+	}
+	return actions, nil
+}
+
+func applyPrune(ctx context.Context, actions []pruneAction) error {
+	for _, a := range actions {
+		var err error
+		if a.Keep == nil {
+			err = stateStore.Delete(ctx, a.Key)
+		} else {
+			err = stateStore.Put(ctx, a.Key, a.Keep)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", a, err)
+		}
+	}
+	return nil
+}
+
+func pruneDaily(ctx context.Context, now time.Time) {
+	var last time.Time
+	if _, err := stateStore.Get(ctx, lastPrunedKey, &last); err != nil {
+		log.Printf("Failed to load last prune time: %v", err)
+		return
+	}
+	if now.Sub(last) < 24*time.Hour {
+		return
+	}
+	metricBufferMu.Lock()
+	defer metricBufferMu.Unlock()
+	actions, err := planPrune(ctx, now)
+	if err == nil {
+		err = applyPrune(ctx, actions)
+	}
+	if err != nil {
+		log.Printf("Failed to prune state: %v", err)
+		return
+	}
+	if len(actions) > 0 {
+		log.Printf("Pruned %d state entries", len(actions))
+	}
+	if err := stateStore.Put(ctx, lastPrunedKey, now); err != nil {
+		log.Printf("Failed to save last prune time: %v", err)
+	}
+}
+
+func runPruneCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only show what would be deleted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	metricBufferMu.Lock()
+	defer metricBufferMu.Unlock()
+	actions, err := planPrune(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, a := range actions {
+		fmt.Println(a)
+	}
+	if len(actions) == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+	if *dryRun {
+		fmt.Printf("\n%d entries would be pruned (dry run)\n", len(actions))
+		return nil
+	}
+	if err := applyPrune(ctx, actions); err != nil {
+		return err
+	}
+	if err := stateStore.Put(ctx, lastPrunedKey, now); err != nil {
+		return err
+	}
+	fmt.Printf("\nPruned %d entries\n", len(actions))
+	return nil
+}
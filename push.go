@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const pushoverMessagesURL = "https://api.pushover.net/1/messages.json"
+
+var pushPriorities = map[string]struct {
+	ntfy     string
+	pushover int
+}{
+	"critical": {"urgent", 1},
+	"error":    {"high", 0},
+	"warning":  {"default", 0},
+	"info":     {"low", -1},
+	"":         {"default", 0},
+}
+
+func notifyPush(ctx context.Context, device SwitchBotDevice, previous []string, alerts []triggeredAlert) {
+	if config.PushNtfyURL == "" && config.PushoverToken == "" {
+		return
+	}
+	for _, alert := range alerts {
+		if slices.Contains(previous, alert.Rule.Name) {
+			continue
+		}
+		if err := sendPush(ctx, device, alert); err != nil {
+			log.Printf("Failed to send push notification for %s: %v", alert.Rule.Name, err)
+		}
+	}
+}
+
+func sendPush(ctx context.Context, device SwitchBotDevice, alert triggeredAlert) error {
+	title := "SwitchBot: " + device.DeviceName
+	priority := pushPriorities[alert.Rule.Severity]
+	var errs []error
+	if config.PushNtfyURL != "" {
+		if err := postNtfy(ctx, config.PushNtfyURL, config.PushNtfyToken, title, priority.ntfy, "warning", alert.text()); err != nil {
+			errs = append(errs, fmt.Errorf("ntfy: %w", err))
+		}
+	}
+	if config.PushoverToken != "" {
+		if err := postPushover(ctx, title, priority.pushover, alert.text()); err != nil {
+			errs = append(errs, fmt.Errorf("pushover: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func postNtfy(ctx context.Context, topicURL, token, title, priority, tags, message string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", topicURL, strings.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", title)
+	req.Header.Set("Priority", priority)
+	req.Header.Set("Tags", tags)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("ntfy error: %s", body)
+	}
+	return nil
+}
+
+func postPushover(ctx context.Context, title string, priority int, message string) error {
+	form := url.Values{
+		"token":    {config.PushoverToken},
+		"user":     {config.PushoverUser},
+		"title":    {title},
+		"message":  {message},
+		"priority": {strconv.Itoa(priority)},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", pushoverMessagesURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("pushover error: %s", body)
+	}
+	return nil
+}
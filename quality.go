@@ -0,0 +1,42 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	qualityRetried      = "retried"
+	qualityStale        = "stale"
+	qualityCalibrated   = "calibrated"
+	qualityInterpolated = "interpolated"
+)
+
+var qualityNotes = map[string]string{
+	qualityRetried:      "再試行後の値",
+	qualityStale:        "キャッシュされた値",
+	qualityCalibrated:   "校正済みの値",
+	qualityInterpolated: "補間された値",
+}
+
+func addQuality(s *SwitchBotDeviceStatus, flag string) {
+	if !slices.Contains(s.Quality, flag) {
+		s.Quality = append(s.Quality, flag)
+	}
+}
+
+func qualityLine(s SwitchBotDeviceStatus) string {
+	if !config.QualityNotes || len(s.Quality) == 0 {
+		return ""
+	}
+	notes := make([]string, 0, len(s.Quality))
+	for _, flag := range s.Quality {
+		if note, ok := qualityNotes[flag]; ok {
+			notes = append(notes, tr(note))
+		}
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return "※" + strings.Join(notes, tr("、"))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"slices"
+	"time"
+)
+
+// QuietMode thresholds for temperature are in the display unit.
+type QuietMode struct {
+	Temperature    float64
+	Humidity       float64
+	CO2            int
+	ForcePostHours int
+}
+
+type quietState struct {
+	Status   SwitchBotDeviceStatus `json:"status"`
+	PostedAt time.Time             `json:"postedAt"`
+}
+
+func quietKey(deviceID string) string {
+	return "quiet_last:" + deviceID
+}
+
+func quietSuppressed(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus, now time.Time) bool {
+	q := config.QuietMode
+	if q == nil || (status.Temperature == nil && status.Humidity == nil && status.CO2 == nil) {
+		return false
+	}
+	var last quietState
+	ok, err := stateStore.Get(ctx, quietKey(device.DeviceID), &last)
+	if err != nil {
+		log.Printf("Failed to load last posted reading for %s: %v", device.DeviceName, err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	if q.ForcePostHours > 0 && now.Sub(last.PostedAt) >= time.Duration(q.ForcePostHours)*time.Hour {
+		return false
+	}
+	if !slices.Equal(stateLines(status), stateLines(last.Status)) {
+		return false
+	}
+	return !changedBy(status.Temperature, last.Status.Temperature, celsiusDelta("temperature", q.Temperature)) &&
+		!changedBy(status.Humidity, last.Status.Humidity, q.Humidity) &&
+		!changedBy(intReading(status.CO2), intReading(last.Status.CO2), float64(q.CO2))
+}
+
+func changedBy(cur, prev *float64, threshold float64) bool {
+	if cur == nil || prev == nil {
+		return cur != prev
+	}
+	d := math.Abs(*cur - *prev)
+	return d > 0 && d >= threshold
+}
+
+func intReading(v *int) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+// recordQuietPosts lets the next run compare against what followers last saw.
+func recordQuietPosts(ctx context.Context, readings []deviceReading, now time.Time) {
+	if config.QuietMode == nil {
+		return
+	}
+	for _, r := range readings {
+		if err := stateStore.Put(ctx, quietKey(r.Device.DeviceID), quietState{Status: r.Status, PostedAt: now}); err != nil {
+			log.Printf("Failed to save last posted reading for %s: %v", r.Device.DeviceName, err)
+		}
+	}
+}
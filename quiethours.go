@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const quietHoursSinceKey = "quiet_hours_since"
+
+type QuietHours struct {
+	Start   string
+	End     string
+	CatchUp bool
+}
+
+func validateQuietHours(q *QuietHours) error {
+	if q == nil {
+		return nil
+	}
+	if _, err := parseClock(q.Start); err != nil {
+		return err
+	}
+	if _, err := parseClock(q.End); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (q *QuietHours) active(now time.Time) bool {
+	local := now.In(timeLocation())
+	start, _ := parseClock(q.Start)
+	end, _ := parseClock(q.End)
+	return inClockRange(local.Hour()*60+local.Minute(), start, end)
+}
+
+func holdForQuietHours(ctx context.Context, now time.Time) bool {
+	q := config.QuietHours
+	if q == nil || !q.active(now) {
+		return false
+	}
+	if q.CatchUp {
+		var since time.Time
+		if ok, err := stateStore.Get(ctx, quietHoursSinceKey, &since); err != nil {
+			log.Printf("Failed to load quiet hours state: %v", err)
+		} else if !ok {
+			if err := stateStore.Put(ctx, quietHoursSinceKey, now); err != nil {
+				log.Printf("Failed to save quiet hours state: %v", err)
+			}
+		}
+	}
+	return true
+}
+
+func postQuietHoursCatchUp(ctx context.Context, readings []deviceReading, now time.Time) {
+	if config.QuietHours == nil || !config.QuietHours.CatchUp {
+		return
+	}
+	var since time.Time
+	ok, err := stateStore.Get(ctx, quietHoursSinceKey, &since)
+	if err != nil {
+		log.Printf("Failed to load quiet hours state: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	loc := timeLocation()
+	var b strings.Builder
+	for _, r := range readings {
+		history, err := loadHistory(ctx, r.Device.DeviceID)
+		if err != nil {
+			log.Printf("Failed to load history for %s: %v", r.Device.DeviceName, err)
+			continue
+		}
+		if lines := summarizeHistory(history, since); lines != "" {
+			b.WriteString("\n" + makeDeviceHeader(r.Device.DeviceName) + "\n" + lines)
+		}
+	}
+	if b.Len() > 0 {
+		message := fmt.Sprintf(tr("🌙 %s〜%sのまとめ"), since.In(loc).Format("15:04"), now.In(loc).Format("15:04")) + "\n" + b.String()
+		log.Println("Generated quiet hours catch-up:", message)
+		if err := notify(ctx, message); err != nil {
+			log.Printf("Failed to post quiet hours catch-up: %v", err)
+			return
+		}
+	}
+	if err := stateStore.Delete(ctx, quietHoursSinceKey); err != nil {
+		log.Printf("Failed to clear quiet hours state: %v", err)
+	}
+}
+
+func summarizeHistory(history []SwitchBotDeviceStatus, since time.Time) string {
+	var b strings.Builder
+	_, tempUnit := displayTemperature(0)
+	for _, m := range []struct {
+		label, unit string
+		decimals    int
+		value       func(SwitchBotDeviceStatus) *float64
+	}{
+		{tr("温度"), tempUnit, 1, func(s SwitchBotDeviceStatus) *float64 {
+			if s.Temperature == nil {
+				return nil
+			}
+			t, _ := displayTemperature(*s.Temperature)
+			return &t
+		}},
+		{tr("湿度"), "%", 1, func(s SwitchBotDeviceStatus) *float64 { return s.Humidity }},
+		{"CO2", "ppm", 0, func(s SwitchBotDeviceStatus) *float64 { return intReading(s.CO2) }},
+	} {
+		var s metricSummary
+		var sum float64
+		var count int
+		for _, h := range history {
+			v := m.value(h)
+			if v == nil || h.ReadAt.Before(since) {
+				continue
+			}
+			if count == 0 || *v < s.Min {
+				s.Min = *v
+			}
+			if count == 0 || *v > s.Max {
+				s.Max = *v
+				s.PeakAt = h.ReadAt
+			}
+			sum += *v
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+		s.Avg = sum / float64(count)
+		writeSummaryLine(&b, m.label, m.unit, m.decimals, s)
+	}
+	return b.String()
+}
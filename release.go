@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	releaseCheckKey      = "release_check"
+	releaseCheckInterval = 24 * time.Hour
+)
+
+var version = ""
+
+type releaseCheck struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	Latest    string    `json:"latest"`
+	URL       string    `json:"url"`
+}
+
+// currentVersion is empty for "(devel)" and v0.0.0 pseudo-versions.
+func currentVersion() string {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	v := info.Main.Version
+	if v == "" || v == "(devel)" || strings.HasPrefix(v, "v0.0.0-") || strings.Contains(v, "+dirty") {
+		return ""
+	}
+	return v
+}
+
+func checkForRelease(ctx context.Context, now time.Time) {
+	if !config.ReleaseCheck || currentVersion() == "" {
+		return
+	}
+	var last releaseCheck
+	if _, err := stateStore.Get(ctx, releaseCheckKey, &last); err != nil {
+		log.Printf("Failed to load release check state: %v", err)
+		return
+	}
+	if now.Sub(last.CheckedAt) < releaseCheckInterval {
+		return
+	}
+	latest, err := fetchLatestRelease(ctx)
+	if err != nil {
+		log.Printf("Failed to check for a new release: %v", err)
+		return
+	}
+	latest.CheckedAt = now
+	if err := stateStore.Put(ctx, releaseCheckKey, latest); err != nil {
+		log.Printf("Failed to save release check state: %v", err)
+	}
+	if line := releaseNotice(latest); line != "" {
+		log.Println(line)
+	}
+}
+
+func fetchLatestRelease(ctx context.Context) (releaseCheck, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.github.com/repos/"+config.ReleaseRepo+"/releases/latest", nil)
+	if err != nil {
+		return releaseCheck{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	res, err := sharedHTTPClient().Do(req)
+	if err != nil {
+		return releaseCheck{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return releaseCheck{}, fmt.Errorf("GitHub releases returned %s", res.Status)
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&release); err != nil {
+		return releaseCheck{}, err
+	}
+	return releaseCheck{Latest: release.TagName, URL: release.HTMLURL}, nil
+}
+
+func releaseNotice(r releaseCheck) string {
+	current := currentVersion()
+	if current == "" || r.Latest == "" || !versionLess(current, r.Latest) {
+		return ""
+	}
+	return fmt.Sprintf("🆕 新しいバージョン %s があります（現在 %s）: %s", r.Latest, current, r.URL)
+}
+
+func versionLess(a, b string) bool {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := range max(len(pa), len(pb)) {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return false
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i != -1 {
+		v = v[:i]
+	}
+	var parts []int
+	for p := range strings.SplitSeq(v, ".") {
+		n, _ := strconv.Atoi(p)
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+func releaseSummaryLine(ctx context.Context) string {
+	if !config.ReleaseCheck {
+		return ""
+	}
+	var last releaseCheck
+	if _, err := stateStore.Get(ctx, releaseCheckKey, &last); err != nil {
+		log.Printf("Failed to load release check state: %v", err)
+		return ""
+	}
+	if line := releaseNotice(last); line != "" {
+		return line + "\n"
+	}
+	return ""
+}
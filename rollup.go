@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	rollupMode                = "rollup"
+	rollupHourlyRetentionDays = 90
+)
+
+// rollupStats keeps Sum and Count so hourly buckets merge exactly into daily ones.
+type rollupStats struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Sum   float64 `json:"sum"`
+	Count int     `json:"count"`
+}
+
+func (s *rollupStats) add(o rollupStats) {
+	if s.Count == 0 {
+		*s = o
+		return
+	}
+	s.Min, s.Max = min(s.Min, o.Min), max(s.Max, o.Max)
+	s.Sum += o.Sum
+	s.Count += o.Count
+}
+
+type rollupBucket struct {
+	Start   time.Time              `json:"start"`
+	Metrics map[string]rollupStats `json:"metrics"`
+}
+
+func (b *rollupBucket) add(name string, v *float64) {
+	if v == nil {
+		return
+	}
+	s := b.Metrics[name]
+	s.add(rollupStats{Min: *v, Max: *v, Sum: *v, Count: 1})
+	b.Metrics[name] = s
+}
+
+func rollupHourlyKey(deviceID, date string) string {
+	return "rollup_hourly:" + deviceID + ":" + date
+}
+
+func rollupDailyKey(deviceID, month string) string {
+	return "rollup_daily:" + deviceID + ":" + month
+}
+
+// runRollup never recomputes an hour, since its raw history may have been trimmed since.
+func runRollup(ctx context.Context, now time.Time) error {
+	keys, err := stateStore.Keys(ctx, "history:")
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, key := range keys {
+		deviceID := strings.TrimPrefix(key, "history:")
+		if err := rollupDevice(ctx, deviceID, now); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", deviceID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func rollupDevice(ctx context.Context, deviceID string, now time.Time) error {
+	history, err := loadHistory(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	current := localHour(now)
+	hours := map[int64]*rollupBucket{}
+	for _, h := range history {
+		start := localHour(h.ReadAt)
+		if !start.Before(current) {
+			continue
+		}
+		b, ok := hours[start.Unix()]
+		if !ok {
+			b = &rollupBucket{Start: start, Metrics: map[string]rollupStats{}}
+			hours[start.Unix()] = b
+		}
+		b.add("temperature", h.Temperature)
+		b.add("humidity", h.Humidity)
+		b.add("co2", intReading(h.CO2))
+		b.add("lightLevel", intReading(h.LightLevel))
+		b.add("power", h.Power)
+	}
+
+	byDate := map[string][]*rollupBucket{}
+	for _, b := range hours {
+		date := opsDate(b.Start)
+		byDate[date] = append(byDate[date], b)
+	}
+	today := opsDate(now)
+	for date, buckets := range byDate {
+		var day []rollupBucket
+		if _, err := stateStore.Get(ctx, rollupHourlyKey(deviceID, date), &day); err != nil {
+			return err
+		}
+		added := 0
+		for _, b := range buckets {
+			if slices.ContainsFunc(day, func(d rollupBucket) bool { return d.Start.Equal(b.Start) }) {
+				continue
+			}
+			day = append(day, *b)
+			added++
+		}
+		if added == 0 {
+			continue
+		}
+		slices.SortFunc(day, func(a, b rollupBucket) int { return a.Start.Compare(b.Start) })
+		if err := stateStore.Put(ctx, rollupHourlyKey(deviceID, date), day); err != nil {
+			return err
+		}
+		if date < today {
+			if err := putDailyRollup(ctx, deviceID, day); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func loadHourlyRollups(ctx context.Context, deviceID string, from, to time.Time) ([]rollupBucket, error) {
+	var buckets []rollupBucket
+	for day := localHour(from); opsDate(day) <= opsDate(to); day = day.AddDate(0, 0, 1) {
+		var hours []rollupBucket
+		if _, err := stateStore.Get(ctx, rollupHourlyKey(deviceID, opsDate(day)), &hours); err != nil {
+			return nil, err
+		}
+		for _, h := range hours {
+			if !h.Start.Before(from) && h.Start.Before(to) {
+				buckets = append(buckets, h)
+			}
+		}
+	}
+	return buckets, nil
+}
+
+// localHour differs from Truncate(time.Hour) in zones with a non-whole-hour offset.
+func localHour(t time.Time) time.Time {
+	t = t.In(timeLocation())
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+func putDailyRollup(ctx context.Context, deviceID string, hours []rollupBucket) error {
+	if len(hours) == 0 {
+		return nil
+	}
+	start := hours[0].Start
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	total := rollupBucket{Start: start, Metrics: map[string]rollupStats{}}
+	for _, h := range hours {
+		for name, s := range h.Metrics {
+			t := total.Metrics[name]
+			t.add(s)
+			total.Metrics[name] = t
+		}
+	}
+
+	key := rollupDailyKey(deviceID, touMonth(start))
+	var month []rollupBucket
+	if _, err := stateStore.Get(ctx, key, &month); err != nil {
+		return err
+	}
+	month = slices.DeleteFunc(month, func(d rollupBucket) bool { return d.Start.Equal(start) })
+	month = append(month, total)
+	slices.SortFunc(month, func(a, b rollupBucket) int { return a.Start.Compare(b.Start) })
+	return stateStore.Put(ctx, key, month)
+}
+
+func rollupKeysToPrune(ctx context.Context, now time.Time) ([]string, error) {
+	keys, err := stateStore.Keys(ctx, "rollup_hourly:")
+	if err != nil {
+		return nil, err
+	}
+	oldest := opsDate(now.AddDate(0, 0, -rollupHourlyRetentionDays))
+	var expired []string
+	for _, key := range keys {
+		date := key[strings.LastIndex(key, ":")+1:]
+		if date < oldest {
+			expired = append(expired, key)
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+)
+
+type SceneBinding struct {
+	Name     string
+	SceneID  string
+	Target   string
+	Command  string
+	Metric   string
+	Operator string
+	Value    float64
+	Devices  []string
+
+	condition Condition
+}
+
+func validateSceneBindings(bindings []SceneBinding) error {
+	for i := range bindings {
+		b := &bindings[i]
+		switch {
+		case b.SceneID != "" && b.Target != "":
+			return fmt.Errorf("scene %q cannot have both SceneID and Target", b.Name)
+		case b.SceneID == "" && b.Target == "":
+			return fmt.Errorf("scene %q requires SceneID or Target", b.Name)
+		case b.Target != "":
+			action, ok := commandActions[b.Command]
+			if !ok || action == "status" {
+				return fmt.Errorf("scene %q has unknown Command %q", b.Name, b.Command)
+			}
+		}
+		c, err := newThresholdCondition(ConditionSpec{Metric: b.Metric, Operator: b.Operator, Value: b.Value}, nil)
+		if err != nil {
+			return fmt.Errorf("scene %q: %w", b.Name, err)
+		}
+		b.condition = c
+	}
+	return nil
+}
+
+func (b SceneBinding) key() string {
+	if b.SceneID != "" {
+		return b.SceneID
+	}
+	return b.Target + ":" + b.Command
+}
+
+func (b SceneBinding) label() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return b.key()
+}
+
+func (b SceneBinding) plan() string {
+	if b.SceneID != "" {
+		return fmt.Sprintf(tr("シーン「%s」"), b.label())
+	}
+	return fmt.Sprintf(tr("%s を %s"), b.Target, b.Command)
+}
+
+func runSceneBindings(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus) []string {
+	if len(config.Scenes) == 0 {
+		return nil
+	}
+	key := "scene_active:" + device.DeviceID
+	var previous []string
+	if _, err := stateStore.Get(ctx, key, &previous); err != nil {
+		log.Printf("Failed to load scene state for %s: %v", device.DeviceName, err)
+	}
+
+	var lines, active []string
+	in := conditionInput{Device: device, Status: status, Now: status.ReadAt}
+	for _, b := range config.Scenes {
+		rule := AlertRule{Devices: b.Devices}
+		if !rule.appliesTo(device) || !b.condition.Evaluate(in) {
+			continue
+		}
+		if slices.Contains(previous, b.key()) {
+			active = append(active, b.key())
+			continue
+		}
+		if config.ScenesDryRun {
+			log.Printf("Would run %s for %s (dry run)", b.key(), device.DeviceName)
+			lines = append(lines, fmt.Sprintf(tr("📝 実行予定: %s"), b.plan()))
+			active = append(active, b.key())
+			continue
+		}
+		// Left inactive, so the next run retries while the condition holds.
+		if err := runSceneAction(ctx, b); err != nil {
+			log.Printf("Failed to execute scene %s for %s: %v", b.label(), device.DeviceName, err)
+			lines = append(lines, fmt.Sprintf(tr("🎬 シーン「%s」の実行に失敗しました"), b.label()))
+			continue
+		}
+		active = append(active, b.key())
+		log.Printf("Executed scene %s for %s", b.label(), device.DeviceName)
+		lines = append(lines, fmt.Sprintf(tr("🎬 シーン「%s」を実行しました"), b.label()))
+	}
+	if !slices.Equal(previous, active) {
+		if err := stateStore.Put(ctx, key, active); err != nil {
+			log.Printf("Failed to save scene state for %s: %v", device.DeviceName, err)
+		}
+	}
+	return lines
+}
+
+func runSceneAction(ctx context.Context, b SceneBinding) error {
+	if b.SceneID != "" {
+		return executeScene(ctx, b.SceneID)
+	}
+	list, err := fetchDeviceList(ctx)
+	if err != nil {
+		return err
+	}
+	target, ok := findDevice(append(list.DeviceList, list.InfraredRemoteList...), b.Target)
+	if !ok {
+		return fmt.Errorf("device %q not found", b.Target)
+	}
+	if result := enqueueCommand(ctx, target, commandActions[b.Command], "scene:"+b.label()); result == commandFailed {
+		return fmt.Errorf("%s was not confirmed", b.Command)
+	}
+	return nil
+}
+
+func executeScene(ctx context.Context, sceneID string) error {
+	url := fmt.Sprintf("https://api.switch-bot.com/v1.1/scenes/%s/execute", sceneID)
+	var resp SwitchBotResponse[json.RawMessage]
+	return switchBotRequest(ctx, "POST", url, nil, &resp)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	serviceName        = "switchbot_bot"
+	serviceDisplayName = "SwitchBot bot"
+)
+
+// runServiceCommand runs before setup, since the service manager starts the
+// process outside the directory holding config.json.
+func runServiceCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: service install|uninstall|run [flags] [-- daemon flags]")
+	}
+	fs := flag.NewFlagSet("service "+args[0], flag.ContinueOnError)
+	dir := fs.String("dir", ".", "directory holding config.json and the state file")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(*dir)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "install":
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		if err := installService(exe, abs, fs.Args()); err != nil {
+			return fmt.Errorf("installing the service failed: %w", err)
+		}
+		fmt.Printf("Installed %s, running the daemon in %s\n", serviceName, abs)
+		return nil
+	case "uninstall":
+		if err := uninstallService(); err != nil {
+			return fmt.Errorf("uninstalling the service failed: %w", err)
+		}
+		fmt.Printf("Uninstalled %s\n", serviceName)
+		return nil
+	case "run":
+		return runService(ctx, abs, fs.Args())
+	}
+	return fmt.Errorf("unknown service command %q", args[0])
+}
+
+func serviceArgs(dir string, daemonArgs []string) []string {
+	return append([]string{"service", "run", "-dir", dir, "--"}, daemonArgs...)
+}
+
+func startDaemon(ctx context.Context, dir string, daemonArgs []string) error {
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	if err := setup(); err != nil {
+		return err
+	}
+	return runDaemon(ctx, daemonArgs)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"log/syslog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const launchdLabel = "com.github.shinderuman.switchbot_bot"
+
+func launchdPlistPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist"), nil
+}
+
+func launchdPlist(exe, dir string, daemonArgs []string) string {
+	var b strings.Builder
+	str := func(s string) {
+		b.WriteString("\t<string>")
+		xml.EscapeText(&b, []byte(s))
+		b.WriteString("</string>\n")
+	}
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString("<plist version=\"1.0\">\n<dict>\n\t<key>Label</key>\n")
+	str(launchdLabel)
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range append([]string{exe}, serviceArgs(dir, daemonArgs)...) {
+		b.WriteByte('\t')
+		str(arg)
+	}
+	b.WriteString("\t</array>\n\t<key>WorkingDirectory</key>\n")
+	str(dir)
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n\t<key>KeepAlive</key>\n\t<true/>\n</dict>\n</plist>\n")
+	return b.String()
+}
+
+func installService(exe, dir string, daemonArgs []string) error {
+	path, err := launchdPlistPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(launchdPlist(exe, dir, daemonArgs)), 0o644); err != nil {
+		return err
+	}
+	return launchctl("bootstrap", fmt.Sprintf("gui/%d", os.Getuid()), path)
+}
+
+func uninstallService() error {
+	path, err := launchdPlistPath()
+	if err != nil {
+		return err
+	}
+	if err := launchctl("bootout", fmt.Sprintf("gui/%d/%s", os.Getuid(), launchdLabel)); err != nil {
+		log.Printf("Stopping the agent failed: %v", err)
+	}
+	return os.Remove(path)
+}
+
+func launchctl(args ...string) error {
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func runService(ctx context.Context, dir string, daemonArgs []string) error {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, serviceName)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	log.SetFlags(0)
+	log.SetOutput(w)
+	return startDaemon(ctx, dir, daemonArgs)
+}
@@ -0,0 +1,22 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+var errServiceUnsupported = errors.New("service install is only supported on Windows and macOS; use a systemd unit running the daemon command instead")
+
+func installService(string, string, []string) error {
+	return errServiceUnsupported
+}
+
+func uninstallService() error {
+	return errServiceUnsupported
+}
+
+func runService(ctx context.Context, dir string, daemonArgs []string) error {
+	return startDaemon(ctx, dir, daemonArgs)
+}
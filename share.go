@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sharePath      = "/share"
+	shareLinksKey  = "share_links"
+	shareIDByteLen = 6
+)
+
+// shareLink must still be listed to be valid, so deleting one revokes it.
+type shareLink struct {
+	ID      string    `json:"id"`
+	Label   string    `json:"label,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+func shareSignature(id string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(config.GuestTokenSecret))
+	fmt.Fprintf(mac, "share:%s:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func loadShareLinks(ctx context.Context, now time.Time) ([]shareLink, error) {
+	var links []shareLink
+	if _, err := stateStore.Get(ctx, shareLinksKey, &links); err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(links, func(l shareLink) bool { return now.After(l.Expires) }), nil
+}
+
+func serveShare(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !validShareLink(r.Context(), q, time.Now()) {
+		http.Error(w, "this link has expired or was revoked", http.StatusForbidden)
+		return
+	}
+	expires, _ := strconv.ParseInt(q.Get("expires"), 10, 64)
+	link := url.Values{"id": {q.Get("id")}, "expires": {q.Get("expires")}, "sig": {q.Get("sig")}}
+	http.SetCookie(w, &http.Cookie{
+		Name:     dashboardShareCookie,
+		Value:    link.Encode(),
+		Path:     "/",
+		Expires:  time.Unix(expires, 0),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	renderDashboard(w, r, false)
+}
+
+func validShareLink(ctx context.Context, q url.Values, now time.Time) bool {
+	if config.GuestTokenSecret == "" {
+		return false
+	}
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil || now.Unix() > expires {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(q.Get("sig")), []byte(shareSignature(q.Get("id"), expires))) != 1 {
+		return false
+	}
+	links, err := loadShareLinks(ctx, now)
+	if err != nil {
+		log.Printf("Failed to load share links: %v", err)
+		return false
+	}
+	return slices.ContainsFunc(links, func(l shareLink) bool { return l.ID == q.Get("id") })
+}
+
+func runShareCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: share create|list|revoke [flags]")
+	}
+	if config.GuestTokenSecret == "" {
+		return fmt.Errorf("GuestTokenSecret is not set")
+	}
+	now := time.Now()
+	links, err := loadShareLinks(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("share create", flag.ContinueOnError)
+		base := fs.String("base", "http://localhost"+config.DaemonListen, "base URL of the daemon")
+		ttl := fs.String("ttl", "24h", "how long the link stays valid")
+		label := fs.String("label", "", "who the link is for")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		d, err := parseLookback(*ttl)
+		if err != nil {
+			return err
+		}
+		b := make([]byte, shareIDByteLen)
+		rand.Read(b)
+		link := shareLink{ID: hex.EncodeToString(b), Label: *label, Expires: now.Add(d)}
+		if err := stateStore.Put(ctx, shareLinksKey, append(links, link)); err != nil {
+			return err
+		}
+		q := url.Values{
+			"id":      {link.ID},
+			"expires": {strconv.FormatInt(link.Expires.Unix(), 10)},
+			"sig":     {shareSignature(link.ID, link.Expires.Unix())},
+		}
+		fmt.Printf("%s%s?%s\n", strings.TrimRight(*base, "/"), sharePath, q.Encode())
+		return nil
+	case "list":
+		for _, l := range links {
+			fmt.Printf("%s\t%s\t%s\n", l.ID, l.Expires.In(timeLocation()).Format(time.DateTime), l.Label)
+		}
+		return stateStore.Put(ctx, shareLinksKey, links)
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: share revoke <id>")
+		}
+		n := len(links)
+		links = slices.DeleteFunc(links, func(l shareLink) bool { return l.ID == args[1] })
+		if len(links) == n {
+			return fmt.Errorf("no active share link %q", args[1])
+		}
+		return stateStore.Put(ctx, shareLinksKey, links)
+	}
+	return fmt.Errorf("unknown share command %q", args[0])
+}
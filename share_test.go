@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestValidShareLink(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(time.Hour).Unix()
+	link := func(id string, expires int64, sig string) url.Values {
+		return url.Values{"id": {id}, "expires": {strconv.FormatInt(expires, 10)}, "sig": {sig}}
+	}
+	tests := []struct {
+		name   string
+		secret string
+		q      url.Values
+		want   bool
+	}{
+		{"valid", "secret", link("abc", expires, ""), true},
+		{"no secret", "", link("abc", expires, ""), false},
+		{"expired", "secret", link("abc", now.Add(-time.Second).Unix(), ""), false},
+		{"revoked", "secret", link("def", expires, ""), false},
+		{"bad signature", "secret", link("abc", expires, "00"), false},
+		{"tampered expiry", "secret", link("abc", expires+1, shareSignature("abc", expires)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestState(t)
+			config.GuestTokenSecret = tt.secret
+			links := []shareLink{{ID: "abc", Expires: time.Unix(expires, 0)}}
+			if err := stateStore.Put(context.Background(), shareLinksKey, links); err != nil {
+				t.Fatal(err)
+			}
+			if tt.q.Get("sig") == "" {
+				exp, _ := strconv.ParseInt(tt.q.Get("expires"), 10, 64)
+				tt.q.Set("sig", shareSignature(tt.q.Get("id"), exp))
+			}
+			if got := validShareLink(context.Background(), tt.q, now); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidKioskSignature(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(time.Hour).Unix()
+	oldConfig := config
+	t.Cleanup(func() { config = oldConfig })
+	config.KioskSecret = "secret"
+	valid := kioskSignature(expires)
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{"valid", fmt.Sprintf("expires=%d&sig=%s", expires, valid), true},
+		{"expired", fmt.Sprintf("expires=%d&sig=%s", now.Add(-time.Second).Unix(), kioskSignature(now.Add(-time.Second).Unix())), false},
+		{"tampered expiry", fmt.Sprintf("expires=%d&sig=%s", expires+1, valid), false},
+		{"bad signature", fmt.Sprintf("expires=%d&sig=00", expires), false},
+		{"missing expiry", "sig=" + valid, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", kioskPath+"?"+tt.query, nil)
+		if got := validKioskSignature(r, now); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidReadToken(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(time.Hour).Unix()
+	oldConfig := config
+	t.Cleanup(func() { config = oldConfig })
+	config.DashboardToken = "dashboard"
+	config.GuestTokenSecret = "secret"
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"dashboard token", "dashboard", true},
+		{"guest token", mintGuestToken(expires), true},
+		{"expired guest token", mintGuestToken(now.Add(-time.Second).Unix()), false},
+		{"tampered guest token", fmt.Sprintf("%s%d.%s", guestTokenPrefix, expires+1, guestTokenSignature(expires)), false},
+		{"share signature as guest token", fmt.Sprintf("%s%d.%s", guestTokenPrefix, expires, shareSignature("", expires)), false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		if got := validReadToken(tt.token, now); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
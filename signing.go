@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var switchBotClockOffset atomic.Int64
+
+func switchBotNow() time.Time {
+	return time.Now().Add(time.Duration(switchBotClockOffset.Load()))
+}
+
+func maxSwitchBotSkew() time.Duration {
+	return time.Duration(config.SwitchBotMaxSkewSeconds) * time.Second
+}
+
+func observeSwitchBotClock(res *http.Response, sentAt time.Time) (time.Duration, bool) {
+	serverTime, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+	// The Date header only has second precision.
+	local := sentAt.Add(time.Since(sentAt) / 2)
+	skew := serverTime.Sub(local).Truncate(time.Second)
+	if skew.Abs() < time.Second {
+		skew = 0
+	}
+	if skew.Abs() >= maxSwitchBotSkew() {
+		log.Printf("Local clock is %v off from the SwitchBot API server; check NTP", skew)
+	}
+	if !config.SwitchBotClockSync {
+		return skew, false
+	}
+	previous := time.Duration(switchBotClockOffset.Swap(int64(skew)))
+	return skew, (skew - previous).Abs() >= time.Second
+}
+
+var errSwitchBotUnauthorized = errors.New("SwitchBot rejected the request signature")
+
+func signatureError(statusCode int, body []byte, skew time.Duration) error {
+	err := fmt.Errorf("%w (HTTP %d): %s", errSwitchBotUnauthorized, statusCode, body)
+	if skew.Abs() < maxSwitchBotSkew() {
+		return fmt.Errorf("%w; check SwitchBotToken and SwitchBotSecret", err)
+	}
+	if config.SwitchBotClockSync {
+		return fmt.Errorf("%w; local clock is %v off from the API server even after correction", err, skew)
+	}
+	return fmt.Errorf("%w; local clock is %v off from the API server, fix NTP or enable SwitchBotClockSync", err, skew)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type replayEvent struct {
+	Device SwitchBotDevice
+	Status SwitchBotDeviceStatus
+}
+
+func runRulesSimulate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("rules simulate", flag.ContinueOnError)
+	since := fs.String("since", "7d", "how far back to replay (e.g. 7d, 12h)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	from, err := replayFrom(*since)
+	if err != nil {
+		return err
+	}
+
+	events, err := loadReplayEvents(ctx, from)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		fmt.Println("No archived readings in range")
+		return nil
+	}
+
+	fired := map[string]int{}
+	for _, alert := range replayAlerts(events, config.Alerts, conditions) {
+		fmt.Printf("%s %s: %s (%s)\n", alert.At.In(timeLocation()).Format("2006-01-02 15:04"), alert.Device.DeviceName, alert.Rule.Name, alert.text())
+		fired[alert.Rule.Name]++
+	}
+	ran := map[string]int{}
+	for _, scene := range replayScenes(events, config.Scenes) {
+		fmt.Printf("%s %s: %s\n", scene.At.In(timeLocation()).Format("2006-01-02 15:04"), scene.Device.DeviceName, scene.Binding.plan())
+		ran[scene.Binding.label()]++
+	}
+	fmt.Printf("\nReplayed %d readings from %s\n", len(events), events[0].Status.ReadAt.In(timeLocation()).Format("2006-01-02 15:04"))
+	for _, rule := range config.Alerts {
+		fmt.Printf("  %s: fired %d times\n", rule.Name, fired[rule.Name])
+	}
+	for _, b := range config.Scenes {
+		fmt.Printf("  %s: would have run %d times\n", b.label(), ran[b.label()])
+	}
+	return nil
+}
+
+func replayFrom(since string) (time.Time, error) {
+	lookback, err := parseLookback(since)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if keep := time.Duration(config.HistoryHours) * time.Hour; lookback > keep {
+		return time.Time{}, fmt.Errorf("--since %s goes past the %dh of history kept; raise HistoryHours or shorten --since", since, config.HistoryHours)
+	}
+	return time.Now().Add(-lookback), nil
+}
+
+func loadReplayEvents(ctx context.Context, from time.Time) ([]replayEvent, error) {
+	devices, err := fetchDevices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetchDevices error: %w", err)
+	}
+	var events []replayEvent
+	for _, device := range devices {
+		if !isTargetDevice(device) {
+			continue
+		}
+		history, err := loadHistory(ctx, device.DeviceID)
+		if err != nil {
+			return nil, fmt.Errorf("loadHistory error for %s: %w", device.DeviceName, err)
+		}
+		for _, status := range history {
+			if !status.ReadAt.Before(from) {
+				events = append(events, replayEvent{Device: device, Status: status})
+			}
+		}
+	}
+	slices.SortFunc(events, func(a, b replayEvent) int {
+		return a.Status.ReadAt.Compare(b.Status.ReadAt)
+	})
+	return events, nil
+}
+
+type replayedAlert struct {
+	triggeredAlert
+	At time.Time
+}
+
+func replayAlerts(events []replayEvent, rules []AlertRule, conds map[string]Condition) []replayedAlert {
+	latest := map[string]SwitchBotDeviceStatus{}
+	histories := map[string][]SwitchBotDeviceStatus{}
+	timers := map[string]conditionTimers{}
+	active := map[string]bool{}
+
+	var result []replayedAlert
+	for _, e := range events {
+		id := e.Device.DeviceID
+		latest[id] = e.Status
+		latest[e.Device.DeviceName] = e.Status
+		if timers[id] == nil {
+			timers[id] = conditionTimers{}
+		}
+
+		firing := map[string]bool{}
+		for _, alert := range evaluateRules(conditionInput{
+			Device:  e.Device,
+			Status:  e.Status,
+			History: histories[id],
+			Latest:  latest,
+			Timers:  timers[id],
+			Now:     e.Status.ReadAt,
+		}, rules, conds) {
+			key := id + "\x00" + alert.Rule.Name
+			firing[key] = true
+			if !active[key] {
+				result = append(result, replayedAlert{triggeredAlert: alert, At: e.Status.ReadAt})
+			}
+		}
+		for _, rule := range rules {
+			key := id + "\x00" + rule.Name
+			active[key] = firing[key]
+		}
+		histories[id] = append(histories[id], e.Status)
+	}
+	return result
+}
+
+type replayedScene struct {
+	Device  SwitchBotDevice
+	Binding SceneBinding
+	At      time.Time
+}
+
+func replayScenes(events []replayEvent, bindings []SceneBinding) []replayedScene {
+	active := map[string]bool{}
+	var result []replayedScene
+	for _, e := range events {
+		in := conditionInput{Device: e.Device, Status: e.Status, Now: e.Status.ReadAt}
+		for _, b := range bindings {
+			key := e.Device.DeviceID + "\x00" + b.key()
+			rule := AlertRule{Devices: b.Devices}
+			firing := rule.appliesTo(e.Device) && b.condition.Evaluate(in)
+			if firing && !active[key] {
+				result = append(result, replayedScene{Device: e.Device, Binding: b, At: e.Status.ReadAt})
+			}
+			active[key] = firing
+		}
+	}
+	return result
+}
+
+func runRulesWhatIf(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("rules whatif", flag.ContinueOnError)
+	since := fs.String("since", "7d", "how far back to replay (e.g. 7d, 12h)")
+	metric := fs.String("metric", "co2", "metric to test (temperature, humidity, co2, battery, lightLevel, power, voltage)")
+	operator := fs.String("operator", ">", "comparison operator")
+	value := fs.Float64("value", 1000, "proposed threshold")
+	minutes := fs.Int("for", 0, "only count the threshold once it has held for this many minutes")
+	device := fs.String("device", "", "limit to one device name or ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	from, err := replayFrom(*since)
+	if err != nil {
+		return err
+	}
+
+	specs := map[string]ConditionSpec{
+		"threshold": {Type: "threshold", Metric: *metric, Operator: *operator, Value: *value},
+	}
+	name := "threshold"
+	if *minutes > 0 {
+		specs["whatif"] = ConditionSpec{Type: "duration", Conditions: []string{"threshold"}, Minutes: *minutes}
+		name = "whatif"
+	}
+	conds, err := buildConditions(specs)
+	if err != nil {
+		return err
+	}
+	rule := AlertRule{Name: "whatif", Condition: name}
+	if *device != "" {
+		rule.Devices = []string{*device}
+	}
+
+	events, err := loadReplayEvents(ctx, from)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		fmt.Println("No archived readings in range")
+		return nil
+	}
+
+	alerts := replayAlerts(events, []AlertRule{rule}, conds)
+	var byHour [24]int
+	for _, alert := range alerts {
+		at := alert.At.In(timeLocation())
+		byHour[at.Hour()]++
+		fmt.Printf("%s %s\n", at.Format("2006-01-02 15:04"), alert.Device.DeviceName)
+	}
+
+	first, last := events[0].Status.ReadAt, events[len(events)-1].Status.ReadAt
+	span := last.Sub(first)
+	weeks := max(span.Hours()/(24*7), 1.0/7)
+	fmt.Printf("\n%s %s %g: %d alerts from %s to %s (%.1f days)\n",
+		*metric, *operator, *value, len(alerts),
+		first.In(timeLocation()).Format("2006-01-02 15:04"), last.In(timeLocation()).Format("2006-01-02 15:04"), span.Hours()/24)
+	if span < 7*24*time.Hour {
+		fmt.Printf("%.1f per week, extrapolated from %.1f days\n", float64(len(alerts))/weeks, span.Hours()/24)
+	} else {
+		fmt.Printf("%.1f per week\n", float64(len(alerts))/weeks)
+	}
+	for hour, n := range byHour {
+		if n > 0 {
+			fmt.Printf("  %02d:00-%02d:59 %d\n", hour, hour, n)
+		}
+	}
+	return nil
+}
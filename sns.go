@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+var (
+	snsClient     *sns.Client
+	snsClientOnce sync.Once
+)
+
+type snsEvent struct {
+	EventType  string                 `json:"eventType"`
+	DeviceID   string                 `json:"deviceId"`
+	DeviceName string                 `json:"deviceName"`
+	DeviceType string                 `json:"deviceType,omitempty"`
+	Time       time.Time              `json:"time"`
+	Status     *SwitchBotDeviceStatus `json:"status,omitempty"`
+	Alert      string                 `json:"alert,omitempty"`
+	Severity   string                 `json:"severity,omitempty"`
+	Message    string                 `json:"message,omitempty"`
+}
+
+func snsPublisher(ctx context.Context) (*sns.Client, error) {
+	cfg, err := loadAWSConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config failed: %w", err)
+	}
+	snsClientOnce.Do(func() {
+		snsClient = sns.NewFromConfig(cfg)
+	})
+	return snsClient, nil
+}
+
+func publishSNSEvent(ctx context.Context, event snsEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	client, err := snsPublisher(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(config.SNSTopicARN),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]snstypes.MessageAttributeValue{
+			"eventType": {DataType: aws.String("String"), StringValue: aws.String(event.EventType)},
+			"deviceId":  {DataType: aws.String("String"), StringValue: aws.String(event.DeviceID)},
+		},
+	})
+	return err
+}
+
+func publishStatusEvents(ctx context.Context, readings []deviceReading) {
+	if config.SNSTopicARN == "" {
+		return
+	}
+	for _, r := range readings {
+		status := r.Status
+		event := snsEvent{
+			EventType:  "status",
+			DeviceID:   r.Device.DeviceID,
+			DeviceName: r.Device.DeviceName,
+			DeviceType: r.Device.DeviceType,
+			Time:       status.ReadAt,
+			Status:     &status,
+		}
+		if err := publishSNSEvent(ctx, event); err != nil {
+			log.Printf("Failed to publish status event for %s to SNS: %v", r.Device.DeviceName, err)
+		}
+	}
+}
+
+func publishAlertEvents(ctx context.Context, device SwitchBotDevice, status SwitchBotDeviceStatus, previous []string, alerts []triggeredAlert) {
+	if config.SNSTopicARN == "" {
+		return
+	}
+	for _, alert := range alerts {
+		if slices.Contains(previous, alert.Rule.Name) {
+			continue
+		}
+		event := snsEvent{
+			EventType:  "alert",
+			DeviceID:   device.DeviceID,
+			DeviceName: device.DeviceName,
+			DeviceType: device.DeviceType,
+			Time:       status.ReadAt,
+			Status:     &status,
+			Alert:      alert.Rule.Name,
+			Severity:   alert.Rule.Severity,
+			Message:    alert.text(),
+		}
+		if err := publishSNSEvent(ctx, event); err != nil {
+			log.Printf("Failed to publish alert %s to SNS: %v", alert.Rule.Name, err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var stateStore StateStore
+
+type StateStore interface {
+	Get(ctx context.Context, key string, out any) (bool, error)
+	Put(ctx context.Context, key string, value any) error
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func newStateStore() (StateStore, error) {
+	if config.StateTable != "" {
+		return newDynamoStateStore(config.StateTable), nil
+	}
+	return newFileStateStore(config.StateFile)
+}
+
+type fileStateStore struct {
+	mu     sync.Mutex
+	path   string
+	values map[string]json.RawMessage
+}
+
+func newFileStateStore(path string) (*fileStateStore, error) {
+	s := &fileStateStore{path: path, values: map[string]json.RawMessage{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state file failed: %w", err)
+	}
+	if err := json.Unmarshal(b, &s.values); err != nil {
+		return nil, fmt.Errorf("parsing state file failed: %w", err)
+	}
+	return s, nil
+}
+
+func (s *fileStateStore) Get(_ context.Context, key string, out any) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw, ok := s.values[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return false, fmt.Errorf("decoding state %q failed: %w", key, err)
+	}
+	return true, nil
+}
+
+func (s *fileStateStore) Put(_ context.Context, key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding state %q failed: %w", key, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = raw
+	b, err := json.MarshalIndent(s.values, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o600)
+}
+
+func (s *fileStateStore) Keys(_ context.Context, prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key := range s.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func (s *fileStateStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; !ok {
+		return nil
+	}
+	delete(s.values, key)
+	b, err := json.MarshalIndent(s.values, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0o600)
+}
+
+type stateExport struct {
+	Version    int                        `json:"version"`
+	ExportedAt time.Time                  `json:"exportedAt"`
+	Entries    map[string]json.RawMessage `json:"entries"`
+}
+
+func runStateCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: state export|import [flags] [file]")
+	}
+	switch args[0] {
+	case "export":
+		return runStateExport(ctx, args[1:])
+	case "import":
+		return runStateImport(ctx, args[1:])
+	}
+	return fmt.Errorf("unknown state command %q", args[0])
+}
+
+func runStateExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("state export", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	keys, err := stateStore.Keys(ctx, "")
+	if err != nil {
+		return err
+	}
+	export := stateExport{Version: 1, ExportedAt: time.Now(), Entries: make(map[string]json.RawMessage, len(keys))}
+	for _, key := range keys {
+		var raw json.RawMessage
+		if _, err := stateStore.Get(ctx, key, &raw); err != nil {
+			return err
+		}
+		export.Entries[key] = raw
+	}
+	b, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path := fs.Arg(0); path != "" && path != "-" {
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d entries to %s\n", len(keys), path)
+		return nil
+	}
+	_, err = os.Stdout.Write(append(b, '\n'))
+	return err
+}
+
+func runStateImport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("state import", flag.ContinueOnError)
+	replace := fs.Bool("replace", false, "delete existing entries that are not in the file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var b []byte
+	var err error
+	if path := fs.Arg(0); path != "" && path != "-" {
+		b, err = os.ReadFile(path)
+	} else {
+		b, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+	var export stateExport
+	if err := json.Unmarshal(b, &export); err != nil {
+		return fmt.Errorf("parsing state export failed: %w", err)
+	}
+	if export.Version != 1 {
+		return fmt.Errorf("unsupported state export version %d", export.Version)
+	}
+
+	if *replace {
+		keys, err := stateStore.Keys(ctx, "")
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if _, ok := export.Entries[key]; ok {
+				continue
+			}
+			if err := stateStore.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+	keys := slices.Sorted(maps.Keys(export.Entries))
+	for _, key := range keys {
+		if err := stateStore.Put(ctx, key, export.Entries[key]); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Imported %d entries exported at %s\n", len(keys), export.ExportedAt.In(timeLocation()).Format("2006-01-02 15:04"))
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultMastodonCharLimit = 500
+	mastodonCharLimitKey     = "mastodon_char_limit"
+	mastodonCharLimitTTL     = 24 * time.Hour
+)
+
+type mastodonCharLimitCache struct {
+	Fingerprint string    `json:"fingerprint"`
+	Limit       int       `json:"limit"`
+	CheckedAt   time.Time `json:"checkedAt"`
+}
+
+func mastodonCharLimit(ctx context.Context) int {
+	fingerprint := credentialFingerprint(config.MastodonURL, "")
+	var cached mastodonCharLimitCache
+	if ok, err := stateStore.Get(ctx, mastodonCharLimitKey, &cached); err != nil {
+		log.Printf("Failed to read cached character limit: %v", err)
+	} else if ok && cached.Fingerprint == fingerprint && cached.Limit > 0 && time.Since(cached.CheckedAt) < mastodonCharLimitTTL {
+		return cached.Limit
+	}
+
+	var instance struct {
+		Configuration struct {
+			Statuses struct {
+				MaxCharacters int `json:"max_characters"`
+			} `json:"statuses"`
+		} `json:"configuration"`
+		// Pleroma, Akkoma, and glitch-soc.
+		MaxTootChars int `json:"max_toot_chars"`
+	}
+	if err := httpGet(ctx, "/instance", &instance); err != nil {
+		log.Printf("Failed to fetch the instance character limit: %v", err)
+		return cmp.Or(cached.Limit, defaultMastodonCharLimit)
+	}
+	limit := cmp.Or(instance.Configuration.Statuses.MaxCharacters, instance.MaxTootChars, defaultMastodonCharLimit)
+	entry := mastodonCharLimitCache{Fingerprint: fingerprint, Limit: limit, CheckedAt: time.Now()}
+	if err := stateStore.Put(ctx, mastodonCharLimitKey, entry); err != nil {
+		log.Printf("Failed to cache character limit: %v", err)
+	}
+	return limit
+}
+
+// splitStatus prefers to break before a device header, then at line ends.
+func splitStatus(text, prefix string, limit int) []string {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+	if prefix != "" {
+		prefix += " "
+	}
+	var parts []string
+	var cur strings.Builder
+	budget := func() int {
+		if len(parts) == 0 {
+			return limit
+		}
+		return limit - utf8.RuneCountInString(prefix)
+	}
+	fits := func(s string) bool {
+		return utf8.RuneCountInString(cur.String())+utf8.RuneCountInString(s) <= budget()
+	}
+	flush := func() {
+		if part := strings.TrimRight(cur.String(), "\n"); part != "" {
+			parts = append(parts, part)
+		}
+		cur.Reset()
+	}
+
+	for _, block := range statusBlocks(text) {
+		if fits(block) {
+			cur.WriteString(block)
+			continue
+		}
+		flush()
+		if fits(block) {
+			cur.WriteString(block)
+			continue
+		}
+		for _, line := range strings.SplitAfter(block, "\n") {
+			if !fits(line) {
+				flush()
+			}
+			for utf8.RuneCountInString(line) > budget() {
+				runes := []rune(line)
+				parts = append(parts, string(runes[:budget()]))
+				line = string(runes[budget():])
+			}
+			cur.WriteString(line)
+		}
+	}
+	flush()
+
+	for i := 1; i < len(parts); i++ {
+		parts[i] = prefix + parts[i]
+	}
+	return parts
+}
+
+func statusBlocks(text string) []string {
+	var blocks []string
+	var cur strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if strings.HasPrefix(line, "# ") && cur.Len() > 0 {
+			blocks = append(blocks, cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(line)
+	}
+	if cur.Len() > 0 {
+		blocks = append(blocks, cur.String())
+	}
+	return blocks
+}
+
+func leadingMentions(text string) string {
+	var mentions []string
+	for _, field := range strings.Fields(text) {
+		if !strings.HasPrefix(field, "@") {
+			break
+		}
+		mentions = append(mentions, field)
+	}
+	return strings.Join(mentions, " ")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+const dailySummaryMode = "daily_summary"
+
+type metricSummary struct {
+	Min, Max, Avg float64
+	PeakAt        time.Time
+	Coverage      seriesCoverage
+}
+
+type summaryBucket struct {
+	At    time.Time
+	Stats rollupStats
+}
+
+func summarizeMetric(ctx context.Context, client func() (*cloudwatch.Client, error), device SwitchBotDevice, name string, now time.Time) (*metricSummary, error) {
+	start := now.Add(-24 * time.Hour)
+	hours, err := loadHourlyRollups(ctx, device.DeviceID, start, now)
+	if err != nil {
+		return nil, err
+	}
+	var buckets []summaryBucket
+	for _, h := range hours {
+		if s, ok := h.Metrics[strings.ToLower(name)]; ok {
+			buckets = append(buckets, summaryBucket{At: h.Start, Stats: s})
+		}
+	}
+	if len(hours) > 0 {
+		if len(buckets) == 0 {
+			return nil, nil
+		}
+		return summarizeBuckets(buckets, start, now, time.Hour), nil
+	}
+
+	c, err := client()
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.GetMetricStatistics(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String(config.MetricsNamespace),
+		MetricName: aws.String(name),
+		Dimensions: metricDimensions(device.DeviceID, device.DeviceName),
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(now),
+		Period:     aws.Int32(300),
+		Statistics: []types.Statistic{
+			types.StatisticMinimum, types.StatisticMaximum, types.StatisticSum, types.StatisticSampleCount,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, dp := range out.Datapoints {
+		buckets = append(buckets, summaryBucket{At: *dp.Timestamp, Stats: rollupStats{
+			Min: *dp.Minimum, Max: *dp.Maximum, Sum: *dp.Sum, Count: int(*dp.SampleCount),
+		}})
+	}
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+	return summarizeBuckets(buckets, start, now, 5*time.Minute), nil
+}
+
+func summarizeBuckets(buckets []summaryBucket, start, end time.Time, minInterval time.Duration) *metricSummary {
+	var s metricSummary
+	var total rollupStats
+	times := make([]time.Time, 0, len(buckets))
+	series := make([]timedValue, 0, len(buckets))
+	for _, b := range buckets {
+		times = append(times, b.At)
+		series = append(series, timedValue{At: b.At, Value: b.Stats.Sum / float64(b.Stats.Count)})
+		if total.Count == 0 || b.Stats.Max > total.Max {
+			s.PeakAt = b.At
+		}
+		total.add(b.Stats)
+	}
+	s.Min, s.Max, s.Avg = total.Min, total.Max, total.Sum/float64(total.Count)
+	s.Coverage = coverage(times, start, end, minInterval)
+	if config.GapPolicy == gapInterpolate {
+		filled := interpolateSeries(series, s.Coverage.Interval)
+		sum := 0.0
+		for _, v := range filled {
+			sum += v.Value
+		}
+		s.Avg = sum / float64(len(filled))
+	}
+	return &s
+}
+
+func formatDailySummary(ctx context.Context, client func() (*cloudwatch.Client, error), device SwitchBotDevice, now time.Time) (string, error) {
+	var b strings.Builder
+	var labels []string
+	var covs []seriesCoverage
+	_, tempUnit := displayTemperature(0)
+	for _, m := range []struct {
+		name, label, unit string
+		decimals          int
+	}{
+		{"Temperature", tr("温度"), tempUnit, 1},
+		{"Humidity", tr("湿度"), "%", 1},
+		{"CO2", "CO2", "ppm", 0},
+	} {
+		s, err := summarizeMetric(ctx, client, device, m.name, now)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", m.name, err)
+		}
+		if s == nil {
+			continue
+		}
+		if m.name == "Temperature" {
+			s.Min, _ = displayTemperature(s.Min)
+			s.Max, _ = displayTemperature(s.Max)
+			s.Avg, _ = displayTemperature(s.Avg)
+		}
+		writeSummaryLine(&b, m.label, m.unit, m.decimals, *s)
+		labels = append(labels, m.label)
+		covs = append(covs, s.Coverage)
+		if m.name == "CO2" {
+			fmt.Fprintf(&b, "%s: %s\n", tr("CO2ピーク"), s.PeakAt.In(timeLocation()).Format("15:04"))
+		}
+	}
+	if b.Len() == 0 {
+		return "", nil
+	}
+	b.WriteString(metricCoverageLine(labels, covs) + "\n")
+	return makeDeviceHeader(device.DeviceName) + " " + tr("過去24時間") + "\n" + b.String(), nil
+}
+
+func writeSummaryLine(b *strings.Builder, label, unit string, decimals int, s metricSummary) {
+	fmt.Fprintf(b, "%s: %s%s%s / %s%s%s / %s%s%s\n", label,
+		tr("最低"), formatNumber(s.Min, decimals), unit,
+		tr("最高"), formatNumber(s.Max, decimals), unit,
+		tr("平均"), formatNumber(s.Avg, decimals), unit)
+}
+
+func runDailySummary(ctx context.Context) error {
+	devices, err := fetchDevices(ctx)
+	if err != nil {
+		recordSwitchBotAuthFailure(ctx, err)
+		return fmt.Errorf("fetchDevices error: %w", err)
+	}
+	client := func() (*cloudwatch.Client, error) { return cloudWatch(ctx) }
+
+	now := time.Now()
+	var errs []error
+	for _, device := range devices {
+		if !isTargetDevice(device) {
+			continue
+		}
+		message, err := formatDailySummary(ctx, client, device, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", device.DeviceName, err))
+			continue
+		}
+		if message == "" {
+			continue
+		}
+		log.Println("Generated daily summary:", message)
+		if err := notify(ctx, message); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", device.DeviceName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
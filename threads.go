@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+type deviceSection struct {
+	Device   SwitchBotDevice
+	Message  string
+	Alerting bool
+	Notable  bool
+}
+
+type sectionNotifier interface {
+	// NotifySections returns the unsent sections so a retry resends only those.
+	NotifySections(ctx context.Context, sections []deviceSection, charts []chartImage) ([]deviceSection, error)
+}
+
+func deviceThreadKey(deviceID string) string {
+	return "device_thread:" + deviceID
+}
+
+func notifySections(ctx context.Context, sections []deviceSection, charts []chartImage) error {
+	message := joinSections(sections)
+	var errs []error
+	for _, n := range notifiers {
+		if _, ok := n.(mastodonNotifier); ok && config.PinnedStatus == pinnedStatusOnly {
+			continue
+		}
+		unsent := sections
+		err := callNotifier(ctx, n, func(ctx context.Context) error {
+			t, sectioned := n.(sectionNotifier)
+			c, charted := n.(chartNotifier)
+			switch {
+			case sectioned:
+				var err error
+				unsent, err = t.NotifySections(ctx, unsent, charts)
+				return err
+			case charted && len(charts) > 0:
+				return c.NotifyWithCharts(ctx, message, charts)
+			}
+			return n.Notify(ctx, message)
+		})
+		if errors.Is(err, errDestinationSkipped) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+			continue
+		}
+		recordOps(func(s *opsStats) { s.Posts++ })
+	}
+	return errors.Join(errs...)
+}
+
+func joinSections(sections []deviceSection) string {
+	messages := make([]string, len(sections))
+	for i, s := range sections {
+		messages[i] = s.Message
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (mastodonNotifier) NotifySections(ctx context.Context, sections []deviceSection, charts []chartImage) ([]deviceSection, error) {
+	if !config.DeviceThreads {
+		alerting := slices.ContainsFunc(sections, func(s deviceSection) bool { return s.Alerting })
+		if err := postMastodonWithCharts(ctx, joinSections(sections), spoilerFor(alerting), charts); err != nil {
+			return sections, err
+		}
+		return nil, nil
+	}
+	var unsent []deviceSection
+	var errs []error
+	for _, s := range sections {
+		if err := postDeviceThreadReply(ctx, s, charts); err != nil {
+			unsent = append(unsent, s)
+			errs = append(errs, fmt.Errorf("%s: %w", s.Device.DeviceName, err))
+		}
+	}
+	return unsent, errors.Join(errs...)
+}
+
+func postDeviceThreadReply(ctx context.Context, s deviceSection, charts []chartImage) error {
+	var own []chartImage
+	for _, chart := range charts {
+		if chart.Title == s.Device.DeviceName {
+			own = append(own, chart)
+		}
+	}
+	payload := mastodonStatus(s.Message, config.PostVisibility, spoilerFor(s.Alerting))
+	if ids := uploadMastodonCharts(ctx, own); len(ids) > 0 {
+		payload["media_ids"] = ids
+	}
+
+	key := deviceThreadKey(s.Device.DeviceID)
+	var anchor string
+	if _, err := stateStore.Get(ctx, key, &anchor); err != nil {
+		log.Printf("Failed to load thread anchor for %s: %v", s.Device.DeviceName, err)
+	}
+	if anchor != "" {
+		payload["in_reply_to_id"] = anchor
+		_, err := createMastodonStatus(ctx, payload)
+		if !errors.Is(err, errMastodonNotFound) {
+			return err
+		}
+		log.Printf("Thread anchor %s for %s is gone; starting a new thread", anchor, s.Device.DeviceName)
+		delete(payload, "in_reply_to_id")
+	}
+
+	id, err := createMastodonStatus(ctx, payload)
+	if err != nil {
+		return err
+	}
+	if err := stateStore.Put(ctx, key, id); err != nil {
+		log.Printf("Failed to save thread anchor for %s: %v", s.Device.DeviceName, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/timestreamwrite"
+	tstypes "github.com/aws/aws-sdk-go-v2/service/timestreamwrite/types"
+)
+
+const timestreamMeasureName = "reading"
+
+var (
+	timestreamClient     *timestreamwrite.Client
+	timestreamClientOnce sync.Once
+)
+
+func timestream(ctx context.Context) (*timestreamwrite.Client, error) {
+	cfg, err := loadRoleAWSConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config failed: %w", err)
+	}
+	timestreamClientOnce.Do(func() {
+		timestreamClient = timestreamwrite.NewFromConfig(cfg)
+	})
+	return timestreamClient, nil
+}
+
+// putTimestreamRecord writes the device's metrics as one multi-measure record
+// named "reading", with one measure per metric name.
+func putTimestreamRecord(ctx context.Context, device SwitchBotDevice, points []metricPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	client, err := timestream(ctx)
+	if err != nil {
+		return err
+	}
+	measures := make([]tstypes.MeasureValue, 0, len(points))
+	for _, p := range points {
+		measures = append(measures, tstypes.MeasureValue{
+			Name:  aws.String(p.Name),
+			Value: aws.String(strconv.FormatFloat(p.Value, 'f', -1, 64)),
+			Type:  tstypes.MeasureValueTypeDouble,
+		})
+	}
+	dimensions := []tstypes.Dimension{
+		{Name: aws.String("DeviceId"), Value: aws.String(device.DeviceID)},
+	}
+	if device.DeviceName != "" {
+		dimensions = append(dimensions, tstypes.Dimension{Name: aws.String("DeviceName"), Value: aws.String(device.DeviceName)})
+	}
+	_, err = client.WriteRecords(ctx, &timestreamwrite.WriteRecordsInput{
+		DatabaseName: aws.String(config.TimestreamDatabase),
+		TableName:    aws.String(config.TimestreamTable),
+		Records: []tstypes.Record{{
+			Dimensions:       dimensions,
+			MeasureName:      aws.String(timestreamMeasureName),
+			MeasureValueType: tstypes.MeasureValueTypeMulti,
+			MeasureValues:    measures,
+			Time:             aws.String(strconv.FormatInt(points[0].Timestamp.UnixMilli(), 10)),
+			TimeUnit:         tstypes.TimeUnitMilliseconds,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("Timestream WriteRecords failed: %w", err)
+	}
+	return nil
+}
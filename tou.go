@@ -0,0 +1,193 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+type TimeOfUse struct {
+	Tariff       []TariffWindow
+	DefaultPrice float64
+	Loads        []ShiftableLoad
+}
+
+type TariffWindow struct {
+	Start string
+	End   string
+	Price float64
+}
+
+type ShiftableLoad struct {
+	Plug     string
+	MaxPrice float64
+}
+
+type touStats struct {
+	Wh         float64
+	Cost       float64
+	RefCost    float64
+	LastReadAt time.Time
+}
+
+const (
+	touReportKey            = "tou_report_posted"
+	touStatsRetentionMonths = 12
+)
+
+func validateTimeOfUse(t *TimeOfUse) error {
+	if t == nil {
+		return nil
+	}
+	for _, w := range t.Tariff {
+		if _, err := parseClock(w.Start); err != nil {
+			return err
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return err
+		}
+	}
+	for _, l := range t.Loads {
+		if l.Plug == "" {
+			return fmt.Errorf("time-of-use load requires Plug")
+		}
+	}
+	return nil
+}
+
+func (t *TimeOfUse) price(at time.Time) float64 {
+	local := at.In(timeLocation())
+	minute := local.Hour()*60 + local.Minute()
+	for _, w := range t.Tariff {
+		start, _ := parseClock(w.Start)
+		end, _ := parseClock(w.End)
+		if inClockRange(minute, start, end) {
+			return w.Price
+		}
+	}
+	return t.DefaultPrice
+}
+
+// averagePrice is what a load would have cost had it run at random times.
+func (t *TimeOfUse) averagePrice(day time.Time) float64 {
+	local := day.In(timeLocation())
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	var sum float64
+	for m := 0; m < 24*60; m += 15 {
+		sum += t.price(midnight.Add(time.Duration(m) * time.Minute))
+	}
+	return sum / (24 * 4)
+}
+
+func touMonth(t time.Time) string {
+	return t.In(timeLocation()).Format("2006-01")
+}
+
+func runTimeOfUse(ctx context.Context, readings []deviceReading, now time.Time) {
+	t := config.TimeOfUse
+	price := t.price(now)
+	statsKey := "tou_stats:" + touMonth(now)
+	stats := map[string]touStats{}
+	if _, err := stateStore.Get(ctx, statsKey, &stats); err != nil {
+		log.Printf("Failed to load time-of-use stats: %v", err)
+	}
+
+	for _, load := range t.Loads {
+		i := slices.IndexFunc(readings, func(r deviceReading) bool {
+			return r.Device.DeviceName == load.Plug || r.Device.DeviceID == load.Plug
+		})
+		if i == -1 {
+			continue
+		}
+		r := readings[i]
+		if r.Status.Power != nil {
+			s := stats[load.Plug]
+			if !s.LastReadAt.IsZero() {
+				hours := min(r.Status.ReadAt.Sub(s.LastReadAt), maxEnergyGap).Hours()
+				wh := *r.Status.Power * max(hours, 0)
+				s.Wh += wh
+				s.Cost += wh / 1000 * price
+				s.RefCost += wh / 1000 * t.averagePrice(now)
+			}
+			s.LastReadAt = r.Status.ReadAt
+			stats[load.Plug] = s
+		}
+
+		want := "turnOff"
+		if price <= load.MaxPrice {
+			want = "turnOn"
+		}
+		if r.Status.PowerState == nil || *r.Status.PowerState == map[string]string{"turnOn": "on", "turnOff": "off"}[want] {
+			continue
+		}
+		if config.ScenesDryRun {
+			log.Printf("Would %s %s at price %g (dry run)", want, r.Device.DeviceName, price)
+			continue
+		}
+		result := enqueueCommand(ctx, r.Device, want, "tariff")
+		log.Printf("Time-of-use %s for %s at price %g: %s", want, r.Device.DeviceName, price, result)
+	}
+	if err := stateStore.Put(ctx, statsKey, stats); err != nil {
+		log.Printf("Failed to save time-of-use stats: %v", err)
+	}
+	postTimeOfUseReport(ctx, now)
+}
+
+func postTimeOfUseReport(ctx context.Context, now time.Time) {
+	month := touMonth(now)
+	var posted string
+	if _, err := stateStore.Get(ctx, touReportKey, &posted); err != nil {
+		log.Printf("Failed to load time-of-use report state: %v", err)
+		return
+	}
+	if posted == month {
+		return
+	}
+	local := now.In(timeLocation())
+	previous := touMonth(time.Date(local.Year(), local.Month(), 0, 0, 0, 0, 0, local.Location()))
+	stats := map[string]touStats{}
+	if _, err := stateStore.Get(ctx, "tou_stats:"+previous, &stats); err != nil {
+		log.Printf("Failed to load time-of-use stats: %v", err)
+		return
+	}
+	if message := formatTimeOfUseReport(previous, stats); message != "" {
+		if err := notify(ctx, message); err != nil {
+			log.Printf("Failed to post time-of-use report: %v", err)
+			return
+		}
+	}
+	if err := stateStore.Put(ctx, touReportKey, month); err != nil {
+		log.Printf("Failed to save time-of-use report state: %v", err)
+	}
+}
+
+func formatTimeOfUseReport(month string, stats map[string]touStats) string {
+	plugs := make([]string, 0, len(stats))
+	for plug, s := range stats {
+		if s.Wh > 0 {
+			plugs = append(plugs, plug)
+		}
+	}
+	if len(plugs) == 0 {
+		return ""
+	}
+	slices.SortFunc(plugs, func(a, b string) int {
+		return cmp.Compare(stats[b].RefCost-stats[b].Cost, stats[a].RefCost-stats[a].Cost)
+	})
+
+	var b strings.Builder
+	var total float64
+	b.WriteString(makeDeviceHeader(fmt.Sprintf("時間帯別料金による節約 (%s)", month)) + "\n")
+	for _, plug := range plugs {
+		s := stats[plug]
+		total += s.RefCost - s.Cost
+		fmt.Fprintf(&b, "%s: %skWh / %s円（平均単価なら%s円）\n", plug,
+			formatNumber(s.Wh/1000, 1), formatNumber(s.Cost, 0), formatNumber(s.RefCost, 0))
+	}
+	fmt.Fprintf(&b, "💰 推定節約額: %s円\n", formatNumber(total, 0))
+	return b.String()
+}
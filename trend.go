@@ -0,0 +1,33 @@
+package main
+
+import "math"
+
+func trend(cur float64, prev *float64, decimals int) string {
+	if prev == nil {
+		return ""
+	}
+	scale := math.Pow(10, float64(decimals))
+	delta := math.Round((cur-*prev)*scale) / scale
+	switch {
+	case delta > 0:
+		return " ↑ (+" + formatNumber(delta, decimals) + ")"
+	case delta < 0:
+		return " ↓ (" + formatNumber(delta, decimals) + ")"
+	}
+	return " →"
+}
+
+func intTrend(cur int, prev *int) string {
+	if prev == nil {
+		return ""
+	}
+	p := float64(*prev)
+	return trend(float64(cur), &p, 0)
+}
+
+func previousReading(history []SwitchBotDeviceStatus) SwitchBotDeviceStatus {
+	if len(history) == 0 {
+		return SwitchBotDeviceStatus{}
+	}
+	return history[len(history)-1]
+}
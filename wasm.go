@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// wasmFormatter runs a WASI module with no filesystem, network, or environment access.
+type wasmFormatter struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+type wasmFormatRequest struct {
+	Device  SwitchBotDevice       `json:"device"`
+	Status  SwitchBotDeviceStatus `json:"status"`
+	Message string                `json:"message"`
+	Alerts  []string              `json:"alerts,omitempty"`
+}
+
+type wasmFormatResponse struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+var (
+	formatter     *wasmFormatter
+	formatterErr  error
+	formatterOnce sync.Once
+)
+
+func loadWASMFormatter(ctx context.Context, path string) (*wasmFormatter, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("compiling %s: %w", path, err)
+	}
+	return &wasmFormatter{runtime: runtime, compiled: compiled}, nil
+}
+
+func (f *wasmFormatter) format(ctx context.Context, req wasmFormatRequest) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+	in, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	mod, err := f.runtime.InstantiateModule(ctx, f.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStdin(bytes.NewReader(append(in, '\n'))).
+		WithStdout(&out).
+		WithStderr(log.Writer()))
+	if err != nil {
+		return "", err
+	}
+	mod.Close(ctx)
+	var resp wasmFormatResponse
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return "", fmt.Errorf("invalid response %q: %w", out.Bytes(), err)
+	}
+	if resp.Error != "" {
+		return "", errors.New(resp.Error)
+	}
+	return resp.Message, nil
+}
+
+func formatSection(ctx context.Context, section deviceSection, status SwitchBotDeviceStatus, alerts []string) string {
+	if config.FormatterWASM == "" {
+		return section.Message
+	}
+	formatterOnce.Do(func() {
+		formatter, formatterErr = loadWASMFormatter(context.Background(), config.FormatterWASM)
+	})
+	if formatterErr != nil {
+		log.Printf("Failed to load WASM formatter: %v", formatterErr)
+		return section.Message
+	}
+	message, err := formatter.format(ctx, wasmFormatRequest{
+		Device:  section.Device,
+		Status:  status,
+		Message: section.Message,
+		Alerts:  alerts,
+	})
+	if err != nil {
+		log.Printf("WASM formatter failed for %s: %v", section.Device.DeviceName, err)
+		return section.Message
+	}
+	if message == "" {
+		return section.Message
+	}
+	return message
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type apiGatewayRequest struct {
+	Body                  string            `json:"body"`
+	IsBase64Encoded       bool              `json:"isBase64Encoded"`
+	QueryStringParameters map[string]string `json:"queryStringParameters"`
+	RequestContext        json.RawMessage   `json:"requestContext"`
+}
+
+type switchBotWebhookEvent struct {
+	EventType    string         `json:"eventType"`
+	EventVersion string         `json:"eventVersion"`
+	Context      map[string]any `json:"context"`
+}
+
+var webhookStateLabels = map[string]map[string]string{
+	"openState": {
+		"open":            "🚪 開いています",
+		"close":           "🚪 閉まっています",
+		"timeOutNotClose": "⚠️ 開いたままです",
+	},
+	"lockState": {
+		"LOCKED":   "🔒 施錠",
+		"UNLOCKED": "🔓 解錠",
+		"JAMMED":   "⚠️ 施錠エラー",
+	},
+	"doorState": {
+		"opened": "🚪 ドアが開いています",
+		"closed": "🚪 ドアが閉まっています",
+	},
+	"detectionState": {
+		"DETECTED":     "👀 動きを検知",
+		"NOT_DETECTED": "💤 動きなし",
+	},
+}
+
+// stateLabel ignores case, which the status API and webhooks disagree on.
+func stateLabel(key, value string) string {
+	for v, label := range webhookStateLabels[key] {
+		if strings.EqualFold(v, value) {
+			return tr(label)
+		}
+	}
+	return fmt.Sprintf("%s: %s", key, value)
+}
+
+func stateLines(status SwitchBotDeviceStatus) []string {
+	var lines []string
+	if status.LockState != nil {
+		lines = append(lines, stateLabel("lockState", *status.LockState))
+	}
+	if status.DoorState != nil {
+		lines = append(lines, stateLabel("doorState", *status.DoorState))
+	}
+	if status.OpenState != nil {
+		lines = append(lines, stateLabel("openState", *status.OpenState))
+	}
+	if status.Moving != nil {
+		if *status.Moving {
+			lines = append(lines, stateLabel("detectionState", "DETECTED"))
+		} else {
+			lines = append(lines, stateLabel("detectionState", "NOT_DETECTED"))
+		}
+	}
+	return lines
+}
+
+func lambdaHandler(ctx context.Context, payload json.RawMessage) (any, error) {
+	var req apiGatewayRequest
+	if err := json.Unmarshal(payload, &req); err == nil && len(req.RequestContext) > 0 {
+		return handleWebhook(ctx, req), nil
+	}
+	if os.Getenv("MODE") == dailySummaryMode {
+		if err := setup(); err != nil {
+			return nil, err
+		}
+		return nil, runDailySummary(ctx)
+	}
+	if os.Getenv("MODE") == emailDigestMode {
+		if err := setup(); err != nil {
+			return nil, err
+		}
+		return nil, runEmailDigest(ctx)
+	}
+	if os.Getenv("MODE") == rollupMode {
+		if err := setup(); err != nil {
+			return nil, err
+		}
+		return nil, runRollup(ctx, time.Now())
+	}
+	return nil, handler(ctx)
+}
+
+func handleWebhook(ctx context.Context, req apiGatewayRequest) events.APIGatewayProxyResponse {
+	respond := func(code int, body string) events.APIGatewayProxyResponse {
+		return events.APIGatewayProxyResponse{StatusCode: code, Body: body}
+	}
+	if err := setup(); err != nil {
+		log.Printf("Webhook setup failed: %v", err)
+		return respond(http.StatusInternalServerError, "setup failed")
+	}
+	if config.WebhookToken == "" {
+		log.Println("Webhook rejected: WebhookToken is required to receive webhooks")
+		return respond(http.StatusInternalServerError, "WebhookToken is not configured")
+	}
+	if subtle.ConstantTimeCompare([]byte(req.QueryStringParameters["token"]), []byte(config.WebhookToken)) != 1 {
+		return respond(http.StatusUnauthorized, "unauthorized")
+	}
+
+	body := []byte(req.Body)
+	if req.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return respond(http.StatusBadRequest, "invalid body encoding")
+		}
+		body = decoded
+	}
+	var event switchBotWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return respond(http.StatusBadRequest, "invalid payload")
+	}
+	if event.EventType != "changeReport" {
+		return respond(http.StatusOK, "ignored")
+	}
+
+	mac, _ := event.Context["deviceMac"].(string)
+	device, ok, err := webhookDevice(ctx, mac)
+	if err != nil {
+		log.Printf("Failed to resolve webhook device: %v", err)
+		return respond(http.StatusBadGateway, "device lookup failed")
+	}
+	if !ok {
+		log.Printf("Webhook for unknown device %q rejected", mac)
+		return respond(http.StatusBadRequest, "unknown device")
+	}
+	if !allowedDevice(device) {
+		return respond(http.StatusOK, "ignored")
+	}
+	status := webhookStatus(device, event.Context)
+	if status.Temperature != nil || status.Humidity != nil || status.CO2 != nil || status.LightLevel != nil {
+		if history, err := loadHistory(ctx, device.DeviceID); err != nil {
+			log.Printf("Failed to load history for %s: %v", device.DeviceName, err)
+		} else if err := recordReading(ctx, device.DeviceID, history, status); err != nil {
+			log.Printf("Failed to record reading for %s: %v", device.DeviceName, err)
+		}
+		if err := PutMetric(ctx, device, status); err != nil {
+			log.Printf("Failed to send metrics to CloudWatch: %v", err)
+		}
+	}
+
+	message := formatWebhookMessage(device, status, event.Context)
+	log.Println("Generated webhook message:", message)
+	post := notify
+	refreshAwayMode(ctx, time.Now())
+	if awayActive.Load() && hasStateChange(event.Context) {
+		post = notifyUrgent
+		if config.UrgentMention != "" {
+			message = config.UrgentMention + "\n" + message
+		}
+	}
+	if err := post(ctx, message); err != nil {
+		log.Printf("Webhook post failed: %v", err)
+		return respond(http.StatusBadGateway, "post failed")
+	}
+	return respond(http.StatusOK, "ok")
+}
+
+func hasStateChange(eventContext map[string]any) bool {
+	for key := range webhookStateLabels {
+		if _, ok := eventContext[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	webhookDevicesTTL     = time.Hour
+	webhookDevicesRefresh = 5 * time.Minute
+)
+
+var webhookDevices struct {
+	sync.Mutex
+	list      []SwitchBotDevice
+	fetchedAt time.Time
+}
+
+func webhookDevice(ctx context.Context, mac string) (SwitchBotDevice, bool, error) {
+	id := strings.ToUpper(strings.ReplaceAll(mac, ":", ""))
+	if id == "" {
+		return SwitchBotDevice{}, false, nil
+	}
+	webhookDevices.Lock()
+	defer webhookDevices.Unlock()
+	find := func() (SwitchBotDevice, bool) {
+		for _, d := range webhookDevices.list {
+			if strings.EqualFold(d.DeviceID, id) {
+				return d, true
+			}
+		}
+		return SwitchBotDevice{}, false
+	}
+	age := time.Since(webhookDevices.fetchedAt)
+	if age < webhookDevicesTTL {
+		if d, ok := find(); ok || age < webhookDevicesRefresh {
+			return d, ok, nil
+		}
+	}
+	devices, err := fetchDevices(ctx)
+	if err != nil {
+		return SwitchBotDevice{}, false, err
+	}
+	webhookDevices.list, webhookDevices.fetchedAt = devices, time.Now()
+	d, ok := find()
+	return d, ok, nil
+}
+
+func webhookStatus(device SwitchBotDevice, eventContext map[string]any) SwitchBotDeviceStatus {
+	status := SwitchBotDeviceStatus{ReadAt: time.Now()}
+	if ms, ok := eventContext["timeOfSample"].(float64); ok && ms > 0 {
+		status.ReadAt = time.UnixMilli(int64(ms))
+	}
+	if v, ok := eventContext["temperature"].(float64); ok {
+		status.Temperature = &v
+	}
+	if v, ok := eventContext["humidity"].(float64); ok {
+		status.Humidity = &v
+	}
+	if v, ok := eventContext["CO2"].(float64); ok {
+		co2 := int(v)
+		status.CO2 = &co2
+	}
+	if v, ok := eventContext["battery"].(float64); ok {
+		battery := int(v)
+		status.Battery = &battery
+	}
+	if v, ok := eventContext["lightLevel"].(float64); ok {
+		light := int(v)
+		status.LightLevel = &light
+	}
+	return calibrateStatus(device, status)
+}
+
+func formatWebhookMessage(device SwitchBotDevice, status SwitchBotDeviceStatus, eventContext map[string]any) string {
+	var b strings.Builder
+	b.WriteString(makeDeviceHeader(device.DeviceName))
+	if status.Battery != nil {
+		fmt.Fprintf(&b, " (%s%s%%)", batteryTierEmoji(*status.Battery), formatInt(*status.Battery))
+	}
+	b.WriteByte('\n')
+	if status.Temperature != nil {
+		t, unit := displayTemperature(*status.Temperature)
+		fmt.Fprintf(&b, "%s: %s%s\n", tr("温度"), formatNumber(t, 1), unit)
+	}
+	if status.Humidity != nil {
+		fmt.Fprintf(&b, "%s: %s%%\n", tr("湿度"), formatNumber(*status.Humidity, 1))
+	}
+	if dew, abs, heat, ok := comfortValues(status); ok && config.ComfortMetrics {
+		d, unit := displayTemperature(dew)
+		w, _ := displayTemperature(heat)
+		fmt.Fprintf(&b, "%s: %s%s / %s: %sg/m³ / WBGT: %s%s\n",
+			tr("露点"), formatNumber(d, 1), unit, tr("絶対湿度"), formatNumber(abs, 1), formatNumber(w, 1), unit)
+	}
+	if status.Temperature != nil && status.Humidity != nil && config.DiscomfortIndex {
+		di := discomfortIndex(*status.Temperature, *status.Humidity)
+		fmt.Fprintf(&b, "%s: %s %s\n", tr("不快指数"), formatNumber(di, 0), discomfortEmoji(di))
+	}
+	if status.CO2 != nil {
+		fmt.Fprintf(&b, "CO2: %sppm %s\n", formatInt(*status.CO2), co2Icon(*status.CO2))
+	}
+	if status.LightLevel != nil {
+		fmt.Fprintf(&b, "%s: %s\n", tr("照度"), formatInt(*status.LightLevel))
+	}
+	keys := make([]string, 0, len(webhookStateLabels))
+	for key := range webhookStateLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, ok := eventContext[key].(string)
+		if !ok {
+			continue
+		}
+		b.WriteString(stateLabel(key, value) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+const xmppTimeout = 30 * time.Second
+
+type xmppNotifier struct {
+	jid       string
+	password  string
+	server    string
+	recipient string
+}
+
+type xmppFeatures struct {
+	StartTLS   *struct{} `xml:"starttls"`
+	Mechanisms []string  `xml:"mechanisms>mechanism"`
+	Bind       *struct{} `xml:"bind"`
+}
+
+func (xmppNotifier) Name() string { return "xmpp" }
+
+func (n xmppNotifier) Notify(ctx context.Context, message string) error {
+	local, domain, ok := strings.Cut(n.jid, "@")
+	if !ok {
+		return fmt.Errorf("invalid JID %q", n.jid)
+	}
+	domain, _, _ = strings.Cut(domain, "/")
+	addr := n.server
+	if addr == "" {
+		addr = net.JoinHostPort(domain, "5222")
+	}
+
+	var d net.Dialer
+	raw, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer raw.Close()
+	raw.SetDeadline(time.Now().Add(xmppTimeout))
+
+	features, dec, err := xmppOpenStream(raw, domain)
+	if err != nil {
+		return err
+	}
+	if features.StartTLS == nil {
+		return fmt.Errorf("server does not offer STARTTLS")
+	}
+	if _, err := io.WriteString(raw, "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"); err != nil {
+		return err
+	}
+	if name, err := xmppNextElement(dec); err != nil {
+		return err
+	} else if name != "proceed" {
+		return fmt.Errorf("STARTTLS rejected: %s", name)
+	}
+	conn := tls.Client(raw, &tls.Config{ServerName: domain})
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("TLS handshake failed: %w", err)
+	}
+
+	if features, dec, err = xmppOpenStream(conn, domain); err != nil {
+		return err
+	}
+	if !slices.Contains(features.Mechanisms, "PLAIN") {
+		return fmt.Errorf("server does not offer SASL PLAIN")
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte("\x00" + local + "\x00" + n.password))
+	if _, err := fmt.Fprintf(conn, "<auth xmlns='urn:ietf:params:xml:ns:xmpp-sasl' mechanism='PLAIN'>%s</auth>", credentials); err != nil {
+		return err
+	}
+	if name, err := xmppNextElement(dec); err != nil {
+		return err
+	} else if name != "success" {
+		return fmt.Errorf("authentication failed: %s", name)
+	}
+
+	if _, dec, err = xmppOpenStream(conn, domain); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(conn, "<iq type='set' id='bind'><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'><resource>switchbot_bot</resource></bind></iq>"); err != nil {
+		return err
+	}
+	if err := xmppExpectResult(dec); err != nil {
+		return fmt.Errorf("resource binding failed: %w", err)
+	}
+
+	var body strings.Builder
+	xml.EscapeText(&body, []byte(message))
+	var to strings.Builder
+	xml.EscapeText(&to, []byte(n.recipient))
+	if _, err := fmt.Fprintf(conn, "<message to='%s' type='chat'><body>%s</body></message></stream:stream>", to.String(), body.String()); err != nil {
+		return err
+	}
+	log.Println("XMPP message sent:", n.recipient)
+	return nil
+}
+
+func xmppOpenStream(conn io.ReadWriter, domain string) (xmppFeatures, *xml.Decoder, error) {
+	var features xmppFeatures
+	if _, err := fmt.Fprintf(conn, "<?xml version='1.0'?><stream:stream to='%s' xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>", domain); err != nil {
+		return features, nil, err
+	}
+	dec := xml.NewDecoder(conn)
+	start, err := xmppNextStart(dec)
+	if err != nil {
+		return features, nil, err
+	}
+	if start.Name.Local != "features" {
+		return features, nil, fmt.Errorf("unexpected element %q", start.Name.Local)
+	}
+	if err := dec.DecodeElement(&features, &start); err != nil {
+		return features, nil, err
+	}
+	return features, dec, nil
+}
+
+func xmppNextStart(dec *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local != "stream" {
+			return start, nil
+		}
+	}
+}
+
+func xmppNextElement(dec *xml.Decoder) (string, error) {
+	start, err := xmppNextStart(dec)
+	if err != nil {
+		return "", err
+	}
+	return start.Name.Local, dec.Skip()
+}
+
+func xmppExpectResult(dec *xml.Decoder) error {
+	start, err := xmppNextStart(dec)
+	if err != nil {
+		return err
+	}
+	var iq struct {
+		Type string `xml:"type,attr"`
+	}
+	if err := dec.DecodeElement(&iq, &start); err != nil {
+		return err
+	}
+	if start.Name.Local != "iq" || iq.Type != "result" {
+		return fmt.Errorf("unexpected %s of type %q", start.Name.Local, iq.Type)
+	}
+	return nil
+}